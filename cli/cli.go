@@ -141,6 +141,11 @@ Version:
 	rootCmd.AddCommand(cmd.DelCmd(manager))
 	rootCmd.AddCommand(cmd.LsCmd(manager))
 	rootCmd.AddCommand(cmd.StatCmd(manager))
+	rootCmd.AddCommand(cmd.ReserveCmd(manager))
+	rootCmd.AddCommand(cmd.UnreserveCmd(manager))
+	rootCmd.AddCommand(cmd.TokensCmd(manager))
+	rootCmd.AddCommand(cmd.CIDRsCmd(manager))
+	rootCmd.AddCommand(cmd.PoolsCmd(manager))
 	rootCmd.AddCommand(cmd.ServeCmd(srv))
 	rootCmd.AddCommand(cmd.VersionCmd())
 
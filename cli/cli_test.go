@@ -4,9 +4,11 @@
 package cli_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/keygen-sh/keygen-relay/cli"
@@ -31,9 +33,61 @@ func TestIntegration(t *testing.T) {
 		RequireExplicitExec: true,
 		TestWork:            true,
 		Setup:               setup,
+		Cmds: map[string]func(ts *testscript.TestScript, neg bool, args []string){
+			"leasetoken": leaseToken,
+			"apitoken":   apiToken,
+		},
 	})
 }
 
+// leaseToken reads the lease token from a claim response and stores it in an env var
+// i.e. leasetoken claim_response.txt LEASE_TOKEN
+func leaseToken(ts *testscript.TestScript, neg bool, args []string) {
+	if neg {
+		ts.Fatalf("unsupported: ! leasetoken")
+	}
+
+	if len(args) != 2 {
+		ts.Fatalf("usage: leasetoken file var")
+	}
+
+	var resp struct {
+		LeaseToken string `json:"lease_token"`
+	}
+
+	if err := json.Unmarshal([]byte(ts.ReadFile(args[0])), &resp); err != nil {
+		ts.Fatalf("failed to parse claim response: %s", err)
+	}
+
+	if resp.LeaseToken == "" {
+		ts.Fatalf("claim response has no lease token")
+	}
+
+	ts.Setenv(args[1], resp.LeaseToken)
+}
+
+// apiToken reads the api token from the output of `relay tokens create` and stores it in
+// an env var i.e. apitoken token.txt API_TOKEN
+func apiToken(ts *testscript.TestScript, neg bool, args []string) {
+	if neg {
+		ts.Fatalf("unsupported: ! apitoken")
+	}
+
+	if len(args) != 2 {
+		ts.Fatalf("usage: apitoken file var")
+	}
+
+	for _, line := range strings.Split(ts.ReadFile(args[0]), "\n") {
+		if token := strings.TrimSpace(line); strings.HasPrefix(token, "relay_") {
+			ts.Setenv(args[1], token)
+
+			return
+		}
+	}
+
+	ts.Fatalf("output has no api token")
+}
+
 func setup(env *testscript.Env) error {
 	setupFixtures(env)
 	setupEnv(env)
@@ -0,0 +1,420 @@
+// Package client implements a client for the relay API, which claims a lease on a
+// license for a node, keeps the lease alive using heartbeats, and releases it when the
+// node is done with it.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds each request, in addition to the wait for claims
+const requestTimeout = 30 * time.Second
+
+// maxResponseSize bounds a response body, which is at most a license file
+const maxResponseSize = 10 << 20
+
+var (
+	ErrNoLicensesAvailable = errors.New("no licenses available")
+	ErrLeaseNotFound       = errors.New("lease not found")
+	ErrLeaseLost           = errors.New("lease was lost")
+	ErrLeaseClosed         = errors.New("lease was closed")
+)
+
+// Error is an error response from the server
+type Error struct {
+	StatusCode int
+	Message    string
+
+	// RetryAfter is the backoff requested by the server via a Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("relay responded with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("relay responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is allows errors.Is to match an error response against the sentinel errors
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNoLicensesAvailable:
+		return e.StatusCode == http.StatusGone
+	case ErrLeaseNotFound:
+		return e.StatusCode == http.StatusNotFound
+	default:
+		return false
+	}
+}
+
+type Client struct {
+	config  *Config
+	http    *http.Client
+	baseURL *url.URL
+}
+
+// New returns a client for the relay server at the configured URL
+func New(cfg *Config) (*Client, error) {
+	if cfg.HeartbeatRatio <= 0 || cfg.HeartbeatRatio >= 1 {
+		return nil, fmt.Errorf("heartbeat ratio must be between 0 and 1: %v", cfg.HeartbeatRatio)
+	}
+
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("max retries must not be negative: %d", cfg.MaxRetries)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	// unix sockets are dialed directly, so the host of the base url is a placeholder
+	if socket, ok := strings.CutPrefix(cfg.URL, "unix://"); ok {
+		if socket == "" {
+			return nil, errors.New("unix socket path is required")
+		}
+
+		c := *client
+		c.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}
+
+		return &Client{config: cfg, http: &c, baseURL: &url.URL{Scheme: "http", Host: "relay"}}, nil
+	}
+
+	baseURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme: %q", baseURL.Scheme)
+	}
+
+	return &Client{config: cfg, http: client, baseURL: baseURL}, nil
+}
+
+// Claim claims a lease on a license for the node's primary lease, retrying retryable
+// failures. The lease is extended in the background until it's closed, and must be
+// closed to release the license.
+func (c *Client) Claim(ctx context.Context, fingerprint string) (*Lease, error) {
+	return c.claim(ctx, fingerprint, "")
+}
+
+// ClaimSubLease claims a sub-lease on a license for one of the node's processes, e.g. a
+// job, identified by leaseID
+func (c *Client) ClaimSubLease(ctx context.Context, fingerprint string, leaseID string) (*Lease, error) {
+	if leaseID == "" {
+		return nil, errors.New("lease id is required")
+	}
+
+	return c.claim(ctx, fingerprint, leaseID)
+}
+
+func (c *Client) claim(ctx context.Context, fingerprint string, leaseID string) (*Lease, error) {
+	if fingerprint == "" {
+		return nil, errors.New("fingerprint is required")
+	}
+
+	for attempt := 0; ; attempt++ {
+		lease, err := c.tryClaim(ctx, fingerprint, leaseID)
+		if err == nil {
+			return lease, nil
+		}
+
+		if attempt >= c.config.MaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff(attempt, err)):
+		}
+	}
+}
+
+type claimRequest struct {
+	TTL int64 `json:"ttl,omitempty"`
+	*Metadata
+}
+
+type claimResponse struct {
+	LicenseFile []byte `json:"license_file"`
+	LicenseKey  string `json:"license_key"`
+	LeaseToken  string `json:"lease_token"`
+	ExpiresAt   int64  `json:"expires_at"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type extendResponse struct {
+	ExpiresAt int64 `json:"expires_at"`
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+func (c *Client) tryClaim(ctx context.Context, fingerprint string, leaseID string) (*Lease, error) {
+	var body any
+	if c.config.TTL > 0 || c.config.Metadata != nil {
+		body = claimRequest{TTL: int64(c.config.TTL.Seconds()), Metadata: c.config.Metadata}
+	}
+
+	query := url.Values{}
+	if c.config.Wait > 0 {
+		query.Set("wait", c.config.Wait.String())
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, nodePath(fingerprint, leaseID), query, "", body, c.config.Wait)
+	if err != nil {
+		return nil, err
+	}
+
+	// a node can't claim a lease it already holds without its lease token
+	if resp.status != http.StatusCreated {
+		return nil, resp.err()
+	}
+
+	var claim claimResponse
+	if err := json.Unmarshal(resp.body, &claim); err != nil {
+		return nil, fmt.Errorf("invalid claim response: %w", err)
+	}
+
+	return newLease(c, fingerprint, leaseID, claim), nil
+}
+
+// extend extends a lease using its lease token, returning the lease's new expiry, where
+// the node's metadata is reported again so that the server has the latest
+func (c *Client) extend(ctx context.Context, lease *Lease) (*extendResponse, error) {
+	var body any
+	if c.config.Metadata != nil {
+		body = claimRequest{Metadata: c.config.Metadata}
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, nodePath(lease.Fingerprint, lease.LeaseID), nil, lease.LeaseToken, body, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.status {
+	case http.StatusAccepted:
+		var extend extendResponse
+		if err := json.Unmarshal(resp.body, &extend); err != nil {
+			return nil, fmt.Errorf("invalid extend response: %w", err)
+		}
+
+		return &extend, nil
+	case http.StatusCreated:
+		// the lease was culled, so the heartbeat claimed a new lease, which is released
+		// since the node's license may have changed
+		var claim claimResponse
+		if err := json.Unmarshal(resp.body, &claim); err == nil {
+			_ = c.release(ctx, lease.Fingerprint, lease.LeaseID, claim.LeaseToken)
+		}
+
+		return nil, &Error{StatusCode: resp.status, Message: "lease expired before it was extended"}
+	default:
+		return nil, resp.err()
+	}
+}
+
+// release releases a lease using its lease token
+func (c *Client) release(ctx context.Context, fingerprint string, leaseID string, token string) error {
+	resp, err := c.do(ctx, http.MethodDelete, nodePath(fingerprint, leaseID), nil, token, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	if resp.status != http.StatusNoContent {
+		return resp.err()
+	}
+
+	return nil
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// err returns the error for an error response, using the error message in its body
+func (r *response) err() error {
+	var body struct {
+		Error string `json:"error"`
+	}
+
+	_ = json.Unmarshal(r.body, &body)
+
+	err := &Error{StatusCode: r.status, Message: body.Error}
+
+	if secs, e := strconv.Atoi(r.header.Get("Retry-After")); e == nil && secs > 0 {
+		err.RetryAfter = time.Duration(secs) * time.Second
+	}
+
+	return err
+}
+
+// do sends a request with a unique nonce, verifying the response's signature when
+// signatures are configured
+func (c *Client) do(ctx context.Context, method string, p requestPath, query url.Values, token string, body any, wait time.Duration) (*response, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout+wait)
+	defer cancel()
+
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(c.baseURL.Path, "/") + p.path
+	u.RawPath = strings.TrimSuffix(c.baseURL.EscapedPath(), "/") + p.rawPath
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Relay-Nonce", nonce)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.config.Pool != "" {
+		req.Header.Set("Relay-Pool", c.config.Pool)
+	}
+
+	if c.config.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIToken)
+	}
+
+	if token != "" {
+		req.Header.Set("Relay-Lease-Token", token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if c.config.VerifySignatures() {
+		env := envelope{method: method, path: u.EscapedPath(), status: res.StatusCode, nonce: nonce, body: b}
+
+		if err := verifySignature(c.config, res.Header.Get("Relay-Signature"), res.Header.Get("Relay-Clock"), env, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to verify response: %w", err)
+		}
+	}
+
+	return &response{status: res.StatusCode, header: res.Header, body: b}, nil
+}
+
+// backoff returns the exponential backoff for a retry, with jitter so that nodes don't
+// retry in lockstep, unless the server asked for a longer backoff
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	backoff := c.config.MinBackoff << min(attempt, 16)
+	if backoff <= 0 || backoff > c.config.MaxBackoff {
+		backoff = c.config.MaxBackoff
+	}
+
+	if jitter := int64(backoff / 5); jitter > 0 {
+		backoff += time.Duration(rand.Int64N(jitter))
+	}
+
+	var e *Error
+	if errors.As(err, &e) && e.RetryAfter > backoff {
+		return e.RetryAfter
+	}
+
+	return backoff
+}
+
+// isRetryable returns true for errors that may succeed when retried, i.e. an exhausted
+// pool, a rate limit, a server error or a network error, but never a signature error
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, ErrSignatureMissing) || errors.Is(err, ErrSignatureInvalid) || errors.Is(err, ErrClockSkew) {
+		return false
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		switch e.StatusCode {
+		case http.StatusGone, http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// requestPath is a request's path, where the raw path is escaped for the request url, and
+// is the path covered by envelope signatures
+type requestPath struct {
+	path    string
+	rawPath string
+}
+
+func nodePath(fingerprint string, leaseID string) requestPath {
+	if leaseID == "" {
+		return requestPath{
+			path:    "/v1/nodes/" + fingerprint,
+			rawPath: "/v1/nodes/" + url.PathEscape(fingerprint),
+		}
+	}
+
+	return requestPath{
+		path:    "/v1/nodes/" + fingerprint + "/leases/" + leaseID,
+		rawPath: "/v1/nodes/" + url.PathEscape(fingerprint) + "/leases/" + url.PathEscape(leaseID),
+	}
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
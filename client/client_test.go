@@ -0,0 +1,560 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/client"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeases is an in-memory lease store behind a fake manager, so that the client can
+// be tested against the real handler
+type fakeLeases struct {
+	mu        sync.Mutex
+	available int
+	leases    map[string]string  // lease token by fingerprint and lease id
+	pools     []string           // pool of each claim and release
+	metadata  []*db.NodeMetadata // metadata reported by each claim and extend
+	claims    int
+	extends   int
+	releases  int
+
+	// claimErrs fail the next claims, in order, before a license is leased
+	claimErrs []licenses.OperationStatus
+}
+
+func newFakeLeases(available int) *fakeLeases {
+	return &fakeLeases{available: available, leases: map[string]string{}}
+}
+
+func (f *fakeLeases) manager() *testutils.FakeManager {
+	return &testutils.FakeManager{
+		ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			if pool != nil {
+				f.pools = append(f.pools, *pool)
+			}
+
+			f.metadata = append(f.metadata, opts.Metadata())
+
+			key := fingerprint + "/" + opts.LeaseID()
+
+			if token, ok := f.leases[key]; ok {
+				if opts.LeaseToken() == nil || *opts.LeaseToken() != token {
+					return nil, licenses.ErrLeaseTokenMismatch
+				}
+
+				f.extends++
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusExtended}, nil
+			}
+
+			f.claims++
+
+			if len(f.claimErrs) > 0 {
+				status := f.claimErrs[0]
+				f.claimErrs = f.claimErrs[1:]
+
+				return &licenses.LicenseOperationResult{Status: status}, nil
+			}
+
+			if f.available == 0 {
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, nil
+			}
+
+			f.available--
+
+			token := fmt.Sprintf("token_%d", f.claims)
+			f.leases[key] = token
+
+			return &licenses.LicenseOperationResult{
+				License:    &db.License{Guid: "test_license_guid", File: []byte("test_license_file"), Key: "test_license_key"},
+				LeaseToken: token,
+				Status:     licenses.OperationStatusCreated,
+			}, nil
+		},
+		ReleaseLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			if pool != nil {
+				f.pools = append(f.pools, *pool)
+			}
+
+			key := fingerprint + "/" + opts.LeaseID()
+
+			token, ok := f.leases[key]
+			if !ok {
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNotFound}, nil
+			}
+
+			if opts.LeaseToken() == nil || *opts.LeaseToken() != token {
+				return nil, licenses.ErrLeaseTokenMismatch
+			}
+
+			delete(f.leases, key)
+
+			f.available++
+			f.releases++
+
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+		},
+	}
+}
+
+// cull removes a lease and frees its license, as if the reaper culled it
+func (f *fakeLeases) cull(fingerprint string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.leases, fingerprint+"/")
+	f.available++
+}
+
+func (f *fakeLeases) stats() (claims int, extends int, releases int, leased int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.claims, f.extends, f.releases, len(f.leases)
+}
+
+// newTestServer serves the real handler, signing responses like the server does
+func newTestServer(t *testing.T, cfg *server.Config, manager *testutils.FakeManager) *httptest.Server {
+	t.Helper()
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(testutils.NewMockServer(cfg, manager)).RegisterRoutes(router)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestClient(t *testing.T, url string, fn func(cfg *client.Config)) *client.Client {
+	t.Helper()
+
+	cfg := client.NewConfig()
+	cfg.URL = url
+	cfg.MinBackoff = 10 * time.Millisecond
+	cfg.MaxBackoff = 50 * time.Millisecond
+
+	if fn != nil {
+		fn(cfg)
+	}
+
+	c, err := client.New(cfg)
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestClaim_Success(t *testing.T) {
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.Pool = "prod"
+	})
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+
+	assert.Equal(t, "test_fingerprint", lease.Fingerprint)
+	assert.Empty(t, lease.LeaseID)
+	assert.Equal(t, []byte("test_license_file"), lease.LicenseFile)
+	assert.Equal(t, "test_license_key", lease.LicenseKey)
+	assert.Equal(t, "token_1", lease.LeaseToken)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lease.ExpiresAt(), 2*time.Second)
+	assert.NoError(t, lease.Err())
+
+	require.NoError(t, lease.Close())
+
+	select {
+	case <-lease.Done():
+	default:
+		t.Fatal("lease should be done after close")
+	}
+
+	assert.ErrorIs(t, lease.Err(), client.ErrLeaseClosed)
+
+	claims, _, releases, leased := leases.stats()
+	assert.Equal(t, 1, claims)
+	assert.Equal(t, 1, releases)
+	assert.Equal(t, 0, leased)
+	assert.Equal(t, []string{"prod", "prod"}, leases.pools, "claims and releases should be sent for the pool")
+
+	// closing twice is a no-op
+	assert.NoError(t, lease.Close())
+}
+
+func TestClaimSubLease(t *testing.T) {
+	leases := newFakeLeases(2)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, nil)
+
+	primary, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	defer primary.Close()
+
+	sub, err := c.ClaimSubLease(context.Background(), "test_fingerprint", "job_1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "job_1", sub.LeaseID)
+	assert.NotEqual(t, primary.LeaseToken, sub.LeaseToken)
+
+	require.NoError(t, sub.Close())
+
+	_, _, releases, leased := leases.stats()
+	assert.Equal(t, 1, releases)
+	assert.Equal(t, 1, leased, "releasing a sub-lease should keep the primary lease")
+}
+
+func TestClaim_Retry(t *testing.T) {
+	leases := newFakeLeases(1)
+	leases.claimErrs = []licenses.OperationStatus{
+		licenses.OperationStatusNoLicensesAvailable,
+		licenses.OperationStatusNoLicensesAvailable,
+	}
+
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+	c := newTestClient(t, srv.URL, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	defer lease.Close()
+
+	claims, _, _, _ := leases.stats()
+	assert.Equal(t, 3, claims)
+}
+
+func TestClaim_RetriesExhausted(t *testing.T) {
+	leases := newFakeLeases(0)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.MaxRetries = 2
+	})
+
+	_, err := c.Claim(context.Background(), "test_fingerprint")
+	assert.ErrorIs(t, err, client.ErrNoLicensesAvailable)
+
+	var e *client.Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusGone, e.StatusCode)
+	assert.Equal(t, "no licenses available", e.Message)
+
+	claims, _, _, _ := leases.stats()
+	assert.Equal(t, 3, claims, "claim should be attempted once and retried twice")
+}
+
+func TestClaim_NotRetryable(t *testing.T) {
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	defer lease.Close()
+
+	// the node's lease is held by another process without its lease token
+	_, err = c.Claim(context.Background(), "test_fingerprint")
+
+	var e *client.Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusForbidden, e.StatusCode)
+
+	claims, _, _, _ := leases.stats()
+	assert.Equal(t, 1, claims, "forbidden claims should not be retried")
+}
+
+func TestClaim_ContextCanceled(t *testing.T) {
+	leases := newFakeLeases(0)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.MaxRetries = 100
+		cfg.MinBackoff = time.Second
+		cfg.MaxBackoff = time.Second
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Claim(ctx, "test_fingerprint")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLease_Heartbeat(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TTL = 2 * time.Second
+
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, cfg, leases.manager())
+
+	c := newTestClient(t, srv.URL, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	defer lease.Close()
+
+	claimedExpiry := lease.ExpiresAt()
+
+	// a 2s lease is extended every 1s
+	assert.Eventually(t, func() bool {
+		_, extends, _, _ := leases.stats()
+
+		return extends >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.True(t, lease.ExpiresAt().After(claimedExpiry))
+	assert.NoError(t, lease.Err())
+}
+
+func TestLease_Metadata(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TTL = 2 * time.Second
+
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, cfg, leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.Metadata = &client.Metadata{Hostname: "build-01", AppName: "builder", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}
+	})
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	defer lease.Close()
+
+	// metadata is reported on the claim and on every heartbeat
+	assert.Eventually(t, func() bool {
+		_, extends, _, _ := leases.stats()
+
+		return extends >= 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	leases.mu.Lock()
+	defer leases.mu.Unlock()
+
+	require.GreaterOrEqual(t, len(leases.metadata), 2)
+
+	for _, metadata := range leases.metadata {
+		assert.Equal(t, &db.NodeMetadata{Hostname: "build-01", AppName: "builder", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}, metadata)
+	}
+}
+
+func TestLease_HeartbeatsDisabled(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TTL = 2 * time.Second
+
+	leases := newFakeLeases(1)
+	manager := leases.manager()
+
+	// heartbeats are disabled, so extending a lease is a conflict
+	claim := manager.ClaimLicenseWithOptionsFn
+	manager.ClaimLicenseWithOptionsFn = func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+		if opts.LeaseToken() != nil && *opts.LeaseToken() != "" {
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusConflict}, nil
+		}
+
+		return claim(ctx, pool, fingerprint, opts)
+	}
+
+	srv := newTestServer(t, cfg, manager)
+	c := newTestClient(t, srv.URL, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return lease.ExpiresAt().IsZero()
+	}, 5*time.Second, 50*time.Millisecond, "lease should not expire")
+
+	assert.NoError(t, lease.Err())
+	require.NoError(t, lease.Close())
+
+	_, _, releases, _ := leases.stats()
+	assert.Equal(t, 1, releases)
+}
+
+func TestLease_Lost(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TTL = 2 * time.Second
+
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, cfg, leases.manager())
+
+	c := newTestClient(t, srv.URL, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+
+	leases.cull("test_fingerprint")
+
+	select {
+	case <-lease.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease should be lost")
+	}
+
+	assert.ErrorIs(t, lease.Err(), client.ErrLeaseLost)
+
+	// the lease claimed by the heartbeat should be released rather than leaked
+	claims, _, releases, leased := leases.stats()
+	assert.Equal(t, 2, claims)
+	assert.Equal(t, 1, releases)
+	assert.Equal(t, 0, leased)
+
+	assert.NoError(t, lease.Close())
+}
+
+func TestClaim_SigningSecret(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.SigningSecrets = []server.SigningSecret{
+		{KeyID: "2024", Secret: "test_secret_2024"},
+		{KeyID: "2025", Secret: "test_secret_2025"},
+	}
+
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, cfg, leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.SigningSecret = "test_secret_2025"
+		cfg.SigningKeyID = "2025"
+	})
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	require.NoError(t, lease.Close())
+
+	// a secret signed by another key id should be rejected
+	c = newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.SigningSecret = "test_secret_2025"
+		cfg.SigningKeyID = "2024"
+	})
+
+	_, err = c.Claim(context.Background(), "test_fingerprint")
+	assert.ErrorIs(t, err, client.ErrSignatureInvalid)
+
+	claims, _, _, _ := leases.stats()
+	assert.Equal(t, 2, claims, "signature errors should not be retried")
+}
+
+func TestClaim_SigningKey(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.SigningKey = privateKey
+
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, cfg, leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.PublicKey = publicKey
+	})
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	require.NoError(t, lease.Close())
+
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	c = newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.PublicKey = otherKey
+	})
+
+	_, err = c.Claim(context.Background(), "test_fingerprint")
+	assert.ErrorIs(t, err, client.ErrSignatureInvalid)
+}
+
+func TestClaim_SignatureMissing(t *testing.T) {
+	leases := newFakeLeases(1)
+	srv := newTestServer(t, server.NewConfig(), leases.manager())
+
+	c := newTestClient(t, srv.URL, func(cfg *client.Config) {
+		cfg.SigningSecret = "test_secret"
+	})
+
+	_, err := c.Claim(context.Background(), "test_fingerprint")
+	assert.ErrorIs(t, err, client.ErrSignatureMissing)
+}
+
+func TestClaim_UnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "relay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "relay.sock")
+
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	leases := newFakeLeases(1)
+	cfg := server.NewConfig()
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(testutils.NewMockServer(cfg, leases.manager())).RegisterRoutes(router)
+
+	srv := &http.Server{Handler: router}
+	defer srv.Close()
+
+	go srv.Serve(ln)
+
+	c := newTestClient(t, "unix://"+path, nil)
+
+	lease, err := c.Claim(context.Background(), "test_fingerprint")
+	require.NoError(t, err)
+	require.NoError(t, lease.Close())
+
+	claims, _, releases, _ := leases.stats()
+	assert.Equal(t, 1, claims)
+	assert.Equal(t, 1, releases)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(cfg *client.Config)
+		err  string
+	}{
+		{name: "unsupported scheme", fn: func(cfg *client.Config) { cfg.URL = "ftp://localhost" }, err: "unsupported url scheme"},
+		{name: "missing socket", fn: func(cfg *client.Config) { cfg.URL = "unix://" }, err: "unix socket path is required"},
+		{name: "heartbeat ratio", fn: func(cfg *client.Config) { cfg.HeartbeatRatio = 1 }, err: "heartbeat ratio must be between 0 and 1"},
+		{name: "negative retries", fn: func(cfg *client.Config) { cfg.MaxRetries = -1 }, err: "max retries must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := client.NewConfig()
+			tt.fn(cfg)
+
+			_, err := client.New(cfg)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
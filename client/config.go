@@ -0,0 +1,87 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	// URL is the relay server's URL e.g. http://localhost:6349, or the path of its unix
+	// socket e.g. unix:///run/relay.sock
+	URL string
+
+	// Pool is sent as the Relay-Pool header, where an empty pool uses the global pool or
+	// the pool the server is serving from
+	Pool string
+
+	// APIToken is sent as a bearer token, for servers requiring pool-scoped API tokens
+	APIToken string
+
+	// HTTPClient is used for requests, where nil uses a client with a sensible timeout
+	HTTPClient *http.Client
+
+	// TTL is the time-to-live to request for leases, where 0 uses the server's TTL
+	TTL time.Duration
+
+	// Metadata describes the node to the server, which is reported on every claim and
+	// heartbeat, where nil reports nothing
+	Metadata *Metadata
+
+	// Wait is how long the server should queue a claim for a license to be freed when the
+	// pool is exhausted, where 0 fails immediately
+	Wait time.Duration
+
+	// MaxRetries is how many times a claim is retried after a retryable failure, i.e. an
+	// exhausted pool, a rate limit, a server error or a network error
+	MaxRetries int
+
+	// MinBackoff and MaxBackoff bound the exponential backoff between retries, unless the
+	// server asks for a longer backoff via a Retry-After header
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// HeartbeatRatio is the fraction of a lease's expires_in after which it's extended
+	// in the background, e.g. 0.5 extends a 60s lease every 30s
+	HeartbeatRatio float64
+
+	// SigningSecret and SigningKeyID verify HMAC-SHA256 envelope signatures, where the key
+	// ID must be given when the server signs using --signing-secrets
+	SigningSecret string
+	SigningKeyID  string
+
+	// PublicKey verifies Ed25519 envelope signatures, for servers using --signing-key
+	PublicKey ed25519.PublicKey
+
+	// MaxClockSkew is how far a signature's timestamp may drift from the local clock before
+	// the response is rejected, to prevent replay attacks and detect clock tampering
+	MaxClockSkew time.Duration
+}
+
+// Metadata describes the host and application holding a lease, e.g. so that an admin can
+// tell who is holding a seat, where empty fields are not reported
+type Metadata struct {
+	Hostname   string            `json:"hostname,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	Username   string            `json:"username,omitempty"`
+	AppName    string            `json:"app_name,omitempty"`
+	AppVersion string            `json:"app_version,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// VerifySignatures returns true if responses must be signed, i.e. when a signing secret
+// or public key is configured
+func (c *Config) VerifySignatures() bool {
+	return c.SigningSecret != "" || len(c.PublicKey) > 0
+}
+
+func NewConfig() *Config {
+	return &Config{
+		URL:            "http://localhost:6349",
+		MaxRetries:     5,
+		MinBackoff:     500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		HeartbeatRatio: 0.5,
+		MaxClockSkew:   5 * time.Minute,
+	}
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minHeartbeatInterval prevents short leases from being extended in a tight loop
+const minHeartbeatInterval = time.Second
+
+// Lease is a node's lease on a license, which is extended in the background at a
+// fraction of its time-to-live until it's closed or lost
+type Lease struct {
+	Fingerprint string
+	LeaseID     string // empty for the node's primary lease
+	LicenseFile []byte
+	LicenseKey  string
+	LeaseToken  string
+
+	client *Client
+
+	mu        sync.Mutex
+	expiresAt time.Time
+	err       error
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	doneOnce sync.Once
+	stopped  chan struct{}
+	closed   sync.Once
+}
+
+func newLease(c *Client, fingerprint string, leaseID string, claim claimResponse) *Lease {
+	// heartbeats outlive the claim's context, and are only stopped by closing the lease
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lease := &Lease{
+		Fingerprint: fingerprint,
+		LeaseID:     leaseID,
+		LicenseFile: claim.LicenseFile,
+		LicenseKey:  claim.LicenseKey,
+		LeaseToken:  claim.LeaseToken,
+		client:      c,
+		expiresAt:   time.Unix(claim.ExpiresAt, 0),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+
+	go lease.heartbeat(time.Duration(claim.ExpiresIn) * time.Second)
+
+	return lease
+}
+
+// ExpiresAt returns when the lease expires unless it's extended, or a zero time when
+// heartbeats are disabled and the lease doesn't expire
+func (l *Lease) ExpiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.expiresAt
+}
+
+// Done returns a channel that's closed when the lease is lost or closed, e.g. to stop
+// using the license once its lease can no longer be extended
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns nil while the lease is held, an error wrapping ErrLeaseLost once it's lost,
+// or ErrLeaseClosed once it's closed
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Close stops extending the lease and releases it, where a lease that was already lost
+// or released by the server is not an error
+func (l *Lease) Close() error {
+	var err error
+
+	l.closed.Do(func() {
+		l.cancel()
+		<-l.stopped
+
+		if l.Err() != nil {
+			return
+		}
+
+		l.finish(ErrLeaseClosed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		for attempt := 0; ; attempt++ {
+			err = l.client.release(ctx, l.Fingerprint, l.LeaseID, l.LeaseToken)
+			if err == nil || errors.Is(err, ErrLeaseNotFound) {
+				err = nil
+
+				return
+			}
+
+			if attempt >= l.client.config.MaxRetries || !isRetryable(err) {
+				err = fmt.Errorf("failed to release lease: %w", err)
+
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				err = fmt.Errorf("failed to release lease: %w", err)
+
+				return
+			case <-time.After(l.client.backoff(attempt, err)):
+			}
+		}
+	})
+
+	return err
+}
+
+// heartbeat extends the lease at a fraction of its time-to-live, retrying failures
+// until the lease expires, at which point the lease is lost
+func (l *Lease) heartbeat(expiresIn time.Duration) {
+	defer close(l.stopped)
+
+	timer := time.NewTimer(l.client.heartbeatInterval(expiresIn))
+	defer timer.Stop()
+
+	var failures int
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		extend, err := l.client.extend(l.ctx, l)
+		if err == nil {
+			failures = 0
+
+			l.mu.Lock()
+			l.expiresAt = time.Unix(extend.ExpiresAt, 0)
+			l.mu.Unlock()
+
+			timer.Reset(l.client.heartbeatInterval(time.Duration(extend.ExpiresIn) * time.Second))
+
+			continue
+		}
+
+		if l.ctx.Err() != nil {
+			return
+		}
+
+		// the lease can't be extended when heartbeats are disabled, but it doesn't expire
+		var e *Error
+		if errors.As(err, &e) && e.StatusCode == http.StatusConflict {
+			l.mu.Lock()
+			l.expiresAt = time.Time{}
+			l.mu.Unlock()
+
+			return
+		}
+
+		// an exhausted pool means the lease was culled, so only other failures are retried
+		retryable := isRetryable(err) && !errors.Is(err, ErrNoLicensesAvailable)
+
+		if remaining := time.Until(l.ExpiresAt()); retryable && remaining > 0 {
+			timer.Reset(min(l.client.backoff(failures, err), remaining))
+			failures++
+
+			continue
+		}
+
+		l.finish(fmt.Errorf("%w: %w", ErrLeaseLost, err))
+
+		return
+	}
+}
+
+// finish records why the lease ended and closes its done channel, only once
+func (l *Lease) finish(err error) {
+	l.doneOnce.Do(func() {
+		l.mu.Lock()
+		l.err = err
+		l.mu.Unlock()
+
+		close(l.done)
+	})
+}
+
+// heartbeatInterval returns how often a lease with the given time-to-live is extended
+func (c *Client) heartbeatInterval(expiresIn time.Duration) time.Duration {
+	return max(time.Duration(float64(expiresIn)*c.config.HeartbeatRatio), minHeartbeatInterval)
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signatureAlgorithmEd25519 is the alg of v4 signatures
+const signatureAlgorithmEd25519 = "ed25519"
+
+var (
+	ErrSignatureMissing = errors.New("signature is missing")
+	ErrSignatureInvalid = errors.New("signature is invalid")
+	ErrClockSkew        = errors.New("signature timestamp is outside of the allowed clock skew")
+)
+
+// envelope is the part of an HTTP exchange covered by an envelope signature, which must
+// match the server's signing data exactly
+type envelope struct {
+	method string
+	path   string
+	status int
+	nonce  string
+	body   []byte
+}
+
+func (e envelope) message(t int64) []byte {
+	return []byte(fmt.Sprintf("%d\n%s\n%s\n%d\n%s\n%s", t, e.method, e.path, e.status, e.nonce, e.body))
+}
+
+// signature is a parsed Relay-Signature header, where only envelope signatures are kept
+// to prevent downgrades to body signatures, which can be replayed for another request
+type signature struct {
+	t   string
+	alg string
+	v3  map[string][][]byte // keyed by kid, where signatures without a kid have an empty kid
+	v4  [][]byte
+}
+
+func parseSignature(header string) (*signature, error) {
+	sig := &signature{v3: map[string][][]byte{}}
+
+	var kid string
+
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		switch k {
+		case "t":
+			sig.t = v
+		case "alg":
+			sig.alg = v
+		case "kid":
+			kid = v
+		case "v3", "v4":
+			b, err := hex.DecodeString(v)
+			if err != nil {
+				return nil, ErrSignatureInvalid
+			}
+
+			if k == "v3" {
+				sig.v3[kid] = append(sig.v3[kid], b)
+			} else {
+				sig.v4 = append(sig.v4, b)
+			}
+		}
+	}
+
+	if sig.t == "" {
+		return nil, ErrSignatureMissing
+	}
+
+	return sig, nil
+}
+
+// verifySignature verifies a response's Relay-Signature header against the request and
+// response it was received for, using every configured secret and key, and ensures that
+// the signature's timestamp is within the allowed clock skew of now
+func verifySignature(cfg *Config, header string, clock string, env envelope, now time.Time) error {
+	if header == "" {
+		return ErrSignatureMissing
+	}
+
+	sig, err := parseSignature(header)
+	if err != nil {
+		return err
+	}
+
+	// the unsigned clock header must agree with the signed timestamp
+	if clock != "" && clock != sig.t {
+		return fmt.Errorf("clock %s does not match timestamp %s: %w", clock, sig.t, ErrSignatureInvalid)
+	}
+
+	t, err := strconv.ParseInt(sig.t, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	msg := env.message(t)
+
+	if cfg.SigningSecret != "" {
+		sigs := sig.v3[cfg.SigningKeyID]
+		if len(sigs) == 0 {
+			return ErrSignatureMissing
+		}
+
+		mac := hmac.New(sha256.New, []byte(cfg.SigningSecret))
+		mac.Write(msg)
+
+		if !matchHMAC(mac.Sum(nil), sigs) {
+			return ErrSignatureInvalid
+		}
+	}
+
+	if len(cfg.PublicKey) > 0 {
+		if len(sig.v4) == 0 {
+			return ErrSignatureMissing
+		}
+
+		if sig.alg != signatureAlgorithmEd25519 {
+			return fmt.Errorf("unsupported signature algorithm %q: %w", sig.alg, ErrSignatureInvalid)
+		}
+
+		if !matchEd25519(cfg.PublicKey, msg, sig.v4) {
+			return ErrSignatureInvalid
+		}
+	}
+
+	if skew := now.Sub(time.Unix(t, 0)).Abs(); skew > cfg.MaxClockSkew {
+		return fmt.Errorf("skew of %s exceeds %s: %w", skew.Round(time.Second), cfg.MaxClockSkew, ErrClockSkew)
+	}
+
+	return nil
+}
+
+func matchHMAC(expected []byte, sigs [][]byte) bool {
+	for _, sig := range sigs {
+		if hmac.Equal(expected, sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchEd25519(publicKey ed25519.PublicKey, msg []byte, sigs [][]byte) bool {
+	for _, sig := range sigs {
+		if ed25519.Verify(publicKey, msg, sig) {
+			return true
+		}
+	}
+
+	return false
+}
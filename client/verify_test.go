@@ -0,0 +1,81 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signHMAC(secret string, env envelope, t int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(env.message(t))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	cfg := NewConfig()
+	cfg.SigningSecret = "test_secret"
+
+	now := time.Now()
+	ts := now.Unix()
+	env := envelope{method: "PUT", path: "/v1/nodes/test_fingerprint", status: 201, nonce: "test_nonce", body: []byte(`{}`)}
+	v3 := signHMAC("test_secret", env, ts)
+	t0 := strconv.FormatInt(ts, 10)
+
+	tests := []struct {
+		name   string
+		header string
+		clock  string
+		env    envelope
+		now    time.Time
+		err    error
+	}{
+		{name: "valid", header: "t=" + t0 + ",v3=" + v3, clock: t0, env: env, now: now},
+		{name: "missing header", header: "", env: env, now: now, err: ErrSignatureMissing},
+		{name: "missing timestamp", header: "v3=" + v3, env: env, now: now, err: ErrSignatureMissing},
+		{name: "body signature only", header: "t=" + t0 + ",v1=" + v3, env: env, now: now, err: ErrSignatureMissing},
+		{name: "clock mismatch", header: "t=" + t0 + ",v3=" + v3, clock: "1", env: env, now: now, err: ErrSignatureInvalid},
+		{name: "replayed nonce", header: "t=" + t0 + ",v3=" + v3, env: envelope{method: env.method, path: env.path, status: env.status, nonce: "other_nonce", body: env.body}, now: now, err: ErrSignatureInvalid},
+		{name: "swapped status", header: "t=" + t0 + ",v3=" + v3, env: envelope{method: env.method, path: env.path, status: 202, nonce: env.nonce, body: env.body}, now: now, err: ErrSignatureInvalid},
+		{name: "key id signature only", header: "t=" + t0 + ",kid=2025,v3=" + v3, env: env, now: now, err: ErrSignatureMissing},
+		{name: "clock ahead", header: "t=" + t0 + ",v3=" + v3, env: env, now: now.Add(-10 * time.Minute), err: ErrClockSkew},
+		{name: "clock behind", header: "t=" + t0 + ",v3=" + v3, env: env, now: now.Add(10 * time.Minute), err: ErrClockSkew},
+		{name: "malformed signature", header: "t=" + t0 + ",v3=zz", env: env, now: now, err: ErrSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(cfg, tt.header, tt.clock, tt.env, tt.now)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifySignature_KeyID(t *testing.T) {
+	cfg := NewConfig()
+	cfg.SigningSecret = "test_secret_2025"
+	cfg.SigningKeyID = "2025"
+
+	ts := time.Now().Unix()
+	env := envelope{method: "DELETE", path: "/v1/nodes/test_fingerprint", status: 204}
+
+	header := "t=" + strconv.FormatInt(ts, 10) +
+		",kid=2024,v3=" + signHMAC("test_secret_2024", env, ts) +
+		",kid=2025,v3=" + signHMAC("test_secret_2025", env, ts)
+
+	assert.NoError(t, verifySignature(cfg, header, "", env, time.Now()))
+
+	// the signature must follow the configured key id
+	cfg.SigningKeyID = "2024"
+	assert.ErrorIs(t, verifySignature(cfg, header, "", env, time.Now()), ErrSignatureInvalid)
+}
@@ -0,0 +1,4 @@
+DELETE FROM
+  event_types
+WHERE
+  id = 12;
@@ -0,0 +1,4 @@
+ALTER TABLE
+  nodes
+ADD
+  COLUMN ttl INTEGER;
@@ -0,0 +1,4 @@
+ALTER TABLE
+  nodes
+DROP
+  COLUMN lease_token_digest;
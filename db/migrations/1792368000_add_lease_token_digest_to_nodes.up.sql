@@ -0,0 +1,4 @@
+ALTER TABLE
+  nodes
+ADD
+  COLUMN lease_token_digest TEXT;
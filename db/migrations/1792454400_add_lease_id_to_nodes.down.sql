@@ -0,0 +1,85 @@
+-- remember which licenses are leased, since dropping the old table will set their node_id
+-- to null via its foreign key
+CREATE TABLE _leased_licenses AS
+SELECT
+  licenses.id,
+  licenses.node_id
+FROM
+  licenses
+  INNER JOIN nodes ON nodes.id = licenses.node_id
+WHERE
+  nodes.lease_id = '';
+
+-- sub-leases can't be represented without a lease_id, so they're released
+DELETE FROM
+  nodes
+WHERE
+  lease_id != '';
+
+-- rebuild the table with the old schema
+CREATE TABLE _nodes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  fingerprint TEXT UNIQUE NOT NULL,
+  last_heartbeat_at INTEGER,
+  created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+  deactivated_at INTEGER,
+  ttl INTEGER,
+  lease_token_digest TEXT
+);
+
+-- copy data from the old table to the new
+INSERT INTO
+  _nodes (
+    id,
+    fingerprint,
+    last_heartbeat_at,
+    created_at,
+    deactivated_at,
+    ttl,
+    lease_token_digest
+  )
+SELECT
+  id,
+  fingerprint,
+  last_heartbeat_at,
+  created_at,
+  deactivated_at,
+  ttl,
+  lease_token_digest
+FROM
+  nodes;
+
+-- drop the old table
+DROP TABLE nodes;
+
+-- replace with new table
+ALTER TABLE
+  _nodes RENAME TO nodes;
+
+CREATE INDEX idx_nodes_activated ON nodes (deactivated_at)
+WHERE
+  deactivated_at IS NULL;
+
+CREATE INDEX idx_nodes_last_heartbeat ON nodes (last_heartbeat_at);
+
+-- restore leases
+UPDATE
+  licenses
+SET
+  node_id = (
+    SELECT
+      node_id
+    FROM
+      _leased_licenses
+    WHERE
+      _leased_licenses.id = licenses.id
+  )
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      _leased_licenses
+  );
+
+DROP TABLE _leased_licenses;
@@ -0,0 +1,80 @@
+-- remember which licenses are leased, since dropping the old table will set their node_id
+-- to null via its foreign key
+CREATE TABLE _leased_licenses AS
+SELECT
+  id,
+  node_id
+FROM
+  licenses
+WHERE
+  node_id IS NOT NULL;
+
+-- rebuild the table with the new schema (this is a workaround for sqlite not supporting dropping unique constraints)
+CREATE TABLE _nodes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  fingerprint TEXT NOT NULL,
+  lease_id TEXT NOT NULL DEFAULT '',
+  last_heartbeat_at INTEGER,
+  created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+  deactivated_at INTEGER,
+  ttl INTEGER,
+  lease_token_digest TEXT,
+  UNIQUE (fingerprint, lease_id)
+);
+
+-- copy data from the old table to the new
+INSERT INTO
+  _nodes (
+    id,
+    fingerprint,
+    last_heartbeat_at,
+    created_at,
+    deactivated_at,
+    ttl,
+    lease_token_digest
+  )
+SELECT
+  id,
+  fingerprint,
+  last_heartbeat_at,
+  created_at,
+  deactivated_at,
+  ttl,
+  lease_token_digest
+FROM
+  nodes;
+
+-- drop the old table
+DROP TABLE nodes;
+
+-- replace with new table
+ALTER TABLE
+  _nodes RENAME TO nodes;
+
+CREATE INDEX idx_nodes_activated ON nodes (deactivated_at)
+WHERE
+  deactivated_at IS NULL;
+
+CREATE INDEX idx_nodes_last_heartbeat ON nodes (last_heartbeat_at);
+
+-- restore leases
+UPDATE
+  licenses
+SET
+  node_id = (
+    SELECT
+      node_id
+    FROM
+      _leased_licenses
+    WHERE
+      _leased_licenses.id = licenses.id
+  )
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      _leased_licenses
+  );
+
+DROP TABLE _leased_licenses;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS pool_cidrs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  pool_id INTEGER NOT NULL,
+  cidr TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('allow', 'deny')),
+  created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+  FOREIGN KEY (pool_id) REFERENCES pools(id) ON DELETE CASCADE,
+  UNIQUE (pool_id, cidr, action)
+);
@@ -0,0 +1,9 @@
+DELETE FROM
+  audit_logs
+WHERE
+  event_type_id = 13;
+
+DELETE FROM
+  event_types
+WHERE
+  id = 13;
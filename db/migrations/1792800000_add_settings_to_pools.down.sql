@@ -0,0 +1,14 @@
+ALTER TABLE
+  pools
+DROP
+  COLUMN heartbeat;
+
+ALTER TABLE
+  pools
+DROP
+  COLUMN ttl;
+
+ALTER TABLE
+  pools
+DROP
+  COLUMN strategy;
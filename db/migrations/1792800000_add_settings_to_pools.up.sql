@@ -0,0 +1,14 @@
+ALTER TABLE
+  pools
+ADD
+  COLUMN strategy TEXT;
+
+ALTER TABLE
+  pools
+ADD
+  COLUMN ttl INTEGER;
+
+ALTER TABLE
+  pools
+ADD
+  COLUMN heartbeat BOOLEAN;
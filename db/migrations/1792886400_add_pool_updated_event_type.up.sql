@@ -0,0 +1,4 @@
+INSERT INTO
+  event_types (id, name)
+VALUES
+  (14, 'pool.updated');
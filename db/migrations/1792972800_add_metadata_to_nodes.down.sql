@@ -0,0 +1,29 @@
+ALTER TABLE
+  nodes
+DROP
+  COLUMN labels;
+
+ALTER TABLE
+  nodes
+DROP
+  COLUMN app_version;
+
+ALTER TABLE
+  nodes
+DROP
+  COLUMN app_name;
+
+ALTER TABLE
+  nodes
+DROP
+  COLUMN username;
+
+ALTER TABLE
+  nodes
+DROP
+  COLUMN platform;
+
+ALTER TABLE
+  nodes
+DROP
+  COLUMN hostname;
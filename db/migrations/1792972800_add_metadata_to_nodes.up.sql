@@ -0,0 +1,29 @@
+ALTER TABLE
+  nodes
+ADD
+  COLUMN hostname TEXT;
+
+ALTER TABLE
+  nodes
+ADD
+  COLUMN platform TEXT;
+
+ALTER TABLE
+  nodes
+ADD
+  COLUMN username TEXT;
+
+ALTER TABLE
+  nodes
+ADD
+  COLUMN app_name TEXT;
+
+ALTER TABLE
+  nodes
+ADD
+  COLUMN app_version TEXT;
+
+ALTER TABLE
+  nodes
+ADD
+  COLUMN labels TEXT;
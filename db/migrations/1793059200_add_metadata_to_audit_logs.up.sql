@@ -0,0 +1,4 @@
+ALTER TABLE
+  audit_logs
+ADD
+  COLUMN metadata TEXT;
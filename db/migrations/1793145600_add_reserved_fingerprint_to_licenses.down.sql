@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_licenses_reserved_fingerprint;
+
+ALTER TABLE
+  licenses
+DROP
+  COLUMN reserved_fingerprint;
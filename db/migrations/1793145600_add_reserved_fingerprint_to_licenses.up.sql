@@ -0,0 +1,6 @@
+ALTER TABLE
+  licenses
+ADD
+  COLUMN reserved_fingerprint TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_licenses_reserved_fingerprint ON licenses(reserved_fingerprint);
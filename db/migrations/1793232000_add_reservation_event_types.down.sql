@@ -0,0 +1,9 @@
+DELETE FROM
+  audit_logs
+WHERE
+  event_type_id IN (15, 16);
+
+DELETE FROM
+  event_types
+WHERE
+  id IN (15, 16);
@@ -0,0 +1,5 @@
+INSERT INTO
+  event_types (id, name)
+VALUES
+  (15, 'license.reserved'),
+  (16, 'license.unreserved');
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_licenses_last_node_id;
+
+ALTER TABLE
+  licenses
+DROP
+  COLUMN last_node_id;
@@ -0,0 +1,13 @@
+ALTER TABLE
+  licenses
+ADD
+  COLUMN last_node_id INTEGER;
+
+UPDATE
+  licenses
+SET
+  last_node_id = node_id
+WHERE
+  node_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_licenses_last_node_id ON licenses(last_node_id);
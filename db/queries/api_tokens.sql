@@ -0,0 +1,25 @@
+-- name: InsertAPIToken :one
+INSERT INTO api_tokens (digest, pool_id)
+VALUES (?, ?)
+RETURNING *;
+
+-- name: GetAPITokens :many
+SELECT *
+FROM api_tokens
+ORDER BY id;
+
+-- name: GetAPITokenByDigest :one
+SELECT *
+FROM api_tokens
+WHERE digest = ?;
+
+-- name: TouchAPITokenByID :one
+UPDATE api_tokens
+SET uses = uses + 1, last_used_at = unixepoch()
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteAPITokenByID :one
+DELETE FROM api_tokens
+WHERE id = ?
+RETURNING *;
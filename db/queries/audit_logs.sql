@@ -1,6 +1,6 @@
 -- name: InsertAuditLog :exec
-INSERT INTO audit_logs (event_type_id, entity_type_id, entity_id, pool_id)
-VALUES (?, ?, ?, ?);
+INSERT INTO audit_logs (event_type_id, entity_type_id, entity_id, pool_id, metadata)
+VALUES (?, ?, ?, ?, ?);
 
 -- name: GetAuditLogs :many
 SELECT id, event_type_id, entity_type_id, entity_id, pool_id, created_at
@@ -13,3 +13,43 @@ SELECT id, event_type_id, entity_type_id, entity_id, pool_id, created_at
 FROM audit_logs
 WHERE entity_type_id = ? AND entity_id = ?
 ORDER BY created_at DESC;
+
+-- name: GetLastAuditLogID :one
+SELECT CAST(COALESCE(MAX(id), 0) AS INTEGER)
+FROM audit_logs;
+
+-- name: GetEventsAfterID :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ?
+ORDER BY audit_logs.id
+LIMIT ?;
+
+-- name: GetEventsAfterIDWithoutPool :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ? AND audit_logs.pool_id IS NULL
+ORDER BY audit_logs.id
+LIMIT ?;
+
+-- name: GetEventsAfterIDWithPool :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ? AND audit_logs.pool_id = ?
+ORDER BY audit_logs.id
+LIMIT ?;
@@ -0,0 +1,41 @@
+-- name: GetLeases :many
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL
+ORDER BY nodes.id
+LIMIT ? OFFSET ?;
+
+-- name: GetLeasesWithoutPool :many
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL AND licenses.pool_id IS NULL
+ORDER BY nodes.id
+LIMIT ? OFFSET ?;
+
+-- name: GetLeasesWithPool :many
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL AND licenses.pool_id = ?
+ORDER BY nodes.id
+LIMIT ? OFFSET ?;
+
+-- name: GetLeaseByFingerprint :one
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL;
+
+-- name: GetLeaseWithoutPoolByFingerprint :one
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL AND licenses.pool_id IS NULL;
+
+-- name: GetLeaseWithPoolByFingerprint :one
+SELECT sqlc.embed(nodes), sqlc.embed(licenses)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL AND licenses.pool_id = ?;
@@ -62,82 +62,142 @@ WHERE node_id = ? AND pool_id = ?;
 
 -- name: ClaimLicenseWithoutPoolFIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY l.created_at ASC
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at ASC
     LIMIT 1
 )
 RETURNING *;
 
 -- name: ClaimLicenseWithPoolFIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY l.created_at ASC
+    WHERE l.node_id IS NULL AND l.pool_id = sqlc.arg(pool_id) AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at ASC
     LIMIT 1
 )
 RETURNING *;
 
 -- name: ClaimLicenseWithoutPoolLIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY l.created_at DESC
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at DESC
     LIMIT 1
 )
 RETURNING *;
 
 -- name: ClaimLicenseWithPoolLIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
-     SELECT l.id
+    SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY l.created_at DESC
+    WHERE l.node_id IS NULL AND l.pool_id = sqlc.arg(pool_id) AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at DESC
     LIMIT 1
 )
 RETURNING *;
 
 -- name: ClaimLicenseWithoutPoolRandom :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY RANDOM()
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, RANDOM()
     LIMIT 1
 )
 RETURNING *;
 
 -- name: ClaimLicenseWithPoolRandom :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
+WHERE id = (
+    SELECT l.id
+    FROM licenses l
+    WHERE l.node_id IS NULL AND l.pool_id = sqlc.arg(pool_id) AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, RANDOM()
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: ClaimLicenseWithoutPoolAffinity :one
+UPDATE licenses
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY RANDOM()
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id)) OR
+        (l.reserved_fingerprint IS NULL AND l.last_node_id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.last_claimed_at DESC
     LIMIT 1
 )
 RETURNING *;
 
+-- name: ClaimLicenseWithPoolAffinity :one
+UPDATE licenses
+SET node_id = sqlc.arg(node_id), last_node_id = sqlc.arg(node_id), last_claimed_at = unixepoch(), claims = claims + 1
+WHERE id = (
+    SELECT l.id
+    FROM licenses l
+    WHERE l.node_id IS NULL AND l.pool_id = sqlc.arg(pool_id) AND (
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = sqlc.arg(node_id)) OR
+        (l.reserved_fingerprint IS NULL AND l.last_node_id = sqlc.arg(node_id))
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.last_claimed_at DESC
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: SetLicenseReservedFingerprintByID :one
+UPDATE licenses
+SET reserved_fingerprint = ?
+WHERE id = ?
+RETURNING *;
+
 -- name: ReleaseLicensesFromDeadNodes :many
 UPDATE licenses
 SET node_id = NULL, last_released_at = unixepoch()
 WHERE node_id IN (
     SELECT id FROM nodes
-    WHERE last_heartbeat_at <= strftime('%s', 'now', ?) AND deactivated_at IS NULL
-)
+    WHERE last_heartbeat_at + COALESCE(nodes.ttl, sqlc.arg(default_ttl)) <= unixepoch() AND deactivated_at IS NULL
+) AND COALESCE((SELECT heartbeat FROM pools WHERE pools.id = licenses.pool_id), CAST(sqlc.arg(default_heartbeat) AS BOOLEAN))
 RETURNING *;
 
+
+-- name: GetLicenseStats :many
+SELECT pool_id, COUNT(*) AS total, COUNT(node_id) AS leased
+FROM licenses
+GROUP BY pool_id
+ORDER BY pool_id;
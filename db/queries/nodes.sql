@@ -1,26 +1,58 @@
 -- name: ActivateNode :one
-INSERT INTO nodes (fingerprint)
-VALUES (?)
-ON CONFLICT (fingerprint) DO UPDATE SET deactivated_at = NULL
+INSERT INTO nodes (fingerprint, lease_id)
+VALUES (?, ?)
+ON CONFLICT (fingerprint, lease_id) DO UPDATE SET deactivated_at = NULL
 RETURNING *;
 
 -- name: GetNodeByFingerprint :one
 SELECT *
 FROM nodes
-WHERE fingerprint = ? AND deactivated_at IS NULL;
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
+
+-- name: GetNodeByID :one
+SELECT *
+FROM nodes
+WHERE id = ?;
 
 -- name: PingNodeHeartbeatByFingerprint :exec
 UPDATE nodes
 SET last_heartbeat_at = unixepoch()
-WHERE fingerprint = ? AND deactivated_at IS NULL;
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
 
 -- name: DeactivateNodeByFingerprint :exec
 UPDATE nodes
 SET deactivated_at = unixepoch()
-WHERE fingerprint = ? AND deactivated_at IS NULL;
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
 
 -- name: DeactivateDeadNodes :many
 UPDATE nodes
 SET deactivated_at = unixepoch()
-WHERE last_heartbeat_at <= strftime('%s', 'now', ?) AND deactivated_at IS NULL
-RETURNING *;
+WHERE last_heartbeat_at + COALESCE(ttl, sqlc.arg(default_ttl)) <= unixepoch() AND deactivated_at IS NULL
+  AND id NOT IN (SELECT node_id FROM licenses WHERE node_id IS NOT NULL)
+RETURNING (SELECT pool_id FROM licenses WHERE licenses.last_node_id = nodes.id ORDER BY last_released_at DESC LIMIT 1) AS pool_id, *;
+
+-- name: SetNodeTTLByFingerprint :exec
+UPDATE nodes
+SET ttl = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
+
+-- name: SetNodeLeaseTokenDigestByFingerprint :exec
+UPDATE nodes
+SET lease_token_digest = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
+
+-- name: SetNodeMetadataByFingerprint :exec
+UPDATE nodes
+SET hostname = ?, platform = ?, username = ?, app_name = ?, app_version = ?, labels = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL;
+
+-- name: CountActiveNodes :one
+SELECT COUNT(DISTINCT fingerprint)
+FROM nodes
+WHERE deactivated_at IS NULL;
+
+-- name: CountLeasesByFingerprint :one
+SELECT COUNT(*)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.deactivated_at IS NULL;
@@ -0,0 +1,20 @@
+-- name: InsertPoolCIDR :one
+INSERT INTO pool_cidrs (pool_id, cidr, action)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetPoolCIDRs :many
+SELECT *
+FROM pool_cidrs
+ORDER BY pool_id, action, id;
+
+-- name: GetPoolCIDRsByPoolID :many
+SELECT *
+FROM pool_cidrs
+WHERE pool_id = ?
+ORDER BY action, id;
+
+-- name: DeletePoolCIDR :one
+DELETE FROM pool_cidrs
+WHERE pool_id = ? AND cidr = ? AND action = ?
+RETURNING *;
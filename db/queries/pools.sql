@@ -22,3 +22,9 @@ ORDER BY id;
 DELETE FROM pools
 WHERE id = ?
 RETURNING *;
+
+-- name: UpdatePoolSettingsByID :one
+UPDATE pools
+SET strategy = ?, ttl = ?, heartbeat = ?
+WHERE id = ?
+RETURNING *;
@@ -3,6 +3,7 @@ package cmd
 import (
 	"strings"
 
+	"github.com/keygen-sh/keygen-go/v3"
 	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/locker"
 	"github.com/keygen-sh/keygen-relay/internal/output"
@@ -23,6 +24,9 @@ func AddCmd(manager licenses.Manager) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			publicKey = strings.TrimSpace(publicKey)
 
+			// FIXME(ezekg) add support for non-global config to SDK
+			keygen.PublicKey = publicKey
+
 			// workaround for lack of support for nullable string flags
 			if p, err := cmd.Flags().GetString("pool"); err == nil {
 				if p != "" {
@@ -54,7 +58,7 @@ func AddCmd(manager licenses.Manager) *cobra.Command {
 				file := files[i]
 				key := strings.TrimSpace(keys[i])
 
-				license, err := manager.AddLicense(cmd.Context(), pool, file, key, publicKey)
+				license, err := manager.AddLicense(cmd.Context(), pool, file, key)
 				if err != nil {
 					output.PrintError(cmd.ErrOrStderr(), err.Error())
 
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/keygen-sh/keygen-go/v3"
 	"github.com/keygen-sh/keygen-relay/internal/cmd"
 	"github.com/keygen-sh/keygen-relay/internal/db"
 	"github.com/keygen-sh/keygen-relay/internal/testutils"
@@ -14,7 +15,9 @@ import (
 
 func TestAddCmd_Success(t *testing.T) {
 	manager := &testutils.FakeManager{
-		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error) {
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
+			assert.Equal(t, "testpublickey", keygen.PublicKey)
+
 			return &db.License{Guid: "test" + key}, nil
 		},
 	}
@@ -36,7 +39,7 @@ func TestAddCmd_Success(t *testing.T) {
 
 func TestAddCmd_Error(t *testing.T) {
 	manager := &testutils.FakeManager{
-		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error) {
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
 			return nil, errors.New("failed to add license")
 		},
 	}
@@ -58,7 +61,7 @@ func TestAddCmd_Error(t *testing.T) {
 
 func TestAdd_MultiSuccess(t *testing.T) {
 	manager := &testutils.FakeManager{
-		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error) {
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
 			return &db.License{Guid: "test" + key}, nil
 		},
 	}
@@ -81,7 +84,7 @@ func TestAdd_MultiSuccess(t *testing.T) {
 
 func TestAdd_MultiError(t *testing.T) {
 	manager := &testutils.FakeManager{
-		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error) {
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
 			return &db.License{Guid: "test_" + key}, nil
 		},
 	}
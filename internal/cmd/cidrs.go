@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/output"
+	"github.com/keygen-sh/keygen-relay/internal/try"
+	"github.com/keygen-sh/keygen-relay/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func CIDRsCmd(manager licenses.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "cidrs",
+		Short:        "manage the cidr ranges allowed or denied access to a pool",
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(cidrsAddCmd(manager))
+	cmd.AddCommand(cidrsLsCmd(manager))
+	cmd.AddCommand(cidrsDelCmd(manager))
+
+	return cmd
+}
+
+// cidrFlags returns the cidr ranges given via the --allow and --deny flags, along with
+// their action
+func cidrFlags(cmd *cobra.Command) (map[licenses.CIDRAction][]string, error) {
+	allow, err := cmd.Flags().GetStringSlice("allow")
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := cmd.Flags().GetStringSlice("deny")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, errors.New("at least one --allow or --deny cidr range is required")
+	}
+
+	return map[licenses.CIDRAction][]string{
+		licenses.CIDRActionAllow: allow,
+		licenses.CIDRActionDeny:  deny,
+	}, nil
+}
+
+func cidrsAddCmd(manager licenses.Manager) *cobra.Command {
+	var pool string
+
+	cmd := &cobra.Command{
+		Use:          "add",
+		Short:        "allow or deny cidr range(s) access to a pool",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cidrs, err := cidrFlags(cmd)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			for _, action := range []licenses.CIDRAction{licenses.CIDRActionAllow, licenses.CIDRActionDeny} {
+				for _, cidr := range cidrs[action] {
+					poolCIDR, err := manager.AddPoolCIDR(cmd.Context(), pool, cidr, action)
+					if err != nil {
+						output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+						return nil
+					}
+
+					output.PrintSuccess(cmd.OutOrStdout(), "cidr range added successfully: %s %s", action, poolCIDR.Cidr)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pool, "pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to add the cidr range(s) to [$RELAY_POOL=prod]")
+	cmd.Flags().StringSlice("allow", nil, "cidr range to allow access to the pool e.g. 10.20.0.0/16")
+	cmd.Flags().StringSlice("deny", nil, "cidr range to deny access to the pool e.g. 10.20.5.0/24")
+	_ = cmd.MarkFlagRequired("pool")
+
+	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
+
+	return cmd
+}
+
+func cidrsLsCmd(manager licenses.Manager) *cobra.Command {
+	var plain bool
+
+	cmd := &cobra.Command{
+		Use:          "ls",
+		Short:        "print the cidr ranges allowed or denied access to each pool",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			poolList, err := manager.GetPools(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			pools := make(map[int64]string, len(poolList))
+			for _, p := range poolList {
+				pools[p.ID] = p.Name
+			}
+
+			cidrs, err := manager.ListPoolCIDRs(cmd.Context())
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			if len(cidrs) == 0 {
+				output.PrintSuccess(cmd.OutOrStdout(), "no cidr ranges found")
+
+				return nil
+			}
+
+			var renderer ui.TableRenderer
+			if plain {
+				renderer = ui.NewSimpleTableRenderer(cmd.OutOrStdout())
+			} else {
+				renderer = ui.NewBubbleteaTableRenderer()
+			}
+
+			columns := []table.Column{
+				{Title: "pool", Width: 8}, // start with min width
+				{Title: "action", Width: 8},
+				{Title: "cidr", Width: 18},
+				{Title: "created_at", Width: 20},
+			}
+
+			tableRows := make([]table.Row, 0, len(cidrs))
+			for _, cidr := range cidrs {
+				poolStr, ok := pools[cidr.PoolID]
+				if !ok {
+					poolStr = "<n/a>" // should never happen
+				}
+
+				// update pool column width dynamically
+				if poolWidth := len(poolStr); poolWidth > columns[0].Width && poolWidth <= 32 {
+					columns[0].Width = poolWidth
+				} else if poolWidth > 32 {
+					columns[0].Width = 32
+				}
+
+				// ipv6 ranges can be up to 43 characters
+				if cidrWidth := len(cidr.Cidr); cidrWidth > columns[2].Width {
+					columns[2].Width = cidrWidth
+				}
+
+				tableRows = append(tableRows, table.Row{poolStr, cidr.Action, cidr.Cidr, formatTime(&cidr.CreatedAt)})
+			}
+
+			if err := renderer.Render(tableRows, columns); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("error rendering table: %v", err))
+
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&plain, "plain", try.Try(try.EnvBool("RELAY_PLAIN"), try.EnvBool("NO_COLOR"), try.EnvBool("CI"), try.Static(false)), "display the table in plain text format [$RELAY_PLAIN=1]")
+
+	return cmd
+}
+
+func cidrsDelCmd(manager licenses.Manager) *cobra.Command {
+	var pool string
+
+	cmd := &cobra.Command{
+		Use:          "del",
+		Short:        "remove allowed or denied cidr range(s) from a pool",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cidrs, err := cidrFlags(cmd)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			for _, action := range []licenses.CIDRAction{licenses.CIDRActionAllow, licenses.CIDRActionDeny} {
+				for _, cidr := range cidrs[action] {
+					if err := manager.RemovePoolCIDR(cmd.Context(), pool, cidr, action); err != nil {
+						output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+						return nil
+					}
+
+					output.PrintSuccess(cmd.OutOrStdout(), "cidr range deleted successfully: %s %s", action, cidr)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pool, "pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to remove the cidr range(s) from [$RELAY_POOL=prod]")
+	cmd.Flags().StringSlice("allow", nil, "allowed cidr range to remove from the pool")
+	cmd.Flags().StringSlice("deny", nil, "denied cidr range to remove from the pool")
+	_ = cmd.MarkFlagRequired("pool")
+
+	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
+
+	return cmd
+}
@@ -0,0 +1,173 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/keygen-sh/keygen-relay/internal/cmd"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCIDRsAddCmd_Success(t *testing.T) {
+	var added []string
+
+	manager := &testutils.FakeManager{
+		AddPoolCIDRFn: func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) (*db.PoolCidr, error) {
+			added = append(added, fmt.Sprintf("%s %s %s", pool, action, cidr))
+
+			return &db.PoolCidr{Cidr: cidr, Action: string(action)}, nil
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(outBuf)
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"add", "--pool=prod", "--allow=10.20.0.0/16", "--deny=10.20.5.0/24"})
+
+	err := cidrsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "cidr range added successfully: allow 10.20.0.0/16")
+	assert.Contains(t, outBuf.String(), "cidr range added successfully: deny 10.20.5.0/24")
+	assert.Equal(t, []string{"prod allow 10.20.0.0/16", "prod deny 10.20.5.0/24"}, added)
+}
+
+func TestCIDRsAddCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		AddPoolCIDRFn: func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) (*db.PoolCidr, error) {
+			return nil, fmt.Errorf("cidr %s: %w", cidr, licenses.ErrBadCIDR)
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(new(bytes.Buffer))
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"add", "--pool=prod", "--allow=bogus"})
+
+	_ = cidrsCmd.Execute()
+
+	assert.Contains(t, errBuf.String(), "error: cidr bogus: invalid cidr range")
+}
+
+func TestCIDRsAddCmd_MissingRange(t *testing.T) {
+	cidrsCmd := cmd.CIDRsCmd(&testutils.FakeManager{})
+
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(new(bytes.Buffer))
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"add", "--pool=prod"})
+
+	_ = cidrsCmd.Execute()
+
+	assert.Contains(t, errBuf.String(), "at least one --allow or --deny cidr range is required")
+}
+
+func TestCIDRsLsCmd_Success(t *testing.T) {
+	manager := &testutils.FakeManager{
+		GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+			return []db.Pool{{ID: 1, Name: "prod"}}, nil
+		},
+		ListPoolCIDRsFn: func(ctx context.Context) ([]db.PoolCidr, error) {
+			return []db.PoolCidr{
+				{ID: 1, PoolID: 1, Cidr: "10.20.0.0/16", Action: "allow", CreatedAt: 1764949400},
+				{ID: 2, PoolID: 1, Cidr: "10.20.5.0/24", Action: "deny", CreatedAt: 1764949400},
+			}, nil
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(outBuf)
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := cidrsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "prod")
+	assert.Contains(t, outBuf.String(), "10.20.0.0/16")
+	assert.Contains(t, outBuf.String(), "10.20.5.0/24")
+}
+
+func TestCIDRsLsCmd_Empty(t *testing.T) {
+	manager := &testutils.FakeManager{
+		ListPoolCIDRsFn: func(ctx context.Context) ([]db.PoolCidr, error) {
+			return []db.PoolCidr{}, nil
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(outBuf)
+	cidrsCmd.SetErr(new(bytes.Buffer))
+
+	cidrsCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := cidrsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, outBuf.String(), "no cidr ranges found")
+}
+
+func TestCIDRsDelCmd_Success(t *testing.T) {
+	var removed []string
+
+	manager := &testutils.FakeManager{
+		RemovePoolCIDRFn: func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) error {
+			removed = append(removed, fmt.Sprintf("%s %s %s", pool, action, cidr))
+
+			return nil
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(outBuf)
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"del", "--pool=prod", "--deny=10.20.5.0/24"})
+
+	err := cidrsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "cidr range deleted successfully: deny 10.20.5.0/24")
+	assert.Equal(t, []string{"prod deny 10.20.5.0/24"}, removed)
+}
+
+func TestCIDRsDelCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		RemovePoolCIDRFn: func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) error {
+			return fmt.Errorf("cidr %s: %w", cidr, licenses.ErrCIDRNotFound)
+		},
+	}
+
+	cidrsCmd := cmd.CIDRsCmd(manager)
+
+	errBuf := new(bytes.Buffer)
+	cidrsCmd.SetOut(new(bytes.Buffer))
+	cidrsCmd.SetErr(errBuf)
+
+	cidrsCmd.SetArgs([]string{"del", "--pool=prod", "--allow=10.0.0.0/8"})
+
+	_ = cidrsCmd.Execute()
+
+	assert.Contains(t, errBuf.String(), "error: cidr 10.0.0.0/8: cidr range not found")
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func strategyTypeCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	return []string{"fifo", "lifo", "rand"}, cobra.ShellCompDirectiveDefault
+	return []string{"fifo", "lifo", "rand", "affinity"}, cobra.ShellCompDirectiveDefault
 }
 
 func poolTypeCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
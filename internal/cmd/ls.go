@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/keygen-sh/keygen-relay/internal/db"
 	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/output"
 	"github.com/keygen-sh/keygen-relay/internal/try"
@@ -13,6 +18,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// leasesPageSize is how many leases are fetched at a time when listing leased nodes
+const leasesPageSize = 100
+
 func LsCmd(manager licenses.Manager) *cobra.Command {
 	var (
 		plain bool
@@ -53,6 +61,13 @@ func LsCmd(manager licenses.Manager) *cobra.Command {
 				return nil
 			}
 
+			nodes, err := leasedNodes(cmd.Context(), manager, pool)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
 			var renderer ui.TableRenderer
 			if plain {
 				renderer = ui.NewSimpleTableRenderer(cmd.OutOrStdout())
@@ -65,6 +80,10 @@ func LsCmd(manager licenses.Manager) *cobra.Command {
 				{Title: "pool", Width: 8}, // start with min width
 				{Title: "claims", Width: 8},
 				{Title: "node_id", Width: 8},
+				{Title: "reserved_for", Width: 16},
+				{Title: "hostname", Width: 16},
+				{Title: "username", Width: 12},
+				{Title: "app", Width: 20},
 				{Title: "last_claimed_at", Width: 20},
 				{Title: "last_released_at", Width: 20},
 			}
@@ -98,10 +117,24 @@ func LsCmd(manager licenses.Manager) *cobra.Command {
 					columns[1].Width = 32
 				}
 
+				reservedForStr := "-"
+				if lic.ReservedFingerprint != nil {
+					reservedForStr = *lic.ReservedFingerprint
+				}
+
+				hostnameStr, usernameStr, appStr := "-", "-", "-"
+				if node, ok := nodes[lic.ID]; ok {
+					if metadata := node.NodeMetadata(); metadata != nil {
+						hostnameStr = formatString(metadata.Hostname)
+						usernameStr = formatString(metadata.Username)
+						appStr = formatApp(metadata)
+					}
+				}
+
 				lastClaimedAtStr := formatTime(lic.LastClaimedAt)
 				lastReleasedAtStr := formatTime(lic.LastReleasedAt)
 
-				tableRows = append(tableRows, table.Row{lic.Guid, poolStr, claimsStr, nodeIDStr, lastClaimedAtStr, lastReleasedAtStr})
+				tableRows = append(tableRows, table.Row{lic.Guid, poolStr, claimsStr, nodeIDStr, reservedForStr, hostnameStr, usernameStr, appStr, lastClaimedAtStr, lastReleasedAtStr})
 			}
 
 			if err := renderer.Render(tableRows, columns); err != nil {
@@ -129,3 +162,52 @@ func formatTime(t *int64) string {
 
 	return time.Unix(*t, 0).UTC().Format(time.RFC3339)
 }
+
+func formatString(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// formatApp formats the application reported by a node as its name and version
+func formatApp(metadata *db.NodeMetadata) string {
+	app := strings.TrimSpace(metadata.AppName + " " + metadata.AppVersion)
+
+	return formatString(app)
+}
+
+// formatLabels formats a node's labels as comma-separated key=value pairs, sorted by key
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// leasedNodes returns the node holding each leased license, keyed by license ID
+func leasedNodes(ctx context.Context, manager licenses.Manager, pool *string) (map[int64]db.Node, error) {
+	nodes := make(map[int64]db.Node)
+
+	for offset := int64(0); ; offset += leasesPageSize {
+		leases, err := manager.ListLeases(ctx, pool, leasesPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, lease := range leases {
+			nodes[lease.License.ID] = lease.Node
+		}
+
+		if len(leases) < leasesPageSize {
+			return nodes, nil
+		}
+	}
+}
@@ -36,6 +36,42 @@ func TestLsCmd_Success(t *testing.T) {
 	assert.Contains(t, outBuf.String(), "License_2")
 }
 
+func TestLsCmd_NodeMetadata(t *testing.T) {
+	nodeID := int64(123)
+	hostname, username, appName, appVersion := "build-01", "ci", "builder", "1.2.3"
+
+	manager := &testutils.FakeManager{
+		ListLicensesFn: func(ctx context.Context, pool *string) ([]db.License, error) {
+			return []db.License{
+				{ID: 1, Guid: "License_1", Key: "License_Key_1", Claims: 5, NodeID: &nodeID},
+				{ID: 2, Guid: "License_2", Key: "License_Key_2", Claims: 10},
+			}, nil
+		},
+		ListLeasesFn: func(ctx context.Context, pool *string, limit int64, offset int64) ([]db.Lease, error) {
+			return []db.Lease{
+				{
+					Node:    db.Node{ID: nodeID, Hostname: &hostname, Username: &username, AppName: &appName, AppVersion: &appVersion},
+					License: db.License{ID: 1, Guid: "License_1"},
+				},
+			}, nil
+		},
+	}
+
+	outBuf := new(bytes.Buffer)
+
+	lsCmd := cmd.LsCmd(manager)
+	lsCmd.SetOut(outBuf)
+	lsCmd.SetArgs([]string{"--plain"})
+
+	err := lsCmd.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, outBuf.String(), "hostname")
+	assert.Contains(t, outBuf.String(), "build-01")
+	assert.Contains(t, outBuf.String(), "ci")
+	assert.Contains(t, outBuf.String(), "builder 1.2.3")
+}
+
 func TestLsCmd_NoLicenses(t *testing.T) {
 	manager := &testutils.FakeManager{
 		ListLicensesFn: func(ctx context.Context, pool *string) ([]db.License, error) {
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/output"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/try"
+	"github.com/keygen-sh/keygen-relay/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func PoolsCmd(manager licenses.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "pools",
+		Short:        "manage the strategy, ttl and heartbeat settings of each pool",
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(poolsLsCmd(manager))
+	cmd.AddCommand(poolsSetCmd(manager))
+	cmd.AddCommand(poolsResetCmd(manager))
+
+	return cmd
+}
+
+func poolsLsCmd(manager licenses.Manager) *cobra.Command {
+	var plain bool
+
+	cmd := &cobra.Command{
+		Use:          "ls",
+		Short:        "print the pools and their settings",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pools, err := manager.GetPools(cmd.Context())
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			if len(pools) == 0 {
+				output.PrintSuccess(cmd.OutOrStdout(), "no pools found")
+
+				return nil
+			}
+
+			var renderer ui.TableRenderer
+			if plain {
+				renderer = ui.NewSimpleTableRenderer(cmd.OutOrStdout())
+			} else {
+				renderer = ui.NewBubbleteaTableRenderer()
+			}
+
+			columns := []table.Column{
+				{Title: "id", Width: 8},
+				{Title: "pool", Width: 8}, // start with min width
+				{Title: "strategy", Width: 10},
+				{Title: "ttl", Width: 10},
+				{Title: "heartbeats", Width: 10},
+				{Title: "created_at", Width: 20},
+			}
+
+			tableRows := make([]table.Row, 0, len(pools))
+			for _, pool := range pools {
+				// update pool column width dynamically
+				if poolWidth := len(pool.Name); poolWidth > columns[1].Width && poolWidth <= 32 {
+					columns[1].Width = poolWidth
+				} else if poolWidth > 32 {
+					columns[1].Width = 32
+				}
+
+				// unset settings fall back to the server's defaults
+				strategy, ttl, heartbeat := "-", "-", "-"
+				if pool.Strategy != nil {
+					strategy = *pool.Strategy
+				}
+
+				if pool.Ttl != nil {
+					ttl = (time.Duration(*pool.Ttl) * time.Second).String()
+				}
+
+				if pool.Heartbeat != nil {
+					heartbeat = strconv.FormatBool(*pool.Heartbeat)
+				}
+
+				tableRows = append(tableRows, table.Row{
+					strconv.FormatInt(pool.ID, 10),
+					pool.Name,
+					strategy,
+					ttl,
+					heartbeat,
+					formatTime(&pool.CreatedAt),
+				})
+			}
+
+			if err := renderer.Render(tableRows, columns); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("error rendering table: %v", err))
+
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&plain, "plain", try.Try(try.EnvBool("RELAY_PLAIN"), try.EnvBool("NO_COLOR"), try.EnvBool("CI"), try.Static(false)), "display the table in plain text format [$RELAY_PLAIN=1]")
+
+	return cmd
+}
+
+func poolsSetCmd(manager licenses.Manager) *cobra.Command {
+	var (
+		pool     string
+		strategy server.StrategyType
+	)
+
+	cmd := &cobra.Command{
+		Use:          "set",
+		Short:        "override the server's default settings for a pool",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var settings licenses.PoolSettings
+
+			// only settings that were given are changed
+			if cmd.Flags().Changed("strategy") {
+				s := string(strategy)
+				settings.Strategy = &s
+			}
+
+			if cmd.Flags().Changed("ttl") {
+				ttl, err := cmd.Flags().GetDuration("ttl")
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return nil
+				}
+
+				if err := validateTTL(ttl); err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return nil
+				}
+
+				settings.TTL = &ttl
+			}
+
+			if cmd.Flags().Changed("heartbeats") {
+				heartbeat, err := cmd.Flags().GetBool("heartbeats")
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return nil
+				}
+
+				settings.Heartbeat = &heartbeat
+			}
+
+			if settings.Strategy == nil && settings.TTL == nil && settings.Heartbeat == nil {
+				output.PrintError(cmd.ErrOrStderr(), "at least one of --strategy, --ttl or --heartbeats is required")
+
+				return nil
+			}
+
+			if _, err := manager.UpdatePool(cmd.Context(), pool, settings); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			output.PrintSuccess(cmd.OutOrStdout(), "pool updated successfully: %s", pool)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pool, "pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to update [$RELAY_POOL=prod]")
+	cmd.Flags().Var(&strategy, "strategy", `strategy for license distribution from the pool e.g. "fifo", "lifo", "rand", or "affinity"`)
+	cmd.Flags().Duration("ttl", 0, "time-to-live for leases in the pool e.g. 8h")
+	cmd.Flags().Bool("heartbeats", true, "whether or not leases in the pool are extended by heartbeats and expire without them e.g. --heartbeats=false")
+	_ = cmd.MarkFlagRequired("pool")
+
+	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
+
+	return cmd
+}
+
+func poolsResetCmd(manager licenses.Manager) *cobra.Command {
+	var pool string
+
+	cmd := &cobra.Command{
+		Use:          "reset",
+		Short:        "reset a pool's settings to the server's defaults",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := manager.ResetPool(cmd.Context(), pool); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			output.PrintSuccess(cmd.OutOrStdout(), "pool reset successfully: %s", pool)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pool, "pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to reset [$RELAY_POOL=prod]")
+	_ = cmd.MarkFlagRequired("pool")
+
+	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
+
+	return cmd
+}
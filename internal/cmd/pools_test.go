@@ -0,0 +1,183 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/cmd"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolsLsCmd_Success(t *testing.T) {
+	manager := &testutils.FakeManager{
+		GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+			return []db.Pool{
+				{ID: 1, Name: "prod", Strategy: ptr("lifo"), Ttl: ptr(int64(8 * 60 * 60)), CreatedAt: 1764949400},
+				{ID: 2, Name: "ci", Heartbeat: ptr(false), CreatedAt: 1764949400},
+			}, nil
+		},
+	}
+
+	poolsCmd := cmd.PoolsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	poolsCmd.SetOut(outBuf)
+	poolsCmd.SetErr(errBuf)
+
+	poolsCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := poolsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "prod")
+	assert.Contains(t, outBuf.String(), "lifo")
+	assert.Contains(t, outBuf.String(), "8h0m0s")
+	assert.Contains(t, outBuf.String(), "ci")
+	assert.Contains(t, outBuf.String(), "false")
+}
+
+func TestPoolsLsCmd_Empty(t *testing.T) {
+	poolsCmd := cmd.PoolsCmd(&testutils.FakeManager{})
+
+	outBuf := new(bytes.Buffer)
+	poolsCmd.SetOut(outBuf)
+	poolsCmd.SetErr(new(bytes.Buffer))
+
+	poolsCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := poolsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, outBuf.String(), "no pools found")
+}
+
+func TestPoolsSetCmd_Success(t *testing.T) {
+	var updated licenses.PoolSettings
+
+	manager := &testutils.FakeManager{
+		UpdatePoolFn: func(ctx context.Context, name string, settings licenses.PoolSettings) (*db.Pool, error) {
+			assert.Equal(t, "prod", name)
+
+			updated = settings
+
+			return &db.Pool{Name: name}, nil
+		},
+	}
+
+	poolsCmd := cmd.PoolsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	poolsCmd.SetOut(outBuf)
+	poolsCmd.SetErr(errBuf)
+
+	poolsCmd.SetArgs([]string{"set", "--pool=prod", "--strategy=lifo", "--ttl=8h"})
+
+	err := poolsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "pool updated successfully: prod")
+	assert.Equal(t, "lifo", *updated.Strategy)
+	assert.Equal(t, 8*time.Hour, *updated.TTL)
+
+	// heartbeats are left unchanged unless given
+	assert.Nil(t, updated.Heartbeat)
+
+	poolsCmd = cmd.PoolsCmd(manager)
+	poolsCmd.SetOut(new(bytes.Buffer))
+	poolsCmd.SetErr(new(bytes.Buffer))
+
+	poolsCmd.SetArgs([]string{"set", "--pool=prod", "--heartbeats=false"})
+
+	err = poolsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Nil(t, updated.Strategy)
+	assert.Nil(t, updated.TTL)
+	assert.False(t, *updated.Heartbeat)
+}
+
+func TestPoolsSetCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		UpdatePoolFn: func(ctx context.Context, name string, settings licenses.PoolSettings) (*db.Pool, error) {
+			return nil, licenses.ErrBadPool
+		},
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "unknown pool", args: []string{"set", "--pool=unknown", "--strategy=fifo"}, err: "error: pool not found"},
+		{name: "invalid strategy", args: []string{"set", "--pool=prod", "--strategy=bogus"}, err: `must be one of "lifo", "fifo", "rand", or "affinity"`},
+		{name: "invalid ttl", args: []string{"set", "--pool=prod", "--ttl=1s"}, err: "time-to-live value must be at least 30s"},
+		{name: "missing settings", args: []string{"set", "--pool=prod"}, err: "at least one of --strategy, --ttl or --heartbeats is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poolsCmd := cmd.PoolsCmd(manager)
+
+			errBuf := new(bytes.Buffer)
+			poolsCmd.SetOut(new(bytes.Buffer))
+			poolsCmd.SetErr(errBuf)
+
+			poolsCmd.SetArgs(tt.args)
+
+			_ = poolsCmd.Execute()
+
+			assert.Contains(t, errBuf.String(), tt.err)
+		})
+	}
+}
+
+func TestPoolsResetCmd_Success(t *testing.T) {
+	var reset string
+
+	manager := &testutils.FakeManager{
+		ResetPoolFn: func(ctx context.Context, name string) (*db.Pool, error) {
+			reset = name
+
+			return &db.Pool{Name: name}, nil
+		},
+	}
+
+	poolsCmd := cmd.PoolsCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	poolsCmd.SetOut(outBuf)
+	poolsCmd.SetErr(new(bytes.Buffer))
+
+	poolsCmd.SetArgs([]string{"reset", "--pool=prod"})
+
+	err := poolsCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, outBuf.String(), "pool reset successfully: prod")
+	assert.Equal(t, "prod", reset)
+}
+
+func TestPoolsResetCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		ResetPoolFn: func(ctx context.Context, name string) (*db.Pool, error) {
+			return nil, fmt.Errorf("pool %s: %w", name, licenses.ErrBadPool)
+		},
+	}
+
+	poolsCmd := cmd.PoolsCmd(manager)
+
+	errBuf := new(bytes.Buffer)
+	poolsCmd.SetOut(new(bytes.Buffer))
+	poolsCmd.SetErr(errBuf)
+
+	poolsCmd.SetArgs([]string{"reset", "--pool=unknown"})
+
+	_ = poolsCmd.Execute()
+
+	assert.Contains(t, errBuf.String(), "error: pool unknown: pool not found")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func ReserveCmd(manager licenses.Manager) *cobra.Command {
+	var (
+		licenseID   string
+		fingerprint string
+	)
+
+	cmd := &cobra.Command{
+		Use:          "reserve",
+		Short:        "reserve a license for a node, so that it's only ever claimed by that node",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			license, err := manager.ReserveLicense(cmd.Context(), licenseID, fingerprint)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			output.PrintSuccess(cmd.OutOrStdout(), "license reserved successfully: %s for %s", license.Guid, fingerprint)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&licenseID, "license", "", "license ID to reserve")
+	cmd.Flags().StringVar(&fingerprint, "fingerprint", "", "fingerprint of the node to reserve the license for")
+	_ = cmd.MarkFlagRequired("license")
+	_ = cmd.MarkFlagRequired("fingerprint")
+
+	return cmd
+}
+
+func UnreserveCmd(manager licenses.Manager) *cobra.Command {
+	var licenseID string
+
+	cmd := &cobra.Command{
+		Use:          "unreserve",
+		Short:        "remove a license's reservation, so that it can be claimed by any node",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			license, err := manager.UnreserveLicense(cmd.Context(), licenseID)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			output.PrintSuccess(cmd.OutOrStdout(), "license unreserved successfully: %s", license.Guid)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&licenseID, "license", "", "license ID to unreserve")
+	_ = cmd.MarkFlagRequired("license")
+
+	return cmd
+}
@@ -0,0 +1,121 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/keygen-sh/keygen-relay/internal/cmd"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveCmd_Success(t *testing.T) {
+	var reserved map[string]string
+
+	manager := &testutils.FakeManager{
+		ReserveLicenseFn: func(ctx context.Context, id string, fingerprint string) (*db.License, error) {
+			reserved = map[string]string{id: fingerprint}
+
+			return &db.License{Guid: id, ReservedFingerprint: &fingerprint}, nil
+		},
+	}
+
+	reserveCmd := cmd.ReserveCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	reserveCmd.SetOut(outBuf)
+	reserveCmd.SetErr(errBuf)
+
+	reserveCmd.SetArgs([]string{"--license=test-id", "--fingerprint=test-fingerprint"})
+
+	err := reserveCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "license reserved successfully: test-id for test-fingerprint")
+	assert.Equal(t, map[string]string{"test-id": "test-fingerprint"}, reserved)
+}
+
+func TestReserveCmd_MissingFlag(t *testing.T) {
+	reserveCmd := cmd.ReserveCmd(&testutils.FakeManager{})
+
+	errBuf := new(bytes.Buffer)
+	reserveCmd.SetOut(new(bytes.Buffer))
+	reserveCmd.SetErr(errBuf)
+
+	reserveCmd.SetArgs([]string{"--license=test-id"})
+
+	err := reserveCmd.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, errBuf.String(), `required flag(s) "fingerprint" not set`)
+}
+
+func TestReserveCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		ReserveLicenseFn: func(ctx context.Context, id string, fingerprint string) (*db.License, error) {
+			return nil, fmt.Errorf("license %s: %w", id, licenses.ErrLicenseNotFound)
+		},
+	}
+
+	reserveCmd := cmd.ReserveCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	reserveCmd.SetOut(outBuf)
+	reserveCmd.SetErr(errBuf)
+
+	reserveCmd.SetArgs([]string{"--license=test-id", "--fingerprint=test-fingerprint"})
+
+	err := reserveCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, outBuf.String())
+	assert.Contains(t, errBuf.String(), "license test-id: license not found")
+}
+
+func TestUnreserveCmd_Success(t *testing.T) {
+	manager := &testutils.FakeManager{
+		UnreserveLicenseFn: func(ctx context.Context, id string) (*db.License, error) {
+			return &db.License{Guid: id}, nil
+		},
+	}
+
+	unreserveCmd := cmd.UnreserveCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	unreserveCmd.SetOut(outBuf)
+	unreserveCmd.SetErr(errBuf)
+
+	unreserveCmd.SetArgs([]string{"--license=test-id"})
+
+	err := unreserveCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "license unreserved successfully: test-id")
+}
+
+func TestUnreserveCmd_NotReserved(t *testing.T) {
+	manager := &testutils.FakeManager{
+		UnreserveLicenseFn: func(ctx context.Context, id string) (*db.License, error) {
+			return nil, fmt.Errorf("license %s: %w", id, licenses.ErrLicenseNotReserved)
+		},
+	}
+
+	unreserveCmd := cmd.UnreserveCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	unreserveCmd.SetOut(outBuf)
+	unreserveCmd.SetErr(errBuf)
+
+	unreserveCmd.SetArgs([]string{"--license=test-id"})
+
+	err := unreserveCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, outBuf.String())
+	assert.Contains(t, errBuf.String(), "license test-id: license is not reserved")
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-go/v3"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/locker"
 	"github.com/keygen-sh/keygen-relay/internal/logger"
 	"github.com/keygen-sh/keygen-relay/internal/output"
@@ -14,7 +20,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const minTTL = 30 * time.Second
+const minTTL = licenses.MinTTL
 
 func ServeCmd(srv server.Server) *cobra.Command {
 	cfg := srv.Config()
@@ -32,7 +38,8 @@ func ServeCmd(srv server.Server) *cobra.Command {
 	})
 
 	router.Use(server.SigningMiddleware(cfg))
-	router.Use(server.LoggingMiddleware)
+	router.Use(server.LoggingMiddleware(srv.Metrics()))
+	router.Use(server.RateLimitMiddleware(cfg, srv.Metrics(), srv.RateLimiters()))
 
 	// Mount the router to the server
 	srv.Mount(router)
@@ -49,6 +56,137 @@ func ServeCmd(srv server.Server) *cobra.Command {
 				}
 			}
 
+			if err := validateTTLBounds(cfg.MinTTL, cfg.MaxTTL); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if specs, err := cmd.Flags().GetStringSlice("pool-ttl"); err == nil && len(specs) > 0 {
+				bounds, err := parsePoolTTLBounds(specs)
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.PoolTTLBounds = bounds
+			}
+
+			if cfg.MaxLeasesPerNode < 0 {
+				err := errors.New("maximum leases per node must not be negative")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if cfg.FingerprintRateLimit.Rate < 0 || cfg.IPRateLimit.Rate < 0 {
+				err := errors.New("rate limits must not be negative")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if burst, err := cmd.Flags().GetInt("rate-limit-burst"); err == nil {
+				if burst < 1 {
+					err := errors.New("rate limit burst must be at least 1")
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.FingerprintRateLimit.Burst = burst
+				cfg.IPRateLimit.Burst = burst
+			}
+
+			if specs, err := cmd.Flags().GetStringSlice("trusted-proxies"); err == nil && len(specs) > 0 {
+				proxies, err := parseTrustedProxies(specs)
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.TrustedProxies = proxies
+			}
+
+			if cfg.ForwardedHeader != "" && len(cfg.TrustedProxies) == 0 {
+				err := errors.New("--forwarded-header requires --trusted-proxies")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+				err := errors.New("both --tls-cert and --tls-key must be provided to enable tls")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if v, err := cmd.Flags().GetString("socket-mode"); err == nil {
+				mode, err := strconv.ParseUint(v, 8, 32)
+				if err != nil || mode > 0777 {
+					err := fmt.Errorf("invalid --socket-mode %q: must be an octal file mode e.g. 0660", v)
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.SocketMode = os.FileMode(mode)
+			}
+
+			if path, ok := cfg.UnixSocket(); ok {
+				if path == "" {
+					err := errors.New("unix socket path is required e.g. unix:///run/relay.sock")
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				if cfg.GRPCPort != 0 {
+					err := errors.New("--grpc-port is not supported when bound to a unix socket")
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+			}
+
+			if cfg.GRPCPort < 0 || cfg.GRPCPort > 65535 {
+				err := errors.New("--grpc-port must be between 0 and 65535")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if cfg.GRPCPort != 0 && cfg.GRPCPort == cfg.ServerPort {
+				err := errors.New("--grpc-port must differ from --port")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if cfg.AffinityFallback == server.Affinity {
+				err := errors.New(`--affinity-fallback must be one of "lifo", "fifo", or "rand"`)
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if cfg.TLSClientCAFile != "" && !cfg.TLSEnabled() {
+				err := errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
+			if cfg.TLSClientFingerprint && cfg.TLSClientCAFile == "" {
+				err := errors.New("--tls-client-fingerprint requires --tls-client-ca")
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return err
+			}
+
 			if disableHeartbeats, err := cmd.Flags().GetBool("no-heartbeats"); err == nil {
 				cfg.EnabledHeartbeat = !disableHeartbeats
 			}
@@ -66,8 +204,44 @@ func ServeCmd(srv server.Server) *cobra.Command {
 				}
 			}
 
+			if specs, err := cmd.Flags().GetStringSlice("signing-secrets"); err == nil && len(specs) > 0 {
+				secrets, err := parseSigningSecrets(specs)
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.SigningSecrets = secrets
+			}
+
+			if path, err := cmd.Flags().GetString("signing-key"); err == nil && path != "" {
+				key, err := server.LoadSigningKey(path)
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return err
+				}
+
+				cfg.SigningKey = key
+			}
+
+			if t, err := cmd.Flags().GetString("admin-token"); err == nil {
+				if t != "" {
+					cfg.AdminToken = &t
+				}
+			}
+
+			cfg.PublicKey = strings.TrimSpace(cfg.PublicKey)
+
+			// set once at startup, since licenses added via the admin api are verified
+			// concurrently using the SDK's global public key
+			keygen.PublicKey = cfg.PublicKey
+
 			srv.Manager().Config().Strategy = string(cfg.Strategy)
+			srv.Manager().Config().AffinityFallback = string(cfg.AffinityFallback)
 			srv.Manager().Config().ExtendOnHeartbeat = cfg.EnabledHeartbeat
+			srv.Manager().Config().MaxLeasesPerNode = cfg.MaxLeasesPerNode
 
 			output.PrintSuccess(cmd.OutOrStdout(), "the server is starting")
 
@@ -89,10 +263,17 @@ func ServeCmd(srv server.Server) *cobra.Command {
 		try.Static(cfg.Strategy),
 	)
 
+	cfg.AffinityFallback = try.Try(
+		try.EnvAs("RELAY_AFFINITY_FALLBACK", func(value string) server.StrategyType {
+			return server.StrategyType(value)
+		}),
+		try.Static(cfg.AffinityFallback),
+	)
+
 	if locker.LockedAddr() {
 		cfg.ServerAddr = locker.Addr
 	} else {
-		cmd.Flags().StringVarP(&cfg.ServerAddr, "bind", "b", try.Try(try.Env("RELAY_ADDR"), try.Env("BIND_ADDR"), try.Static(cfg.ServerAddr)), "ip address to bind the relay server to [$RELAY_ADDR=0.0.0.0]")
+		cmd.Flags().StringVarP(&cfg.ServerAddr, "bind", "b", try.Try(try.Env("RELAY_ADDR"), try.Env("BIND_ADDR"), try.Static(cfg.ServerAddr)), "ip address or unix socket e.g. unix:///run/relay.sock to bind the relay server to [$RELAY_ADDR=0.0.0.0]")
 	}
 
 	if locker.LockedPort() {
@@ -106,17 +287,61 @@ func ServeCmd(srv server.Server) *cobra.Command {
 		cmd.Flags().IntVarP(&cfg.ServerPort, "port", "p", try.Try(try.EnvInt("RELAY_PORT"), try.EnvInt("PORT"), try.Static(cfg.ServerPort)), "port to run the relay server on [$RELAY_PORT=6349]")
 	}
 
-	if locker.LockedSigningSecret() {
-		cfg.SigningSecret = &locker.SigningSecret
+	cmd.Flags().String("socket-mode", try.Try(try.Env("RELAY_SOCKET_MODE"), try.Static(fmt.Sprintf("%04o", cfg.SocketMode))), "file mode of the unix socket when bound to one [$RELAY_SOCKET_MODE=0660]")
+	cmd.Flags().StringVar(&cfg.SocketOwner, "socket-owner", try.Try(try.Env("RELAY_SOCKET_OWNER"), try.Static(cfg.SocketOwner)), "owner of the unix socket when bound to one e.g. relay:relay [$RELAY_SOCKET_OWNER=relay:relay]")
+	cmd.Flags().IntVar(&cfg.GRPCPort, "grpc-port", try.Try(try.EnvInt("RELAY_GRPC_PORT"), try.Static(cfg.GRPCPort)), "port to run the grpc api on, where 0 disables the grpc api [$RELAY_GRPC_PORT=6350]")
+
+	// signing secrets are locked together, so that a node-locked relay can't be made to
+	// sign responses with an additional secret
+	if locker.LockedSigningSecret() || locker.LockedSigningSecrets() {
+		if locker.LockedSigningSecret() {
+			cfg.SigningSecret = &locker.SigningSecret
+		}
+
+		if locker.LockedSigningSecrets() {
+			secrets, err := parseSigningSecrets(splitList(locker.SigningSecrets))
+			if err != nil {
+				panic(err)
+			}
+
+			cfg.SigningSecrets = secrets
+		}
 	} else {
 		cmd.Flags().String("signing-secret", try.Try(try.Env("RELAY_SIGNING_SECRET"), try.Static("")), "secret for signing responses [$RELAY_SIGNING_SECRET=hunter2]")
+		cmd.Flags().StringSlice("signing-secrets", try.EnvAs("RELAY_SIGNING_SECRETS", splitList)(), "secrets for signing responses as kid=secret, where each secret signs every response to allow rotation [$RELAY_SIGNING_SECRETS=a=hunter2,b=hunter3]")
+	}
+
+	cmd.Flags().String("signing-key", try.Try(try.Env("RELAY_SIGNING_KEY"), try.Static("")), "path to a pem-encoded ed25519 private key for signing responses [$RELAY_SIGNING_KEY=/etc/relay/signing.key]")
+
+	if locker.Locked() {
+		cfg.PublicKey = locker.PublicKey
+	} else {
+		cmd.Flags().StringVar(&cfg.PublicKey, "public-key", try.Try(try.Env("RELAY_PUBLIC_KEY"), try.Static("")), "your keygen.sh public key for verifying licenses added via the admin api [$RELAY_PUBLIC_KEY=e860..48b6]")
 	}
 
 	cmd.Flags().DurationVar(&cfg.TTL, "ttl", try.Try(try.EnvDuration("RELAY_LEASE_TTL"), try.Static(cfg.TTL)), "time-to-live for leases [$RELAY_LEASE_TTL=60s]")
+	cmd.Flags().DurationVar(&cfg.MinTTL, "min-ttl", try.Try(try.EnvDuration("RELAY_MIN_TTL"), try.Static(cfg.MinTTL)), "minimum time-to-live a node may request for its lease [$RELAY_MIN_TTL=30s]")
+	cmd.Flags().DurationVar(&cfg.MaxTTL, "max-ttl", try.Try(try.EnvDuration("RELAY_MAX_TTL"), try.Static(cfg.MaxTTL)), "maximum time-to-live a node may request for its lease, defaulting to --ttl [$RELAY_MAX_TTL=1h]")
+	cmd.Flags().StringSlice("pool-ttl", try.EnvAs("RELAY_POOL_TTL", splitList)(), "time-to-live bounds for a pool as pool=min:max, overriding --min-ttl and --max-ttl [$RELAY_POOL_TTL=prod=1m:8h]")
 	cmd.Flags().Bool("no-heartbeats", try.Try(try.EnvBool("RELAY_NO_HEARTBEATS"), try.Static(false)), "disable node heartbeat monitoring and culling as well as lease extensions [$RELAY_NO_HEARTBEAT=1]")
-	cmd.Flags().Var(&cfg.Strategy, "strategy", `strategy for license distribution e.g. "fifo", "lifo", or "rand" [$RELAY_STRATEGY=rand]`)
+	cmd.Flags().Var(&cfg.Strategy, "strategy", `strategy for license distribution e.g. "fifo", "lifo", "rand", or "affinity" [$RELAY_STRATEGY=rand]`)
+	cmd.Flags().Var(&cfg.AffinityFallback, "affinity-fallback", `strategy for license distribution when the "affinity" strategy finds no previously held license e.g. "fifo", "lifo", or "rand" [$RELAY_AFFINITY_FALLBACK=rand]`)
 	cmd.Flags().DurationVar(&cfg.CullInterval, "cull-interval", try.Try(try.EnvDuration("RELAY_CULL_INTERVAL"), try.Static(cfg.CullInterval)), "interval at which to cull dead nodes [$RELAY_CULL_INTERVAL=15s]")
+	cmd.Flags().DurationVar(&cfg.MaxWait, "max-wait", try.Try(try.EnvDuration("RELAY_MAX_WAIT"), try.Static(cfg.MaxWait)), "maximum time a claim may wait for a license to be freed via ?wait=, where 0 disables waiting [$RELAY_MAX_WAIT=5m]")
+	cmd.Flags().IntVar(&cfg.MaxLeasesPerNode, "max-leases-per-node", try.Try(try.EnvInt("RELAY_MAX_LEASES_PER_NODE"), try.Static(cfg.MaxLeasesPerNode)), "maximum number of leases a node may hold at once, including sub-leases, where 0 is unlimited [$RELAY_MAX_LEASES_PER_NODE=4]")
+	cmd.Flags().Float64Var(&cfg.FingerprintRateLimit.Rate, "fingerprint-rate-limit", try.Try(try.EnvFloat("RELAY_FINGERPRINT_RATE_LIMIT"), try.Static(cfg.FingerprintRateLimit.Rate)), "maximum requests per second per node fingerprint, where 0 is unlimited [$RELAY_FINGERPRINT_RATE_LIMIT=1]")
+	cmd.Flags().Float64Var(&cfg.IPRateLimit.Rate, "ip-rate-limit", try.Try(try.EnvFloat("RELAY_IP_RATE_LIMIT"), try.Static(cfg.IPRateLimit.Rate)), "maximum requests per second per client ip address, where 0 is unlimited [$RELAY_IP_RATE_LIMIT=10]")
+	cmd.Flags().Int("rate-limit-burst", try.Try(try.EnvInt("RELAY_RATE_LIMIT_BURST"), try.Static(10)), "maximum burst of requests allowed above the rate limits [$RELAY_RATE_LIMIT_BURST=10]")
+	cmd.Flags().DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", try.Try(try.EnvDuration("RELAY_SHUTDOWN_TIMEOUT"), try.Static(cfg.ShutdownTimeout)), "time to wait for in-flight requests to drain during shutdown [$RELAY_SHUTDOWN_TIMEOUT=30s]")
 	cmd.Flags().String("pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to serve licenses from [$RELAY_POOL=prod]")
+	cmd.Flags().StringVar(&cfg.TLSCertFile, "tls-cert", try.Try(try.Env("RELAY_TLS_CERT"), try.Static(cfg.TLSCertFile)), "path to a pem-encoded certificate for serving over tls [$RELAY_TLS_CERT=/etc/relay/tls.crt]")
+	cmd.Flags().StringVar(&cfg.TLSKeyFile, "tls-key", try.Try(try.Env("RELAY_TLS_KEY"), try.Static(cfg.TLSKeyFile)), "path to a pem-encoded private key for serving over tls [$RELAY_TLS_KEY=/etc/relay/tls.key]")
+	cmd.Flags().StringVar(&cfg.TLSClientCAFile, "tls-client-ca", try.Try(try.Env("RELAY_TLS_CLIENT_CA"), try.Static(cfg.TLSClientCAFile)), "path to a pem-encoded ca bundle for verifying client certificates i.e. mtls [$RELAY_TLS_CLIENT_CA=/etc/relay/ca.crt]")
+	cmd.Flags().BoolVar(&cfg.TLSClientFingerprint, "tls-client-fingerprint", try.Try(try.EnvBool("RELAY_TLS_CLIENT_FINGERPRINT"), try.Static(cfg.TLSClientFingerprint)), "require node fingerprints to match the common name of the client certificate [$RELAY_TLS_CLIENT_FINGERPRINT=1]")
+	cmd.Flags().StringVar(&cfg.ForwardedHeader, "forwarded-header", try.Try(try.Env("RELAY_FORWARDED_HEADER"), try.Static(cfg.ForwardedHeader)), "header containing the client ip address when behind a trusted proxy e.g. X-Forwarded-For [$RELAY_FORWARDED_HEADER=X-Forwarded-For]")
+	cmd.Flags().StringSlice("trusted-proxies", try.EnvAs("RELAY_TRUSTED_PROXIES", splitList)(), "cidr ranges of proxies trusted to set the forwarded header [$RELAY_TRUSTED_PROXIES=10.0.0.0/8]")
+	cmd.Flags().BoolVar(&cfg.RequireAPITokens, "require-tokens", try.Try(try.EnvBool("RELAY_REQUIRE_TOKENS"), try.Static(cfg.RequireAPITokens)), "require nodes to present an api token scoped to their pool when claiming and releasing licenses [$RELAY_REQUIRE_TOKENS=1]")
+	cmd.Flags().String("admin-token", try.Try(try.Env("RELAY_ADMIN_TOKEN"), try.Static("")), "bearer token for the admin api, which is disabled when unset [$RELAY_ADMIN_TOKEN=hunter2]")
 
 	_ = cmd.RegisterFlagCompletionFunc("strategy", strategyTypeCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
@@ -126,7 +351,111 @@ func ServeCmd(srv server.Server) *cobra.Command {
 
 func validateTTL(ttl time.Duration) error {
 	if ttl < minTTL {
-		return fmt.Errorf("time-to-live value must be at least %s", minTTL)
+		return licenses.ErrBadTTL
 	}
 	return nil
 }
+
+func validateTTLBounds(minimum time.Duration, maximum time.Duration) error {
+	if minimum != 0 && minimum < minTTL {
+		return fmt.Errorf("minimum time-to-live value must be at least %s", minTTL)
+	}
+
+	if maximum != 0 && maximum < minimum {
+		return fmt.Errorf("maximum time-to-live value must be at least %s", minimum)
+	}
+
+	return nil
+}
+
+// parsePoolTTLBounds parses per-pool ttl bounds in the form pool=min:max
+func parsePoolTTLBounds(specs []string) (map[string]server.TTLBounds, error) {
+	bounds := make(map[string]server.TTLBounds, len(specs))
+
+	for _, spec := range specs {
+		pool, rng, ok := strings.Cut(spec, "=")
+		if !ok || pool == "" {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: must be in the form pool=min:max", spec)
+		}
+
+		lo, hi, ok := strings.Cut(rng, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: must be in the form pool=min:max", spec)
+		}
+
+		minimum, err := time.ParseDuration(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: %w", spec, err)
+		}
+
+		maximum, err := time.ParseDuration(hi)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: %w", spec, err)
+		}
+
+		if minimum < minTTL {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: minimum must be at least %s", spec, minTTL)
+		}
+
+		if maximum < minimum {
+			return nil, fmt.Errorf("invalid pool time-to-live %q: maximum must be at least %s", spec, minimum)
+		}
+
+		bounds[pool] = server.TTLBounds{Min: minimum, Max: maximum}
+	}
+
+	return bounds, nil
+}
+
+// parseSigningSecrets parses signing secrets with key IDs in the form kid=secret, keeping
+// their order so that signatures are emitted in a stable order
+func parseSigningSecrets(specs []string) ([]server.SigningSecret, error) {
+	secrets := make([]server.SigningSecret, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+
+	for _, spec := range specs {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid signing secret %q: must be in the form kid=secret", spec)
+		}
+
+		if strings.ContainsAny(kid, " \t") {
+			return nil, fmt.Errorf("invalid signing secret %q: key id must not contain whitespace", spec)
+		}
+
+		if seen[kid] {
+			return nil, fmt.Errorf("invalid signing secret %q: key id %q is duplicated", spec, kid)
+		}
+
+		seen[kid] = true
+		secrets = append(secrets, server.SigningSecret{KeyID: kid, Secret: secret})
+	}
+
+	return secrets, nil
+}
+
+// parseTrustedProxies parses the cidr ranges of trusted proxies, where a single ip address
+// is treated as a range containing only that address
+func parseTrustedProxies(specs []string) ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0, len(specs))
+
+	for _, spec := range specs {
+		ipnet, err := licenses.ParseCIDR(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", spec, err)
+		}
+
+		proxies = append(proxies, ipnet)
+	}
+
+	return proxies, nil
+}
+
+// splitList splits a comma-separated environment variable into a list
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
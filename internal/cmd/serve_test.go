@@ -2,7 +2,12 @@ package cmd_test
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -69,6 +74,7 @@ func TestServeCmd_Flags(t *testing.T) {
 		"--ttl", "1m",
 		"--no-heartbeats",
 		"--strategy", "lifo",
+		"--shutdown-timeout", "5s",
 	})
 
 	output := &bytes.Buffer{}
@@ -86,6 +92,7 @@ func TestServeCmd_Flags(t *testing.T) {
 	assert.Equal(t, string(cfg.Server.Strategy), cfg.License.Strategy)
 	assert.Equal(t, cfg.Server.EnabledHeartbeat, cfg.License.ExtendOnHeartbeat)
 	assert.Equal(t, cfg.Server.CullInterval, 5*time.Second)
+	assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout)
 }
 
 func TestServeCmd_InvalidStrategy(t *testing.T) {
@@ -116,6 +123,72 @@ func TestServeCmd_InvalidStrategy(t *testing.T) {
 	assert.False(t, mockServer.RunCalled)
 }
 
+func TestServeCmd_AffinityFallback(t *testing.T) {
+	cfg := &config.Config{
+		Server: &server.Config{
+			ServerPort:       6349,
+			TTL:              30 * time.Second,
+			EnabledHeartbeat: true,
+			Strategy:         server.FIFO,
+			AffinityFallback: server.FIFO,
+		},
+		License: &licenses.Config{},
+	}
+
+	manager := testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return cfg.License
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg.Server, &manager)
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--strategy", "affinity",
+		"--affinity-fallback", "rand",
+	})
+
+	output := &bytes.Buffer{}
+	serveCmd.SetOut(output)
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, "affinity", cfg.License.Strategy)
+	assert.Equal(t, "rand", cfg.License.AffinityFallback)
+}
+
+func TestServeCmd_InvalidAffinityFallback(t *testing.T) {
+	cfg := &config.Config{
+		Server: &server.Config{
+			ServerPort:       6349,
+			TTL:              30 * time.Second,
+			EnabledHeartbeat: true,
+			Strategy:         server.FIFO,
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg.Server, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--strategy", "affinity",
+		"--affinity-fallback", "affinity",
+	})
+
+	output := &bytes.Buffer{}
+	serveCmd.SetOut(output)
+	serveCmd.SetErr(output)
+
+	err := serveCmd.Execute()
+
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "--affinity-fallback must be one of")
+	assert.False(t, mockServer.RunCalled)
+}
+
 func TestServeCmd_RunError(t *testing.T) {
 	cfg := &config.Config{
 		Server: &server.Config{
@@ -168,3 +241,516 @@ func TestServeCmd_InvalidTTL(t *testing.T) {
 	assert.Contains(t, output.String(), "time-to-live value must be at least 30s")
 	assert.False(t, mockServer.RunCalled)
 }
+
+func TestServeCmd_TTLBounds(t *testing.T) {
+	cfg := server.NewConfig()
+	manager := &testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return licenses.NewConfig()
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg, manager)
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--min-ttl", "1m",
+		"--max-ttl", "1h",
+		"--pool-ttl", "prod=5m:8h,dev=30s:1m",
+	})
+
+	output := &bytes.Buffer{}
+	serveCmd.SetOut(output)
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, server.TTLBounds{Min: 1 * time.Minute, Max: 1 * time.Hour}, cfg.TTLBoundsFor(nil))
+	assert.Equal(t, server.TTLBounds{Min: 5 * time.Minute, Max: 8 * time.Hour}, cfg.TTLBoundsFor(ptr("prod")))
+	assert.Equal(t, server.TTLBounds{Min: 30 * time.Second, Max: 1 * time.Minute}, cfg.TTLBoundsFor(ptr("dev")))
+	assert.Equal(t, server.TTLBounds{Min: 1 * time.Minute, Max: 1 * time.Hour}, cfg.TTLBoundsFor(ptr("test")))
+}
+
+func TestServeCmd_MaxLeasesPerNode(t *testing.T) {
+	cfg := server.NewConfig()
+	managerCfg := licenses.NewConfig()
+	manager := &testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return managerCfg
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg, manager)
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--max-leases-per-node", "4"})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, 4, managerCfg.MaxLeasesPerNode)
+
+	mockServer = testutils.NewMockServer(server.NewConfig(), manager)
+	serveCmd = cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--max-leases-per-node", "-1"})
+	serveCmd.SetOut(&bytes.Buffer{})
+	serveCmd.SetErr(&bytes.Buffer{})
+
+	err = serveCmd.Execute()
+
+	assert.Error(t, err)
+	assert.False(t, mockServer.RunCalled)
+}
+
+func TestServeCmd_InvalidTTLBounds(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"--min-ttl", "10s"}, expected: "minimum time-to-live value must be at least 30s"},
+		{args: []string{"--min-ttl", "1m", "--max-ttl", "30s"}, expected: "maximum time-to-live value must be at least 1m0s"},
+		{args: []string{"--pool-ttl", "prod"}, expected: `invalid pool time-to-live "prod"`},
+		{args: []string{"--pool-ttl", "prod=1m"}, expected: `invalid pool time-to-live "prod=1m"`},
+		{args: []string{"--pool-ttl", "prod=1m:foo"}, expected: `invalid pool time-to-live "prod=1m:foo"`},
+		{args: []string{"--pool-ttl", "prod=10s:1m"}, expected: "minimum must be at least 30s"},
+		{args: []string{"--pool-ttl", "prod=5m:1m"}, expected: "maximum must be at least 5m0s"},
+	}
+
+	for _, tt := range tests {
+		mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+		serveCmd := cmd.ServeCmd(mockServer)
+
+		serveCmd.SetArgs(tt.args)
+
+		output := &bytes.Buffer{}
+		serveCmd.SetOut(output)
+		serveCmd.SetErr(output)
+
+		_ = serveCmd.Execute()
+
+		assert.Contains(t, output.String(), tt.expected)
+		assert.False(t, mockServer.RunCalled)
+	}
+}
+
+func TestServeCmd_AdminToken(t *testing.T) {
+	cfg := &config.Config{
+		Server: &server.Config{
+			ServerPort:       6349,
+			TTL:              30 * time.Second,
+			EnabledHeartbeat: true,
+			Strategy:         server.FIFO,
+		},
+		License: &licenses.Config{},
+	}
+
+	manager := testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return cfg.License
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg.Server, &manager)
+
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--admin-token", "hunter2",
+		"--public-key", "e860..48b6",
+	})
+
+	output := &bytes.Buffer{}
+	serveCmd.SetOut(output)
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, "hunter2", *cfg.Server.AdminToken)
+	assert.Equal(t, "e860..48b6", cfg.Server.PublicKey)
+}
+
+func TestServeCmd_SigningSecrets(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--signing-secret", "hunter2",
+		"--signing-secrets", "a=hunter3,b=hunter4==",
+		"--signing-secrets", "c=hunter5",
+	})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, "hunter2", *cfg.SigningSecret)
+	assert.Equal(t, []server.SigningSecret{
+		{KeyID: "a", Secret: "hunter3"},
+		{KeyID: "b", Secret: "hunter4=="},
+		{KeyID: "c", Secret: "hunter5"},
+	}, cfg.SigningSecrets)
+
+	tests := []struct {
+		name  string
+		specs string
+		err   string
+	}{
+		{name: "missing key id", specs: "=hunter2", err: "must be in the form kid=secret"},
+		{name: "missing secret", specs: "a=", err: "must be in the form kid=secret"},
+		{name: "missing separator", specs: "hunter2", err: "must be in the form kid=secret"},
+		{name: "duplicate key id", specs: "a=hunter2,a=hunter3", err: `key id "a" is duplicated`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			errOutput := &bytes.Buffer{}
+			serveCmd.SetArgs([]string{"--signing-secrets", tt.specs})
+			serveCmd.SetOut(&bytes.Buffer{})
+			serveCmd.SetErr(errOutput)
+
+			err := serveCmd.Execute()
+
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
+
+func TestServeCmd_RequireTokens(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--require-tokens"})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.True(t, cfg.RequireAPITokens)
+}
+
+func TestServeCmd_RateLimits(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--fingerprint-rate-limit", "0.5",
+		"--ip-rate-limit", "20",
+		"--rate-limit-burst", "5",
+	})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, server.RateLimit{Rate: 0.5, Burst: 5}, cfg.FingerprintRateLimit)
+	assert.Equal(t, server.RateLimit{Rate: 20, Burst: 5}, cfg.IPRateLimit)
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "negative rate", args: []string{"--ip-rate-limit", "-1"}, err: "rate limits must not be negative"},
+		{name: "zero burst", args: []string{"--rate-limit-burst", "0"}, err: "rate limit burst must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			serveCmd.SetArgs(tt.args)
+			serveCmd.SetOut(&bytes.Buffer{})
+			serveCmd.SetErr(&bytes.Buffer{})
+
+			err := serveCmd.Execute()
+
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
+
+func TestServeCmd_SigningKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "signing.key")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	assert.NoError(t, err)
+
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--signing-key", path})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err = serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, key, cfg.SigningKey)
+
+	mockServer = testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+	serveCmd = cmd.ServeCmd(mockServer)
+
+	errOutput := &bytes.Buffer{}
+	serveCmd.SetArgs([]string{"--signing-key", filepath.Join(t.TempDir(), "missing.key")})
+	serveCmd.SetOut(&bytes.Buffer{})
+	serveCmd.SetErr(errOutput)
+
+	err = serveCmd.Execute()
+
+	assert.Error(t, err)
+	assert.False(t, mockServer.RunCalled)
+	assert.Contains(t, errOutput.String(), "failed to read signing key")
+}
+
+func TestServeCmd_InvalidTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "cert without key",
+			args: []string{"--tls-cert", "tls.crt"},
+			err:  "both --tls-cert and --tls-key must be provided to enable tls",
+		},
+		{
+			name: "key without cert",
+			args: []string{"--tls-key", "tls.key"},
+			err:  "both --tls-cert and --tls-key must be provided to enable tls",
+		},
+		{
+			name: "client ca without tls",
+			args: []string{"--tls-client-ca", "ca.crt"},
+			err:  "--tls-client-ca requires --tls-cert and --tls-key",
+		},
+		{
+			name: "client fingerprint without client ca",
+			args: []string{"--tls-cert", "tls.crt", "--tls-key", "tls.key", "--tls-client-fingerprint"},
+			err:  "--tls-client-fingerprint requires --tls-client-ca",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &server.Config{
+				ServerPort:       6349,
+				TTL:              30 * time.Second,
+				EnabledHeartbeat: true,
+				Strategy:         server.FIFO,
+			}
+
+			mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			serveCmd.SetArgs(tt.args)
+
+			output := &bytes.Buffer{}
+			serveCmd.SetOut(output)
+			serveCmd.SetErr(output)
+
+			_ = serveCmd.Execute()
+
+			assert.Contains(t, output.String(), tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
+
+func TestServeCmd_TLS(t *testing.T) {
+	cfg := &config.Config{
+		Server: &server.Config{
+			ServerPort:       6349,
+			TTL:              30 * time.Second,
+			EnabledHeartbeat: true,
+			Strategy:         server.FIFO,
+		},
+		License: &licenses.Config{},
+	}
+
+	manager := testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return cfg.License
+		},
+	}
+
+	mockServer := testutils.NewMockServer(cfg.Server, &manager)
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--tls-cert", "tls.crt",
+		"--tls-key", "tls.key",
+		"--tls-client-ca", "ca.crt",
+		"--tls-client-fingerprint",
+	})
+
+	output := &bytes.Buffer{}
+	serveCmd.SetOut(output)
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.True(t, cfg.Server.TLSEnabled())
+	assert.Equal(t, "ca.crt", cfg.Server.TLSClientCAFile)
+	assert.True(t, cfg.Server.TLSClientFingerprint)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestServeCmd_TrustedProxies(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{
+		"--forwarded-header", "X-Forwarded-For",
+		"--trusted-proxies", "10.0.0.0/8,192.0.2.1",
+	})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, "X-Forwarded-For", cfg.ForwardedHeader)
+	assert.Len(t, cfg.TrustedProxies, 2)
+	assert.Equal(t, "10.0.0.0/8", cfg.TrustedProxies[0].String())
+	assert.Equal(t, "192.0.2.1/32", cfg.TrustedProxies[1].String())
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "invalid proxy", args: []string{"--forwarded-header", "X-Forwarded-For", "--trusted-proxies", "10.0.0.0/33"}, err: "invalid trusted proxy"},
+		{name: "missing proxies", args: []string{"--forwarded-header", "X-Forwarded-For"}, err: "--forwarded-header requires --trusted-proxies"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			serveCmd.SetArgs(tt.args)
+			serveCmd.SetOut(&bytes.Buffer{})
+			serveCmd.SetErr(&bytes.Buffer{})
+
+			err := serveCmd.Execute()
+
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
+
+func TestServeCmd_GRPCPort(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--grpc-port", "6350"})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+	assert.Equal(t, 6350, cfg.GRPCPort)
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "out of range", args: []string{"--grpc-port", "65536"}, err: "--grpc-port must be between 0 and 65535"},
+		{name: "same as http port", args: []string{"--port", "6349", "--grpc-port", "6349"}, err: "--grpc-port must differ from --port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			serveCmd.SetArgs(tt.args)
+			serveCmd.SetOut(&bytes.Buffer{})
+			serveCmd.SetErr(&bytes.Buffer{})
+
+			err := serveCmd.Execute()
+
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
+
+func TestServeCmd_UnixSocket(t *testing.T) {
+	cfg := server.NewConfig()
+	mockServer := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	serveCmd := cmd.ServeCmd(mockServer)
+
+	serveCmd.SetArgs([]string{"--bind", "unix:///run/relay.sock", "--socket-mode", "0600", "--socket-owner", "relay:relay"})
+	serveCmd.SetOut(&bytes.Buffer{})
+
+	err := serveCmd.Execute()
+
+	assert.NoError(t, err)
+	assert.True(t, mockServer.RunCalled)
+
+	path, ok := cfg.UnixSocket()
+	assert.True(t, ok)
+	assert.Equal(t, "/run/relay.sock", path)
+	assert.Equal(t, os.FileMode(0600), cfg.SocketMode)
+	assert.Equal(t, "relay:relay", cfg.SocketOwner)
+
+	tests := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "invalid mode", args: []string{"--bind", "unix:///run/relay.sock", "--socket-mode", "rw"}, err: "invalid --socket-mode"},
+		{name: "mode out of range", args: []string{"--bind", "unix:///run/relay.sock", "--socket-mode", "01777"}, err: "invalid --socket-mode"},
+		{name: "missing path", args: []string{"--bind", "unix://"}, err: "unix socket path is required"},
+		{name: "grpc port", args: []string{"--bind", "unix:///run/relay.sock", "--grpc-port", "6350"}, err: "--grpc-port is not supported when bound to a unix socket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+			serveCmd := cmd.ServeCmd(mockServer)
+
+			serveCmd.SetArgs(tt.args)
+			serveCmd.SetOut(&bytes.Buffer{})
+			serveCmd.SetErr(&bytes.Buffer{})
+
+			err := serveCmd.Execute()
+
+			assert.ErrorContains(t, err, tt.err)
+			assert.False(t, mockServer.RunCalled)
+		})
+	}
+}
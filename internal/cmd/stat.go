@@ -35,6 +35,12 @@ func StatCmd(manager licenses.Manager) *cobra.Command {
 				{Title: "pool", Width: 8}, // start with min width
 				{Title: "claims", Width: 8},
 				{Title: "node_id", Width: 8},
+				{Title: "reserved_for", Width: 16},
+				{Title: "hostname", Width: 16},
+				{Title: "platform", Width: 14},
+				{Title: "username", Width: 12},
+				{Title: "app", Width: 20},
+				{Title: "labels", Width: 24},
 				{Title: "last_claimed_at", Width: 20},
 				{Title: "last_released_at", Width: 20},
 			}
@@ -67,11 +73,34 @@ func StatCmd(manager licenses.Manager) *cobra.Command {
 				nodeIDStr = "-"
 			}
 
+			reservedForStr := "-"
+			if license.ReservedFingerprint != nil {
+				reservedForStr = *license.ReservedFingerprint
+			}
+
+			hostnameStr, platformStr, usernameStr, appStr, labelsStr := "-", "-", "-", "-", "-"
+			if license.NodeID != nil {
+				node, err := manager.GetNodeByID(cmd.Context(), *license.NodeID)
+				if err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return nil
+				}
+
+				if metadata := node.NodeMetadata(); metadata != nil {
+					hostnameStr = formatString(metadata.Hostname)
+					platformStr = formatString(metadata.Platform)
+					usernameStr = formatString(metadata.Username)
+					appStr = formatApp(metadata)
+					labelsStr = formatLabels(metadata.Labels)
+				}
+			}
+
 			lastClaimedAtStr := formatTime(license.LastClaimedAt)
 			lastReleasedAtStr := formatTime(license.LastReleasedAt)
 
 			tableRows := []table.Row{
-				{license.Guid, poolStr, claimsStr, nodeIDStr, lastClaimedAtStr, lastReleasedAtStr},
+				{license.Guid, poolStr, claimsStr, nodeIDStr, reservedForStr, hostnameStr, platformStr, usernameStr, appStr, labelsStr, lastClaimedAtStr, lastReleasedAtStr},
 			}
 
 			var renderer ui.TableRenderer
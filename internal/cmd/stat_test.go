@@ -49,6 +49,35 @@ func TestStatCmd_Success(t *testing.T) {
 	assert.Contains(t, outBuf.String(), "2024-01-05T10:00:00Z")
 }
 
+func TestStatCmd_NodeMetadata(t *testing.T) {
+	nodeID := int64(123)
+	hostname, platform, labels := "build-01", "linux/amd64", `{"team":"platform","env":"ci"}`
+
+	manager := &testutils.FakeManager{
+		GetLicenseByGUIDFn: func(ctx context.Context, pool *string, id string) (*db.License, error) {
+			return &db.License{ID: 1, Guid: "License_1", Key: "License_Key1", Claims: 1, NodeID: &nodeID}, nil
+		},
+		GetNodeByIDFn: func(ctx context.Context, id int64) (*db.Node, error) {
+			assert.Equal(t, nodeID, id)
+
+			return &db.Node{ID: nodeID, Hostname: &hostname, Platform: &platform, Labels: &labels}, nil
+		},
+	}
+
+	outBuf := new(bytes.Buffer)
+
+	statCmd := cmd.StatCmd(manager)
+	statCmd.SetOut(outBuf)
+	statCmd.SetArgs([]string{"--license=License_1", "--plain"})
+
+	err := statCmd.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, outBuf.String(), "build-01")
+	assert.Contains(t, outBuf.String(), "linux/amd64")
+	assert.Contains(t, outBuf.String(), "env=ci,team=platform")
+}
+
 func TestStatCmd_MissingFlag(t *testing.T) {
 	manager := &testutils.FakeManager{}
 
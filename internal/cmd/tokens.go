@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/output"
+	"github.com/keygen-sh/keygen-relay/internal/try"
+	"github.com/keygen-sh/keygen-relay/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func TokensCmd(manager licenses.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "tokens",
+		Short:        "manage pool-scoped api tokens for authenticating nodes",
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(tokensCreateCmd(manager))
+	cmd.AddCommand(tokensLsCmd(manager))
+	cmd.AddCommand(tokensDelCmd(manager))
+
+	return cmd
+}
+
+func tokensCreateCmd(manager licenses.Manager) *cobra.Command {
+	var pool *string
+
+	cmd := &cobra.Command{
+		Use:          "create",
+		Short:        "create an api token scoped to a pool",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// workaround for lack of support for nullable string flags
+			if p, err := cmd.Flags().GetString("pool"); err == nil {
+				if p != "" {
+					pool = &p
+				}
+			}
+
+			token, apiToken, err := manager.CreateAPIToken(cmd.Context(), pool)
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			// the token is only stored as a digest, so this is the only time it can be shown
+			output.PrintSuccess(cmd.OutOrStdout(), "api token created successfully: %d", apiToken.ID)
+			output.Print(cmd.OutOrStdout(), "%s", token)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("pool", try.Try(try.Env("RELAY_POOL"), try.Static("")), "pool to scope the api token to [$RELAY_POOL=prod]")
+
+	_ = cmd.RegisterFlagCompletionFunc("pool", poolTypeCompletion)
+
+	return cmd
+}
+
+func tokensLsCmd(manager licenses.Manager) *cobra.Command {
+	var plain bool
+
+	cmd := &cobra.Command{
+		Use:          "ls",
+		Short:        "print api tokens, with usage stats for each token",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			poolList, err := manager.GetPools(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			pools := make(map[int64]string, len(poolList))
+			for _, p := range poolList {
+				pools[p.ID] = p.Name
+			}
+
+			tokens, err := manager.ListAPITokens(cmd.Context())
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			if len(tokens) == 0 {
+				output.PrintSuccess(cmd.OutOrStdout(), "no api tokens found")
+
+				return nil
+			}
+
+			var renderer ui.TableRenderer
+			if plain {
+				renderer = ui.NewSimpleTableRenderer(cmd.OutOrStdout())
+			} else {
+				renderer = ui.NewBubbleteaTableRenderer()
+			}
+
+			columns := []table.Column{
+				{Title: "id", Width: 8},
+				{Title: "pool", Width: 8}, // start with min width
+				{Title: "uses", Width: 8},
+				{Title: "last_used_at", Width: 20},
+				{Title: "created_at", Width: 20},
+			}
+
+			tableRows := make([]table.Row, 0, len(tokens))
+			for _, token := range tokens {
+				var poolStr string
+				if token.PoolID != nil {
+					if name, ok := pools[*token.PoolID]; ok {
+						poolStr = name
+					} else {
+						poolStr = "<n/a>" // should never happen
+					}
+				} else {
+					poolStr = "-"
+				}
+
+				// update pool column width dynamically
+				if poolWidth := len(poolStr); poolWidth > columns[1].Width && poolWidth <= 32 {
+					columns[1].Width = poolWidth
+				} else if poolWidth > 32 {
+					columns[1].Width = 32
+				}
+
+				tableRows = append(tableRows, table.Row{
+					strconv.FormatInt(token.ID, 10),
+					poolStr,
+					strconv.FormatInt(token.Uses, 10),
+					formatTime(token.LastUsedAt),
+					formatTime(&token.CreatedAt),
+				})
+			}
+
+			if err := renderer.Render(tableRows, columns); err != nil {
+				output.PrintError(cmd.ErrOrStderr(), fmt.Sprintf("error rendering table: %v", err))
+
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&plain, "plain", try.Try(try.EnvBool("RELAY_PLAIN"), try.EnvBool("NO_COLOR"), try.EnvBool("CI"), try.Static(false)), "display the table in plain text format [$RELAY_PLAIN=1]")
+
+	return cmd
+}
+
+func tokensDelCmd(manager licenses.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "del",
+		Short:        "revoke api token(s)",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := cmd.Flags().GetInt64Slice("token")
+			if err != nil {
+				output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+				return nil
+			}
+
+			for _, id := range ids {
+				if err := manager.DeleteAPIToken(cmd.Context(), id); err != nil {
+					output.PrintError(cmd.ErrOrStderr(), err.Error())
+
+					return nil
+				}
+
+				output.PrintSuccess(cmd.OutOrStdout(), "api token deleted successfully: %d", id)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Slice("token", nil, "api token ID to revoke")
+	_ = cmd.MarkFlagRequired("token")
+
+	return cmd
+}
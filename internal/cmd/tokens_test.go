@@ -0,0 +1,132 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/keygen-sh/keygen-relay/internal/cmd"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokensCreateCmd_Success(t *testing.T) {
+	var createdPool *string
+
+	manager := &testutils.FakeManager{
+		CreateAPITokenFn: func(ctx context.Context, pool *string) (string, *db.ApiToken, error) {
+			createdPool = pool
+
+			return "relay_test_token", &db.ApiToken{ID: 1}, nil
+		},
+	}
+
+	tokensCmd := cmd.TokensCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	tokensCmd.SetOut(outBuf)
+	tokensCmd.SetErr(errBuf)
+
+	tokensCmd.SetArgs([]string{"create", "--pool=prod"})
+
+	err := tokensCmd.Execute()
+	assert.NoError(t, err)
+	assert.Empty(t, errBuf.String())
+	assert.Contains(t, outBuf.String(), "api token created successfully: 1")
+	assert.Contains(t, outBuf.String(), "relay_test_token")
+	assert.Equal(t, "prod", *createdPool)
+}
+
+func TestTokensCreateCmd_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		CreateAPITokenFn: func(ctx context.Context, pool *string) (string, *db.ApiToken, error) {
+			return "", nil, licenses.ErrBadPool
+		},
+	}
+
+	tokensCmd := cmd.TokensCmd(manager)
+
+	errBuf := new(bytes.Buffer)
+	tokensCmd.SetOut(new(bytes.Buffer))
+	tokensCmd.SetErr(errBuf)
+
+	tokensCmd.SetArgs([]string{"create", "--pool=unknown"})
+
+	_ = tokensCmd.Execute()
+
+	assert.Contains(t, errBuf.String(), "error: pool not found")
+}
+
+func TestTokensLsCmd_Success(t *testing.T) {
+	poolID := int64(1)
+	lastUsedAt := int64(1764949490)
+
+	manager := &testutils.FakeManager{
+		GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+			return []db.Pool{{ID: poolID, Name: "prod"}}, nil
+		},
+		ListAPITokensFn: func(ctx context.Context) ([]db.ApiToken, error) {
+			return []db.ApiToken{
+				{ID: 1, PoolID: &poolID, Uses: 42, LastUsedAt: &lastUsedAt, CreatedAt: 1764949400},
+				{ID: 2, CreatedAt: 1764949400},
+			}, nil
+		},
+	}
+
+	tokensCmd := cmd.TokensCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	tokensCmd.SetOut(outBuf)
+	tokensCmd.SetErr(new(bytes.Buffer))
+
+	tokensCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := tokensCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, outBuf.String(), "prod")
+	assert.Contains(t, outBuf.String(), "42")
+	assert.Contains(t, outBuf.String(), "2025-12-05T15:44:50Z")
+}
+
+func TestTokensLsCmd_Empty(t *testing.T) {
+	tokensCmd := cmd.TokensCmd(&testutils.FakeManager{})
+
+	outBuf := new(bytes.Buffer)
+	tokensCmd.SetOut(outBuf)
+
+	tokensCmd.SetArgs([]string{"ls", "--plain"})
+
+	err := tokensCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, outBuf.String(), "no api tokens found")
+}
+
+func TestTokensDelCmd(t *testing.T) {
+	manager := &testutils.FakeManager{
+		DeleteAPITokenFn: func(ctx context.Context, id int64) error {
+			if id != 1 {
+				return fmt.Errorf("api token %d: %w", id, licenses.ErrAPITokenNotFound)
+			}
+
+			return nil
+		},
+	}
+
+	tokensCmd := cmd.TokensCmd(manager)
+
+	outBuf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	tokensCmd.SetOut(outBuf)
+	tokensCmd.SetErr(errBuf)
+
+	tokensCmd.SetArgs([]string{"del", "--token=1", "--token=2"})
+
+	_ = tokensCmd.Execute()
+
+	assert.Contains(t, outBuf.String(), "api token deleted successfully: 1")
+	assert.Contains(t, errBuf.String(), "error: api token 2: api token not found")
+}
@@ -0,0 +1,132 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: api_tokens.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deleteAPITokenByID = `-- name: DeleteAPITokenByID :one
+DELETE FROM api_tokens
+WHERE id = ?
+RETURNING id, digest, pool_id, uses, last_used_at, created_at
+`
+
+func (q *Queries) DeleteAPITokenByID(ctx context.Context, id int64) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, deleteAPITokenByID, id)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Digest,
+		&i.PoolID,
+		&i.Uses,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPITokenByDigest = `-- name: GetAPITokenByDigest :one
+SELECT id, digest, pool_id, uses, last_used_at, created_at
+FROM api_tokens
+WHERE digest = ?
+`
+
+func (q *Queries) GetAPITokenByDigest(ctx context.Context, digest string) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, getAPITokenByDigest, digest)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Digest,
+		&i.PoolID,
+		&i.Uses,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPITokens = `-- name: GetAPITokens :many
+SELECT id, digest, pool_id, uses, last_used_at, created_at
+FROM api_tokens
+ORDER BY id
+`
+
+func (q *Queries) GetAPITokens(ctx context.Context) ([]ApiToken, error) {
+	rows, err := q.db.QueryContext(ctx, getAPITokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiToken
+	for rows.Next() {
+		var i ApiToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Digest,
+			&i.PoolID,
+			&i.Uses,
+			&i.LastUsedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertAPIToken = `-- name: InsertAPIToken :one
+INSERT INTO api_tokens (digest, pool_id)
+VALUES (?, ?)
+RETURNING id, digest, pool_id, uses, last_used_at, created_at
+`
+
+type InsertAPITokenParams struct {
+	Digest string
+	PoolID *int64
+}
+
+func (q *Queries) InsertAPIToken(ctx context.Context, arg InsertAPITokenParams) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, insertAPIToken, arg.Digest, arg.PoolID)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Digest,
+		&i.PoolID,
+		&i.Uses,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const touchAPITokenByID = `-- name: TouchAPITokenByID :one
+UPDATE api_tokens
+SET uses = uses + 1, last_used_at = unixepoch()
+WHERE id = ?
+RETURNING id, digest, pool_id, uses, last_used_at, created_at
+`
+
+func (q *Queries) TouchAPITokenByID(ctx context.Context, id int64) (ApiToken, error) {
+	row := q.db.QueryRowContext(ctx, touchAPITokenByID, id)
+	var i ApiToken
+	err := row.Scan(
+		&i.ID,
+		&i.Digest,
+		&i.PoolID,
+		&i.Uses,
+		&i.LastUsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
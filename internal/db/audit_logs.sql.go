@@ -106,9 +106,217 @@ func (q *Queries) GetAuditLogsByEntity(ctx context.Context, arg GetAuditLogsByEn
 	return items, nil
 }
 
+const getEventsAfterID = `-- name: GetEventsAfterID :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ?
+ORDER BY audit_logs.id
+LIMIT ?
+`
+
+type GetEventsAfterIDParams struct {
+	ID    int64
+	Limit int64
+}
+
+type GetEventsAfterIDRow struct {
+	ID              int64
+	EventType       string
+	EntityType      string
+	EntityID        int64
+	PoolID          *int64
+	PoolName        *string
+	LicenseGuid     *string
+	NodeFingerprint *string
+	Metadata        *string
+	CreatedAt       int64
+}
+
+func (q *Queries) GetEventsAfterID(ctx context.Context, arg GetEventsAfterIDParams) ([]GetEventsAfterIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEventsAfterID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventsAfterIDRow
+	for rows.Next() {
+		var i GetEventsAfterIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.EntityType,
+			&i.EntityID,
+			&i.PoolID,
+			&i.PoolName,
+			&i.LicenseGuid,
+			&i.NodeFingerprint,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventsAfterIDWithPool = `-- name: GetEventsAfterIDWithPool :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ? AND audit_logs.pool_id = ?
+ORDER BY audit_logs.id
+LIMIT ?
+`
+
+type GetEventsAfterIDWithPoolParams struct {
+	ID     int64
+	PoolID *int64
+	Limit  int64
+}
+
+type GetEventsAfterIDWithPoolRow struct {
+	ID              int64
+	EventType       string
+	EntityType      string
+	EntityID        int64
+	PoolID          *int64
+	PoolName        *string
+	LicenseGuid     *string
+	NodeFingerprint *string
+	Metadata        *string
+	CreatedAt       int64
+}
+
+func (q *Queries) GetEventsAfterIDWithPool(ctx context.Context, arg GetEventsAfterIDWithPoolParams) ([]GetEventsAfterIDWithPoolRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEventsAfterIDWithPool, arg.ID, arg.PoolID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventsAfterIDWithPoolRow
+	for rows.Next() {
+		var i GetEventsAfterIDWithPoolRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.EntityType,
+			&i.EntityID,
+			&i.PoolID,
+			&i.PoolName,
+			&i.LicenseGuid,
+			&i.NodeFingerprint,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventsAfterIDWithoutPool = `-- name: GetEventsAfterIDWithoutPool :many
+SELECT audit_logs.id, event_types.name AS event_type, entity_types.name AS entity_type, audit_logs.entity_id, audit_logs.pool_id, pools.name AS pool_name, licenses.guid AS license_guid, nodes.fingerprint AS node_fingerprint, audit_logs.metadata, audit_logs.created_at
+FROM audit_logs
+INNER JOIN event_types ON event_types.id = audit_logs.event_type_id
+INNER JOIN entity_types ON entity_types.id = audit_logs.entity_type_id
+LEFT JOIN pools ON pools.id = audit_logs.pool_id
+LEFT JOIN licenses ON audit_logs.entity_type_id = 1 AND licenses.id = audit_logs.entity_id
+LEFT JOIN nodes ON audit_logs.entity_type_id = 2 AND nodes.id = audit_logs.entity_id
+WHERE audit_logs.id > ? AND audit_logs.pool_id IS NULL
+ORDER BY audit_logs.id
+LIMIT ?
+`
+
+type GetEventsAfterIDWithoutPoolParams struct {
+	ID    int64
+	Limit int64
+}
+
+type GetEventsAfterIDWithoutPoolRow struct {
+	ID              int64
+	EventType       string
+	EntityType      string
+	EntityID        int64
+	PoolID          *int64
+	PoolName        *string
+	LicenseGuid     *string
+	NodeFingerprint *string
+	Metadata        *string
+	CreatedAt       int64
+}
+
+func (q *Queries) GetEventsAfterIDWithoutPool(ctx context.Context, arg GetEventsAfterIDWithoutPoolParams) ([]GetEventsAfterIDWithoutPoolRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEventsAfterIDWithoutPool, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventsAfterIDWithoutPoolRow
+	for rows.Next() {
+		var i GetEventsAfterIDWithoutPoolRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventType,
+			&i.EntityType,
+			&i.EntityID,
+			&i.PoolID,
+			&i.PoolName,
+			&i.LicenseGuid,
+			&i.NodeFingerprint,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastAuditLogID = `-- name: GetLastAuditLogID :one
+SELECT CAST(COALESCE(MAX(id), 0) AS INTEGER)
+FROM audit_logs
+`
+
+func (q *Queries) GetLastAuditLogID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastAuditLogID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const insertAuditLog = `-- name: InsertAuditLog :exec
-INSERT INTO audit_logs (event_type_id, entity_type_id, entity_id, pool_id)
-VALUES (?, ?, ?, ?)
+INSERT INTO audit_logs (event_type_id, entity_type_id, entity_id, pool_id, metadata)
+VALUES (?, ?, ?, ?, ?)
 `
 
 type InsertAuditLogParams struct {
@@ -116,6 +324,7 @@ type InsertAuditLogParams struct {
 	EntityTypeID int64
 	EntityID     int64
 	PoolID       *int64
+	Metadata     *string
 }
 
 func (q *Queries) InsertAuditLog(ctx context.Context, arg InsertAuditLogParams) error {
@@ -124,6 +333,7 @@ func (q *Queries) InsertAuditLog(ctx context.Context, arg InsertAuditLogParams)
 		arg.EntityTypeID,
 		arg.EntityID,
 		arg.PoolID,
+		arg.Metadata,
 	)
 	return err
 }
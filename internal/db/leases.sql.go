@@ -0,0 +1,372 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: leases.sql
+
+package db
+
+import (
+	"context"
+)
+
+const getLeaseByFingerprint = `-- name: GetLeaseByFingerprint :one
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL
+`
+
+type GetLeaseByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+type GetLeaseByFingerprintRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeaseByFingerprint(ctx context.Context, arg GetLeaseByFingerprintParams) (GetLeaseByFingerprintRow, error) {
+	row := q.db.QueryRowContext(ctx, getLeaseByFingerprint, arg.Fingerprint, arg.LeaseID)
+	var i GetLeaseByFingerprintRow
+	err := row.Scan(
+		&i.Node.ID,
+		&i.Node.Fingerprint,
+		&i.Node.LeaseID,
+		&i.Node.LastHeartbeatAt,
+		&i.Node.CreatedAt,
+		&i.Node.DeactivatedAt,
+		&i.Node.Ttl,
+		&i.Node.LeaseTokenDigest,
+		&i.Node.Hostname,
+		&i.Node.Platform,
+		&i.Node.Username,
+		&i.Node.AppName,
+		&i.Node.AppVersion,
+		&i.Node.Labels,
+		&i.License.ID,
+		&i.License.Guid,
+		&i.License.File,
+		&i.License.Key,
+		&i.License.Claims,
+		&i.License.LastClaimedAt,
+		&i.License.LastReleasedAt,
+		&i.License.NodeID,
+		&i.License.PoolID,
+		&i.License.CreatedAt,
+		&i.License.ReservedFingerprint,
+		&i.License.LastNodeID,
+	)
+	return i, err
+}
+
+const getLeaseWithPoolByFingerprint = `-- name: GetLeaseWithPoolByFingerprint :one
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL AND licenses.pool_id = ?
+`
+
+type GetLeaseWithPoolByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+	PoolID      *int64
+}
+
+type GetLeaseWithPoolByFingerprintRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeaseWithPoolByFingerprint(ctx context.Context, arg GetLeaseWithPoolByFingerprintParams) (GetLeaseWithPoolByFingerprintRow, error) {
+	row := q.db.QueryRowContext(ctx, getLeaseWithPoolByFingerprint, arg.Fingerprint, arg.LeaseID, arg.PoolID)
+	var i GetLeaseWithPoolByFingerprintRow
+	err := row.Scan(
+		&i.Node.ID,
+		&i.Node.Fingerprint,
+		&i.Node.LeaseID,
+		&i.Node.LastHeartbeatAt,
+		&i.Node.CreatedAt,
+		&i.Node.DeactivatedAt,
+		&i.Node.Ttl,
+		&i.Node.LeaseTokenDigest,
+		&i.Node.Hostname,
+		&i.Node.Platform,
+		&i.Node.Username,
+		&i.Node.AppName,
+		&i.Node.AppVersion,
+		&i.Node.Labels,
+		&i.License.ID,
+		&i.License.Guid,
+		&i.License.File,
+		&i.License.Key,
+		&i.License.Claims,
+		&i.License.LastClaimedAt,
+		&i.License.LastReleasedAt,
+		&i.License.NodeID,
+		&i.License.PoolID,
+		&i.License.CreatedAt,
+		&i.License.ReservedFingerprint,
+		&i.License.LastNodeID,
+	)
+	return i, err
+}
+
+const getLeaseWithoutPoolByFingerprint = `-- name: GetLeaseWithoutPoolByFingerprint :one
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.lease_id = ? AND nodes.deactivated_at IS NULL AND licenses.pool_id IS NULL
+`
+
+type GetLeaseWithoutPoolByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+type GetLeaseWithoutPoolByFingerprintRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeaseWithoutPoolByFingerprint(ctx context.Context, arg GetLeaseWithoutPoolByFingerprintParams) (GetLeaseWithoutPoolByFingerprintRow, error) {
+	row := q.db.QueryRowContext(ctx, getLeaseWithoutPoolByFingerprint, arg.Fingerprint, arg.LeaseID)
+	var i GetLeaseWithoutPoolByFingerprintRow
+	err := row.Scan(
+		&i.Node.ID,
+		&i.Node.Fingerprint,
+		&i.Node.LeaseID,
+		&i.Node.LastHeartbeatAt,
+		&i.Node.CreatedAt,
+		&i.Node.DeactivatedAt,
+		&i.Node.Ttl,
+		&i.Node.LeaseTokenDigest,
+		&i.Node.Hostname,
+		&i.Node.Platform,
+		&i.Node.Username,
+		&i.Node.AppName,
+		&i.Node.AppVersion,
+		&i.Node.Labels,
+		&i.License.ID,
+		&i.License.Guid,
+		&i.License.File,
+		&i.License.Key,
+		&i.License.Claims,
+		&i.License.LastClaimedAt,
+		&i.License.LastReleasedAt,
+		&i.License.NodeID,
+		&i.License.PoolID,
+		&i.License.CreatedAt,
+		&i.License.ReservedFingerprint,
+		&i.License.LastNodeID,
+	)
+	return i, err
+}
+
+const getLeases = `-- name: GetLeases :many
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL
+ORDER BY nodes.id
+LIMIT ? OFFSET ?
+`
+
+type GetLeasesParams struct {
+	Limit  int64
+	Offset int64
+}
+
+type GetLeasesRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeases(ctx context.Context, arg GetLeasesParams) ([]GetLeasesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLeases, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLeasesRow
+	for rows.Next() {
+		var i GetLeasesRow
+		if err := rows.Scan(
+			&i.Node.ID,
+			&i.Node.Fingerprint,
+			&i.Node.LeaseID,
+			&i.Node.LastHeartbeatAt,
+			&i.Node.CreatedAt,
+			&i.Node.DeactivatedAt,
+			&i.Node.Ttl,
+			&i.Node.LeaseTokenDigest,
+			&i.Node.Hostname,
+			&i.Node.Platform,
+			&i.Node.Username,
+			&i.Node.AppName,
+			&i.Node.AppVersion,
+			&i.Node.Labels,
+			&i.License.ID,
+			&i.License.Guid,
+			&i.License.File,
+			&i.License.Key,
+			&i.License.Claims,
+			&i.License.LastClaimedAt,
+			&i.License.LastReleasedAt,
+			&i.License.NodeID,
+			&i.License.PoolID,
+			&i.License.CreatedAt,
+			&i.License.ReservedFingerprint,
+			&i.License.LastNodeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLeasesWithPool = `-- name: GetLeasesWithPool :many
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL AND licenses.pool_id = ?
+ORDER BY nodes.id
+LIMIT ? OFFSET ?
+`
+
+type GetLeasesWithPoolParams struct {
+	PoolID *int64
+	Limit  int64
+	Offset int64
+}
+
+type GetLeasesWithPoolRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeasesWithPool(ctx context.Context, arg GetLeasesWithPoolParams) ([]GetLeasesWithPoolRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLeasesWithPool, arg.PoolID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLeasesWithPoolRow
+	for rows.Next() {
+		var i GetLeasesWithPoolRow
+		if err := rows.Scan(
+			&i.Node.ID,
+			&i.Node.Fingerprint,
+			&i.Node.LeaseID,
+			&i.Node.LastHeartbeatAt,
+			&i.Node.CreatedAt,
+			&i.Node.DeactivatedAt,
+			&i.Node.Ttl,
+			&i.Node.LeaseTokenDigest,
+			&i.Node.Hostname,
+			&i.Node.Platform,
+			&i.Node.Username,
+			&i.Node.AppName,
+			&i.Node.AppVersion,
+			&i.Node.Labels,
+			&i.License.ID,
+			&i.License.Guid,
+			&i.License.File,
+			&i.License.Key,
+			&i.License.Claims,
+			&i.License.LastClaimedAt,
+			&i.License.LastReleasedAt,
+			&i.License.NodeID,
+			&i.License.PoolID,
+			&i.License.CreatedAt,
+			&i.License.ReservedFingerprint,
+			&i.License.LastNodeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLeasesWithoutPool = `-- name: GetLeasesWithoutPool :many
+SELECT nodes.id, nodes.fingerprint, nodes.lease_id, nodes.last_heartbeat_at, nodes.created_at, nodes.deactivated_at, nodes.ttl, nodes.lease_token_digest, nodes.hostname, nodes.platform, nodes.username, nodes.app_name, nodes.app_version, nodes.labels, licenses.id, licenses.guid, licenses.file, licenses."key", licenses.claims, licenses.last_claimed_at, licenses.last_released_at, licenses.node_id, licenses.pool_id, licenses.created_at, licenses.reserved_fingerprint, licenses.last_node_id
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.deactivated_at IS NULL AND licenses.pool_id IS NULL
+ORDER BY nodes.id
+LIMIT ? OFFSET ?
+`
+
+type GetLeasesWithoutPoolParams struct {
+	Limit  int64
+	Offset int64
+}
+
+type GetLeasesWithoutPoolRow struct {
+	Node    Node
+	License License
+}
+
+func (q *Queries) GetLeasesWithoutPool(ctx context.Context, arg GetLeasesWithoutPoolParams) ([]GetLeasesWithoutPoolRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLeasesWithoutPool, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLeasesWithoutPoolRow
+	for rows.Next() {
+		var i GetLeasesWithoutPoolRow
+		if err := rows.Scan(
+			&i.Node.ID,
+			&i.Node.Fingerprint,
+			&i.Node.LeaseID,
+			&i.Node.LastHeartbeatAt,
+			&i.Node.CreatedAt,
+			&i.Node.DeactivatedAt,
+			&i.Node.Ttl,
+			&i.Node.LeaseTokenDigest,
+			&i.Node.Hostname,
+			&i.Node.Platform,
+			&i.Node.Username,
+			&i.Node.AppName,
+			&i.Node.AppVersion,
+			&i.Node.Labels,
+			&i.License.ID,
+			&i.License.Guid,
+			&i.License.File,
+			&i.License.Key,
+			&i.License.Claims,
+			&i.License.LastClaimedAt,
+			&i.License.LastReleasedAt,
+			&i.License.NodeID,
+			&i.License.PoolID,
+			&i.License.CreatedAt,
+			&i.License.ReservedFingerprint,
+			&i.License.LastNodeID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -9,17 +9,61 @@ import (
 	"context"
 )
 
+const claimLicenseWithPoolAffinity = `-- name: ClaimLicenseWithPoolAffinity :one
+UPDATE licenses
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
+WHERE id = (
+    SELECT l.id
+    FROM licenses l
+    WHERE l.node_id IS NULL AND l.pool_id = ?2 AND (
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1) OR
+        (l.reserved_fingerprint IS NULL AND l.last_node_id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.last_claimed_at DESC
+    LIMIT 1
+)
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
+`
+
+type ClaimLicenseWithPoolAffinityParams struct {
+	NodeID *int64
+	PoolID *int64
+}
+
+func (q *Queries) ClaimLicenseWithPoolAffinity(ctx context.Context, arg ClaimLicenseWithPoolAffinityParams) (License, error) {
+	row := q.db.QueryRowContext(ctx, claimLicenseWithPoolAffinity, arg.NodeID, arg.PoolID)
+	var i License
+	err := row.Scan(
+		&i.ID,
+		&i.Guid,
+		&i.File,
+		&i.Key,
+		&i.Claims,
+		&i.LastClaimedAt,
+		&i.LastReleasedAt,
+		&i.NodeID,
+		&i.PoolID,
+		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
+	)
+	return i, err
+}
+
 const claimLicenseWithPoolFIFO = `-- name: ClaimLicenseWithPoolFIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY l.created_at ASC
+    WHERE l.node_id IS NULL AND l.pool_id = ?2 AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at ASC
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 type ClaimLicenseWithPoolFIFOParams struct {
@@ -41,21 +85,26 @@ func (q *Queries) ClaimLicenseWithPoolFIFO(ctx context.Context, arg ClaimLicense
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const claimLicenseWithPoolLIFO = `-- name: ClaimLicenseWithPoolLIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
-     SELECT l.id
+    SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY l.created_at DESC
+    WHERE l.node_id IS NULL AND l.pool_id = ?2 AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at DESC
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 type ClaimLicenseWithPoolLIFOParams struct {
@@ -77,21 +126,26 @@ func (q *Queries) ClaimLicenseWithPoolLIFO(ctx context.Context, arg ClaimLicense
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const claimLicenseWithPoolRandom = `-- name: ClaimLicenseWithPoolRandom :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id = ?
-    ORDER BY RANDOM()
+    WHERE l.node_id IS NULL AND l.pool_id = ?2 AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, RANDOM()
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 type ClaimLicenseWithPoolRandomParams struct {
@@ -113,21 +167,62 @@ func (q *Queries) ClaimLicenseWithPoolRandom(ctx context.Context, arg ClaimLicen
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
+	)
+	return i, err
+}
+
+const claimLicenseWithoutPoolAffinity = `-- name: ClaimLicenseWithoutPoolAffinity :one
+UPDATE licenses
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
+WHERE id = (
+    SELECT l.id
+    FROM licenses l
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1) OR
+        (l.reserved_fingerprint IS NULL AND l.last_node_id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.last_claimed_at DESC
+    LIMIT 1
+)
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
+`
+
+func (q *Queries) ClaimLicenseWithoutPoolAffinity(ctx context.Context, nodeID *int64) (License, error) {
+	row := q.db.QueryRowContext(ctx, claimLicenseWithoutPoolAffinity, nodeID)
+	var i License
+	err := row.Scan(
+		&i.ID,
+		&i.Guid,
+		&i.File,
+		&i.Key,
+		&i.Claims,
+		&i.LastClaimedAt,
+		&i.LastReleasedAt,
+		&i.NodeID,
+		&i.PoolID,
+		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const claimLicenseWithoutPoolFIFO = `-- name: ClaimLicenseWithoutPoolFIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY l.created_at ASC
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at ASC
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 func (q *Queries) ClaimLicenseWithoutPoolFIFO(ctx context.Context, nodeID *int64) (License, error) {
@@ -144,21 +239,26 @@ func (q *Queries) ClaimLicenseWithoutPoolFIFO(ctx context.Context, nodeID *int64
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const claimLicenseWithoutPoolLIFO = `-- name: ClaimLicenseWithoutPoolLIFO :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY l.created_at DESC
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, l.created_at DESC
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 func (q *Queries) ClaimLicenseWithoutPoolLIFO(ctx context.Context, nodeID *int64) (License, error) {
@@ -175,21 +275,26 @@ func (q *Queries) ClaimLicenseWithoutPoolLIFO(ctx context.Context, nodeID *int64
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const claimLicenseWithoutPoolRandom = `-- name: ClaimLicenseWithoutPoolRandom :one
 UPDATE licenses
-SET node_id = ?, last_claimed_at = unixepoch(), claims = claims + 1
+SET node_id = ?1, last_node_id = ?1, last_claimed_at = unixepoch(), claims = claims + 1
 WHERE id = (
     SELECT l.id
     FROM licenses l
-    WHERE l.node_id IS NULL AND l.pool_id IS NULL
-    ORDER BY RANDOM()
+    WHERE l.node_id IS NULL AND l.pool_id IS NULL AND (
+        l.reserved_fingerprint IS NULL OR
+        l.reserved_fingerprint = (SELECT n.fingerprint FROM nodes n WHERE n.id = ?1)
+    )
+    ORDER BY l.reserved_fingerprint IS NULL, RANDOM()
     LIMIT 1
 )
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 func (q *Queries) ClaimLicenseWithoutPoolRandom(ctx context.Context, nodeID *int64) (License, error) {
@@ -206,6 +311,8 @@ func (q *Queries) ClaimLicenseWithoutPoolRandom(ctx context.Context, nodeID *int
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
@@ -213,7 +320,7 @@ func (q *Queries) ClaimLicenseWithoutPoolRandom(ctx context.Context, nodeID *int
 const deleteLicenseByGUID = `-- name: DeleteLicenseByGUID :one
 DELETE FROM licenses
 WHERE guid = ?
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 func (q *Queries) DeleteLicenseByGUID(ctx context.Context, guid string) (License, error) {
@@ -230,12 +337,14 @@ func (q *Queries) DeleteLicenseByGUID(ctx context.Context, guid string) (License
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const getLicenseByGUID = `-- name: GetLicenseByGUID :one
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE guid = ?
 `
@@ -254,12 +363,50 @@ func (q *Queries) GetLicenseByGUID(ctx context.Context, guid string) (License, e
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
+const getLicenseStats = `-- name: GetLicenseStats :many
+SELECT pool_id, COUNT(*) AS total, COUNT(node_id) AS leased
+FROM licenses
+GROUP BY pool_id
+ORDER BY pool_id
+`
+
+type GetLicenseStatsRow struct {
+	PoolID *int64
+	Total  int64
+	Leased int64
+}
+
+func (q *Queries) GetLicenseStats(ctx context.Context) ([]GetLicenseStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLicenseStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLicenseStatsRow
+	for rows.Next() {
+		var i GetLicenseStatsRow
+		if err := rows.Scan(&i.PoolID, &i.Total, &i.Leased); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLicenseWithPoolByGUID = `-- name: GetLicenseWithPoolByGUID :one
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE guid = ? AND pool_id = ?
 `
@@ -283,12 +430,14 @@ func (q *Queries) GetLicenseWithPoolByGUID(ctx context.Context, arg GetLicenseWi
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const getLicenseWithPoolByNodeID = `-- name: GetLicenseWithPoolByNodeID :one
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE node_id = ? AND pool_id = ?
 `
@@ -312,12 +461,14 @@ func (q *Queries) GetLicenseWithPoolByNodeID(ctx context.Context, arg GetLicense
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const getLicenseWithoutPoolByGUID = `-- name: GetLicenseWithoutPoolByGUID :one
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE guid = ? AND pool_id IS NULL
 `
@@ -336,12 +487,14 @@ func (q *Queries) GetLicenseWithoutPoolByGUID(ctx context.Context, guid string)
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const getLicenseWithoutPoolByNodeID = `-- name: GetLicenseWithoutPoolByNodeID :one
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE node_id = ? AND pool_id IS NULL
 `
@@ -360,12 +513,14 @@ func (q *Queries) GetLicenseWithoutPoolByNodeID(ctx context.Context, nodeID *int
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
 
 const getLicenses = `-- name: GetLicenses :many
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 ORDER BY id
 `
@@ -390,6 +545,8 @@ func (q *Queries) GetLicenses(ctx context.Context) ([]License, error) {
 			&i.NodeID,
 			&i.PoolID,
 			&i.CreatedAt,
+			&i.ReservedFingerprint,
+			&i.LastNodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -405,7 +562,7 @@ func (q *Queries) GetLicenses(ctx context.Context) ([]License, error) {
 }
 
 const getLicensesWithPool = `-- name: GetLicensesWithPool :many
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE pool_id = ?
 ORDER BY id
@@ -431,6 +588,8 @@ func (q *Queries) GetLicensesWithPool(ctx context.Context, poolID *int64) ([]Lic
 			&i.NodeID,
 			&i.PoolID,
 			&i.CreatedAt,
+			&i.ReservedFingerprint,
+			&i.LastNodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -446,7 +605,7 @@ func (q *Queries) GetLicensesWithPool(ctx context.Context, poolID *int64) ([]Lic
 }
 
 const getLicensesWithoutPool = `-- name: GetLicensesWithoutPool :many
-SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+SELECT id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 FROM licenses
 WHERE pool_id IS NULL
 ORDER BY id
@@ -472,6 +631,8 @@ func (q *Queries) GetLicensesWithoutPool(ctx context.Context) ([]License, error)
 			&i.NodeID,
 			&i.PoolID,
 			&i.CreatedAt,
+			&i.ReservedFingerprint,
+			&i.LastNodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -489,7 +650,7 @@ func (q *Queries) GetLicensesWithoutPool(ctx context.Context) ([]License, error)
 const insertLicense = `-- name: InsertLicense :one
 INSERT INTO licenses (pool_id, guid, file, key)
 VALUES (?, ?, ?, ?)
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
 type InsertLicenseParams struct {
@@ -518,6 +679,8 @@ func (q *Queries) InsertLicense(ctx context.Context, arg InsertLicenseParams) (L
 		&i.NodeID,
 		&i.PoolID,
 		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
 	)
 	return i, err
 }
@@ -554,13 +717,18 @@ UPDATE licenses
 SET node_id = NULL, last_released_at = unixepoch()
 WHERE node_id IN (
     SELECT id FROM nodes
-    WHERE last_heartbeat_at <= strftime('%s', 'now', ?) AND deactivated_at IS NULL
-)
-RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at
+    WHERE last_heartbeat_at + COALESCE(nodes.ttl, ?1) <= unixepoch() AND deactivated_at IS NULL
+) AND COALESCE((SELECT heartbeat FROM pools WHERE pools.id = licenses.pool_id), CAST(?2 AS BOOLEAN))
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
 `
 
-func (q *Queries) ReleaseLicensesFromDeadNodes(ctx context.Context, strftime interface{}) ([]License, error) {
-	rows, err := q.db.QueryContext(ctx, releaseLicensesFromDeadNodes, strftime)
+type ReleaseLicensesFromDeadNodesParams struct {
+	DefaultTtl       *int64
+	DefaultHeartbeat bool
+}
+
+func (q *Queries) ReleaseLicensesFromDeadNodes(ctx context.Context, arg ReleaseLicensesFromDeadNodesParams) ([]License, error) {
+	rows, err := q.db.QueryContext(ctx, releaseLicensesFromDeadNodes, arg.DefaultTtl, arg.DefaultHeartbeat)
 	if err != nil {
 		return nil, err
 	}
@@ -579,6 +747,8 @@ func (q *Queries) ReleaseLicensesFromDeadNodes(ctx context.Context, strftime int
 			&i.NodeID,
 			&i.PoolID,
 			&i.CreatedAt,
+			&i.ReservedFingerprint,
+			&i.LastNodeID,
 		); err != nil {
 			return nil, err
 		}
@@ -592,3 +762,35 @@ func (q *Queries) ReleaseLicensesFromDeadNodes(ctx context.Context, strftime int
 	}
 	return items, nil
 }
+
+const setLicenseReservedFingerprintByID = `-- name: SetLicenseReservedFingerprintByID :one
+UPDATE licenses
+SET reserved_fingerprint = ?
+WHERE id = ?
+RETURNING id, guid, file, "key", claims, last_claimed_at, last_released_at, node_id, pool_id, created_at, reserved_fingerprint, last_node_id
+`
+
+type SetLicenseReservedFingerprintByIDParams struct {
+	ReservedFingerprint *string
+	ID                  int64
+}
+
+func (q *Queries) SetLicenseReservedFingerprintByID(ctx context.Context, arg SetLicenseReservedFingerprintByIDParams) (License, error) {
+	row := q.db.QueryRowContext(ctx, setLicenseReservedFingerprintByID, arg.ReservedFingerprint, arg.ID)
+	var i License
+	err := row.Scan(
+		&i.ID,
+		&i.Guid,
+		&i.File,
+		&i.Key,
+		&i.Claims,
+		&i.LastClaimedAt,
+		&i.LastReleasedAt,
+		&i.NodeID,
+		&i.PoolID,
+		&i.CreatedAt,
+		&i.ReservedFingerprint,
+		&i.LastNodeID,
+	)
+	return i, err
+}
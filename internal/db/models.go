@@ -4,6 +4,15 @@
 
 package db
 
+type ApiToken struct {
+	ID         int64
+	Digest     string
+	PoolID     *int64
+	Uses       int64
+	LastUsedAt *int64
+	CreatedAt  int64
+}
+
 type AuditLog struct {
 	ID           int64
 	EventTypeID  int64
@@ -11,6 +20,7 @@ type AuditLog struct {
 	EntityID     int64
 	CreatedAt    int64
 	PoolID       *int64
+	Metadata     *string
 }
 
 type EntityType struct {
@@ -24,28 +34,50 @@ type EventType struct {
 }
 
 type License struct {
-	ID             int64
-	Guid           string
-	File           []byte
-	Key            string
-	Claims         int64
-	LastClaimedAt  *int64
-	LastReleasedAt *int64
-	NodeID         *int64
-	PoolID         *int64
-	CreatedAt      int64
+	ID                  int64
+	Guid                string
+	File                []byte
+	Key                 string
+	Claims              int64
+	LastClaimedAt       *int64
+	LastReleasedAt      *int64
+	NodeID              *int64
+	PoolID              *int64
+	CreatedAt           int64
+	ReservedFingerprint *string
+	LastNodeID          *int64
 }
 
 type Node struct {
-	ID              int64
-	Fingerprint     string
-	LastHeartbeatAt *int64
-	CreatedAt       int64
-	DeactivatedAt   *int64
+	ID               int64
+	Fingerprint      string
+	LeaseID          string
+	LastHeartbeatAt  *int64
+	CreatedAt        int64
+	DeactivatedAt    *int64
+	Ttl              *int64
+	LeaseTokenDigest *string
+	Hostname         *string
+	Platform         *string
+	Username         *string
+	AppName          *string
+	AppVersion       *string
+	Labels           *string
 }
 
 type Pool struct {
 	ID        int64
 	Name      string
 	CreatedAt int64
+	Strategy  *string
+	Ttl       *int64
+	Heartbeat *bool
+}
+
+type PoolCidr struct {
+	ID        int64
+	PoolID    int64
+	Cidr      string
+	Action    string
+	CreatedAt int64
 }
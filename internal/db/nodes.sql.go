@@ -10,47 +10,117 @@ import (
 )
 
 const activateNode = `-- name: ActivateNode :one
-INSERT INTO nodes (fingerprint)
-VALUES (?)
-ON CONFLICT (fingerprint) DO UPDATE SET deactivated_at = NULL
-RETURNING id, fingerprint, last_heartbeat_at, created_at, deactivated_at
+INSERT INTO nodes (fingerprint, lease_id)
+VALUES (?, ?)
+ON CONFLICT (fingerprint, lease_id) DO UPDATE SET deactivated_at = NULL
+RETURNING id, fingerprint, lease_id, last_heartbeat_at, created_at, deactivated_at, ttl, lease_token_digest, hostname, platform, username, app_name, app_version, labels
 `
 
-func (q *Queries) ActivateNode(ctx context.Context, fingerprint string) (Node, error) {
-	row := q.db.QueryRowContext(ctx, activateNode, fingerprint)
+type ActivateNodeParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) ActivateNode(ctx context.Context, arg ActivateNodeParams) (Node, error) {
+	row := q.db.QueryRowContext(ctx, activateNode, arg.Fingerprint, arg.LeaseID)
 	var i Node
 	err := row.Scan(
 		&i.ID,
 		&i.Fingerprint,
+		&i.LeaseID,
 		&i.LastHeartbeatAt,
 		&i.CreatedAt,
 		&i.DeactivatedAt,
+		&i.Ttl,
+		&i.LeaseTokenDigest,
+		&i.Hostname,
+		&i.Platform,
+		&i.Username,
+		&i.AppName,
+		&i.AppVersion,
+		&i.Labels,
 	)
 	return i, err
 }
 
+const countActiveNodes = `-- name: CountActiveNodes :one
+SELECT COUNT(DISTINCT fingerprint)
+FROM nodes
+WHERE deactivated_at IS NULL
+`
+
+func (q *Queries) CountActiveNodes(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveNodes)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLeasesByFingerprint = `-- name: CountLeasesByFingerprint :one
+SELECT COUNT(*)
+FROM nodes
+INNER JOIN licenses ON licenses.node_id = nodes.id
+WHERE nodes.fingerprint = ? AND nodes.deactivated_at IS NULL
+`
+
+func (q *Queries) CountLeasesByFingerprint(ctx context.Context, fingerprint string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLeasesByFingerprint, fingerprint)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deactivateDeadNodes = `-- name: DeactivateDeadNodes :many
 UPDATE nodes
 SET deactivated_at = unixepoch()
-WHERE last_heartbeat_at <= strftime('%s', 'now', ?) AND deactivated_at IS NULL
-RETURNING id, fingerprint, last_heartbeat_at, created_at, deactivated_at
+WHERE last_heartbeat_at + COALESCE(ttl, ?1) <= unixepoch() AND deactivated_at IS NULL
+  AND id NOT IN (SELECT node_id FROM licenses WHERE node_id IS NOT NULL)
+RETURNING (SELECT pool_id FROM licenses WHERE licenses.last_node_id = nodes.id ORDER BY last_released_at DESC LIMIT 1) AS pool_id, id, fingerprint, lease_id, last_heartbeat_at, created_at, deactivated_at, ttl, lease_token_digest, hostname, platform, username, app_name, app_version, labels
 `
 
-func (q *Queries) DeactivateDeadNodes(ctx context.Context, strftime interface{}) ([]Node, error) {
-	rows, err := q.db.QueryContext(ctx, deactivateDeadNodes, strftime)
+type DeactivateDeadNodesRow struct {
+	PoolID           *int64
+	ID               int64
+	Fingerprint      string
+	LeaseID          string
+	LastHeartbeatAt  *int64
+	CreatedAt        int64
+	DeactivatedAt    *int64
+	Ttl              *int64
+	LeaseTokenDigest *string
+	Hostname         *string
+	Platform         *string
+	Username         *string
+	AppName          *string
+	AppVersion       *string
+	Labels           *string
+}
+
+func (q *Queries) DeactivateDeadNodes(ctx context.Context, defaultTtl *int64) ([]DeactivateDeadNodesRow, error) {
+	rows, err := q.db.QueryContext(ctx, deactivateDeadNodes, defaultTtl)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Node
+	var items []DeactivateDeadNodesRow
 	for rows.Next() {
-		var i Node
+		var i DeactivateDeadNodesRow
 		if err := rows.Scan(
+			&i.PoolID,
 			&i.ID,
 			&i.Fingerprint,
+			&i.LeaseID,
 			&i.LastHeartbeatAt,
 			&i.CreatedAt,
 			&i.DeactivatedAt,
+			&i.Ttl,
+			&i.LeaseTokenDigest,
+			&i.Hostname,
+			&i.Platform,
+			&i.Username,
+			&i.AppName,
+			&i.AppVersion,
+			&i.Labels,
 		); err != nil {
 			return nil, err
 		}
@@ -68,29 +138,76 @@ func (q *Queries) DeactivateDeadNodes(ctx context.Context, strftime interface{})
 const deactivateNodeByFingerprint = `-- name: DeactivateNodeByFingerprint :exec
 UPDATE nodes
 SET deactivated_at = unixepoch()
-WHERE fingerprint = ? AND deactivated_at IS NULL
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
 `
 
-func (q *Queries) DeactivateNodeByFingerprint(ctx context.Context, fingerprint string) error {
-	_, err := q.db.ExecContext(ctx, deactivateNodeByFingerprint, fingerprint)
+type DeactivateNodeByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) DeactivateNodeByFingerprint(ctx context.Context, arg DeactivateNodeByFingerprintParams) error {
+	_, err := q.db.ExecContext(ctx, deactivateNodeByFingerprint, arg.Fingerprint, arg.LeaseID)
 	return err
 }
 
 const getNodeByFingerprint = `-- name: GetNodeByFingerprint :one
-SELECT id, fingerprint, last_heartbeat_at, created_at, deactivated_at
+SELECT id, fingerprint, lease_id, last_heartbeat_at, created_at, deactivated_at, ttl, lease_token_digest, hostname, platform, username, app_name, app_version, labels
 FROM nodes
-WHERE fingerprint = ? AND deactivated_at IS NULL
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
 `
 
-func (q *Queries) GetNodeByFingerprint(ctx context.Context, fingerprint string) (Node, error) {
-	row := q.db.QueryRowContext(ctx, getNodeByFingerprint, fingerprint)
+type GetNodeByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) GetNodeByFingerprint(ctx context.Context, arg GetNodeByFingerprintParams) (Node, error) {
+	row := q.db.QueryRowContext(ctx, getNodeByFingerprint, arg.Fingerprint, arg.LeaseID)
 	var i Node
 	err := row.Scan(
 		&i.ID,
 		&i.Fingerprint,
+		&i.LeaseID,
 		&i.LastHeartbeatAt,
 		&i.CreatedAt,
 		&i.DeactivatedAt,
+		&i.Ttl,
+		&i.LeaseTokenDigest,
+		&i.Hostname,
+		&i.Platform,
+		&i.Username,
+		&i.AppName,
+		&i.AppVersion,
+		&i.Labels,
+	)
+	return i, err
+}
+
+const getNodeByID = `-- name: GetNodeByID :one
+SELECT id, fingerprint, lease_id, last_heartbeat_at, created_at, deactivated_at, ttl, lease_token_digest, hostname, platform, username, app_name, app_version, labels
+FROM nodes
+WHERE id = ?
+`
+
+func (q *Queries) GetNodeByID(ctx context.Context, id int64) (Node, error) {
+	row := q.db.QueryRowContext(ctx, getNodeByID, id)
+	var i Node
+	err := row.Scan(
+		&i.ID,
+		&i.Fingerprint,
+		&i.LeaseID,
+		&i.LastHeartbeatAt,
+		&i.CreatedAt,
+		&i.DeactivatedAt,
+		&i.Ttl,
+		&i.LeaseTokenDigest,
+		&i.Hostname,
+		&i.Platform,
+		&i.Username,
+		&i.AppName,
+		&i.AppVersion,
+		&i.Labels,
 	)
 	return i, err
 }
@@ -98,10 +215,80 @@ func (q *Queries) GetNodeByFingerprint(ctx context.Context, fingerprint string)
 const pingNodeHeartbeatByFingerprint = `-- name: PingNodeHeartbeatByFingerprint :exec
 UPDATE nodes
 SET last_heartbeat_at = unixepoch()
-WHERE fingerprint = ? AND deactivated_at IS NULL
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
 `
 
-func (q *Queries) PingNodeHeartbeatByFingerprint(ctx context.Context, fingerprint string) error {
-	_, err := q.db.ExecContext(ctx, pingNodeHeartbeatByFingerprint, fingerprint)
+type PingNodeHeartbeatByFingerprintParams struct {
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) PingNodeHeartbeatByFingerprint(ctx context.Context, arg PingNodeHeartbeatByFingerprintParams) error {
+	_, err := q.db.ExecContext(ctx, pingNodeHeartbeatByFingerprint, arg.Fingerprint, arg.LeaseID)
+	return err
+}
+
+const setNodeLeaseTokenDigestByFingerprint = `-- name: SetNodeLeaseTokenDigestByFingerprint :exec
+UPDATE nodes
+SET lease_token_digest = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
+`
+
+type SetNodeLeaseTokenDigestByFingerprintParams struct {
+	LeaseTokenDigest *string
+	Fingerprint      string
+	LeaseID          string
+}
+
+func (q *Queries) SetNodeLeaseTokenDigestByFingerprint(ctx context.Context, arg SetNodeLeaseTokenDigestByFingerprintParams) error {
+	_, err := q.db.ExecContext(ctx, setNodeLeaseTokenDigestByFingerprint, arg.LeaseTokenDigest, arg.Fingerprint, arg.LeaseID)
+	return err
+}
+
+const setNodeMetadataByFingerprint = `-- name: SetNodeMetadataByFingerprint :exec
+UPDATE nodes
+SET hostname = ?, platform = ?, username = ?, app_name = ?, app_version = ?, labels = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
+`
+
+type SetNodeMetadataByFingerprintParams struct {
+	Hostname    *string
+	Platform    *string
+	Username    *string
+	AppName     *string
+	AppVersion  *string
+	Labels      *string
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) SetNodeMetadataByFingerprint(ctx context.Context, arg SetNodeMetadataByFingerprintParams) error {
+	_, err := q.db.ExecContext(ctx, setNodeMetadataByFingerprint,
+		arg.Hostname,
+		arg.Platform,
+		arg.Username,
+		arg.AppName,
+		arg.AppVersion,
+		arg.Labels,
+		arg.Fingerprint,
+		arg.LeaseID,
+	)
+	return err
+}
+
+const setNodeTTLByFingerprint = `-- name: SetNodeTTLByFingerprint :exec
+UPDATE nodes
+SET ttl = ?
+WHERE fingerprint = ? AND lease_id = ? AND deactivated_at IS NULL
+`
+
+type SetNodeTTLByFingerprintParams struct {
+	Ttl         *int64
+	Fingerprint string
+	LeaseID     string
+}
+
+func (q *Queries) SetNodeTTLByFingerprint(ctx context.Context, arg SetNodeTTLByFingerprintParams) error {
+	_, err := q.db.ExecContext(ctx, setNodeTTLByFingerprint, arg.Ttl, arg.Fingerprint, arg.LeaseID)
 	return err
 }
@@ -0,0 +1,131 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: pool_cidrs.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deletePoolCIDR = `-- name: DeletePoolCIDR :one
+DELETE FROM pool_cidrs
+WHERE pool_id = ? AND cidr = ? AND action = ?
+RETURNING id, pool_id, cidr, "action", created_at
+`
+
+type DeletePoolCIDRParams struct {
+	PoolID int64
+	Cidr   string
+	Action string
+}
+
+func (q *Queries) DeletePoolCIDR(ctx context.Context, arg DeletePoolCIDRParams) (PoolCidr, error) {
+	row := q.db.QueryRowContext(ctx, deletePoolCIDR, arg.PoolID, arg.Cidr, arg.Action)
+	var i PoolCidr
+	err := row.Scan(
+		&i.ID,
+		&i.PoolID,
+		&i.Cidr,
+		&i.Action,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPoolCIDRs = `-- name: GetPoolCIDRs :many
+SELECT id, pool_id, cidr, "action", created_at
+FROM pool_cidrs
+ORDER BY pool_id, action, id
+`
+
+func (q *Queries) GetPoolCIDRs(ctx context.Context) ([]PoolCidr, error) {
+	rows, err := q.db.QueryContext(ctx, getPoolCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PoolCidr
+	for rows.Next() {
+		var i PoolCidr
+		if err := rows.Scan(
+			&i.ID,
+			&i.PoolID,
+			&i.Cidr,
+			&i.Action,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPoolCIDRsByPoolID = `-- name: GetPoolCIDRsByPoolID :many
+SELECT id, pool_id, cidr, "action", created_at
+FROM pool_cidrs
+WHERE pool_id = ?
+ORDER BY action, id
+`
+
+func (q *Queries) GetPoolCIDRsByPoolID(ctx context.Context, poolID int64) ([]PoolCidr, error) {
+	rows, err := q.db.QueryContext(ctx, getPoolCIDRsByPoolID, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PoolCidr
+	for rows.Next() {
+		var i PoolCidr
+		if err := rows.Scan(
+			&i.ID,
+			&i.PoolID,
+			&i.Cidr,
+			&i.Action,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertPoolCIDR = `-- name: InsertPoolCIDR :one
+INSERT INTO pool_cidrs (pool_id, cidr, action)
+VALUES (?, ?, ?)
+RETURNING id, pool_id, cidr, "action", created_at
+`
+
+type InsertPoolCIDRParams struct {
+	PoolID int64
+	Cidr   string
+	Action string
+}
+
+func (q *Queries) InsertPoolCIDR(ctx context.Context, arg InsertPoolCIDRParams) (PoolCidr, error) {
+	row := q.db.QueryRowContext(ctx, insertPoolCIDR, arg.PoolID, arg.Cidr, arg.Action)
+	var i PoolCidr
+	err := row.Scan(
+		&i.ID,
+		&i.PoolID,
+		&i.Cidr,
+		&i.Action,
+		&i.CreatedAt,
+	)
+	return i, err
+}
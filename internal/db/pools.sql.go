@@ -12,31 +12,45 @@ import (
 const createPool = `-- name: CreatePool :one
 INSERT INTO pools (name)
 VALUES (?)
-RETURNING id, name, created_at
+RETURNING id, name, created_at, strategy, ttl, heartbeat
 `
 
 func (q *Queries) CreatePool(ctx context.Context, name string) (Pool, error) {
 	row := q.db.QueryRowContext(ctx, createPool, name)
 	var i Pool
-	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Strategy,
+		&i.Ttl,
+		&i.Heartbeat,
+	)
 	return i, err
 }
 
 const deletePoolByID = `-- name: DeletePoolByID :one
 DELETE FROM pools
 WHERE id = ?
-RETURNING id, name, created_at
+RETURNING id, name, created_at, strategy, ttl, heartbeat
 `
 
 func (q *Queries) DeletePoolByID(ctx context.Context, id int64) (Pool, error) {
 	row := q.db.QueryRowContext(ctx, deletePoolByID, id)
 	var i Pool
-	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Strategy,
+		&i.Ttl,
+		&i.Heartbeat,
+	)
 	return i, err
 }
 
 const getPoolByID = `-- name: GetPoolByID :one
-SELECT id, name, created_at
+SELECT id, name, created_at, strategy, ttl, heartbeat
 FROM pools
 WHERE id = ?
 `
@@ -44,12 +58,19 @@ WHERE id = ?
 func (q *Queries) GetPoolByID(ctx context.Context, id int64) (Pool, error) {
 	row := q.db.QueryRowContext(ctx, getPoolByID, id)
 	var i Pool
-	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Strategy,
+		&i.Ttl,
+		&i.Heartbeat,
+	)
 	return i, err
 }
 
 const getPoolByName = `-- name: GetPoolByName :one
-SELECT id, name, created_at
+SELECT id, name, created_at, strategy, ttl, heartbeat
 FROM pools
 WHERE name = ?
 `
@@ -57,12 +78,19 @@ WHERE name = ?
 func (q *Queries) GetPoolByName(ctx context.Context, name string) (Pool, error) {
 	row := q.db.QueryRowContext(ctx, getPoolByName, name)
 	var i Pool
-	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Strategy,
+		&i.Ttl,
+		&i.Heartbeat,
+	)
 	return i, err
 }
 
 const getPools = `-- name: GetPools :many
-SELECT id, name, created_at
+SELECT id, name, created_at, strategy, ttl, heartbeat
 FROM pools
 ORDER BY id
 `
@@ -76,7 +104,14 @@ func (q *Queries) GetPools(ctx context.Context) ([]Pool, error) {
 	var items []Pool
 	for rows.Next() {
 		var i Pool
-		if err := rows.Scan(&i.ID, &i.Name, &i.CreatedAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.Strategy,
+			&i.Ttl,
+			&i.Heartbeat,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -89,3 +124,36 @@ func (q *Queries) GetPools(ctx context.Context) ([]Pool, error) {
 	}
 	return items, nil
 }
+
+const updatePoolSettingsByID = `-- name: UpdatePoolSettingsByID :one
+UPDATE pools
+SET strategy = ?, ttl = ?, heartbeat = ?
+WHERE id = ?
+RETURNING id, name, created_at, strategy, ttl, heartbeat
+`
+
+type UpdatePoolSettingsByIDParams struct {
+	Strategy  *string
+	Ttl       *int64
+	Heartbeat *bool
+	ID        int64
+}
+
+func (q *Queries) UpdatePoolSettingsByID(ctx context.Context, arg UpdatePoolSettingsByIDParams) (Pool, error) {
+	row := q.db.QueryRowContext(ctx, updatePoolSettingsByID,
+		arg.Strategy,
+		arg.Ttl,
+		arg.Heartbeat,
+		arg.ID,
+	)
+	var i Pool
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.Strategy,
+		&i.Ttl,
+		&i.Heartbeat,
+	)
+	return i, err
+}
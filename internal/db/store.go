@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -25,6 +26,11 @@ const (
 	EventTypeNodeDeactivated
 	EventTypeNodeCulled
 	EventTypePoolAdded
+	EventTypePoolRemoved
+	EventTypePoolAccessDenied
+	EventTypePoolUpdated
+	EventTypeLicenseReserved
+	EventTypeLicenseUnreserved
 )
 
 type EntityTypeId int
@@ -60,6 +66,11 @@ func NewStore(queries *Queries, connection *sql.DB) *Store {
 	}
 }
 
+// Ping verifies the connection to the database is still alive
+func (s *Store) Ping(ctx context.Context) error {
+	return s.connection.PingContext(ctx)
+}
+
 // BeginTx begins a transaction and returns a TxStore that encapsulates transaction operations
 func (s *Store) BeginTx(ctx context.Context) (*TxStore, error) {
 	tx, err := s.connection.BeginTx(ctx, nil)
@@ -162,8 +173,10 @@ func (s *Store) ReleaseLicenseByNodeID(ctx context.Context, nodeID *int64, predi
 	}
 }
 
-func (s *Store) ActivateNode(ctx context.Context, fingerprint string) (*Node, error) {
-	node, err := s.queries.ActivateNode(ctx, fingerprint)
+// ActivateNode activates a node's lease, where an empty lease ID is the node's primary
+// lease and any other lease ID is one of the node's sub-leases
+func (s *Store) ActivateNode(ctx context.Context, fingerprint string, leaseID string) (*Node, error) {
+	node, err := s.queries.ActivateNode(ctx, ActivateNodeParams{fingerprint, leaseID})
 	if err != nil {
 		return nil, err
 	}
@@ -171,12 +184,12 @@ func (s *Store) ActivateNode(ctx context.Context, fingerprint string) (*Node, er
 	return &node, nil
 }
 
-func (s *Store) DeactivateNodeByFingerprint(ctx context.Context, fingerprint string) error {
-	return s.queries.DeactivateNodeByFingerprint(ctx, fingerprint)
+func (s *Store) DeactivateNodeByFingerprint(ctx context.Context, fingerprint string, leaseID string) error {
+	return s.queries.DeactivateNodeByFingerprint(ctx, DeactivateNodeByFingerprintParams{fingerprint, leaseID})
 }
 
-func (s *Store) GetNodeByFingerprint(ctx context.Context, fingerprint string) (*Node, error) {
-	node, err := s.queries.GetNodeByFingerprint(ctx, fingerprint)
+func (s *Store) GetNodeByFingerprint(ctx context.Context, fingerprint string, leaseID string) (*Node, error) {
+	node, err := s.queries.GetNodeByFingerprint(ctx, GetNodeByFingerprintParams{fingerprint, leaseID})
 	if err != nil {
 		return nil, err
 	}
@@ -184,8 +197,57 @@ func (s *Store) GetNodeByFingerprint(ctx context.Context, fingerprint string) (*
 	return &node, nil
 }
 
-func (s *Store) PingNodeHeartbeatByFingerprint(ctx context.Context, fingerprint string) error {
-	return s.queries.PingNodeHeartbeatByFingerprint(ctx, fingerprint)
+func (s *Store) GetNodeByID(ctx context.Context, id int64) (*Node, error) {
+	node, err := s.queries.GetNodeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (s *Store) PingNodeHeartbeatByFingerprint(ctx context.Context, fingerprint string, leaseID string) error {
+	return s.queries.PingNodeHeartbeatByFingerprint(ctx, PingNodeHeartbeatByFingerprintParams{fingerprint, leaseID})
+}
+
+// SetNodeTTLByFingerprint sets a node's TTL, or resets it to the server default when nil
+func (s *Store) SetNodeTTLByFingerprint(ctx context.Context, fingerprint string, leaseID string, ttl *time.Duration) error {
+	var t *int64
+	if ttl != nil {
+		secs := int64(ttl.Seconds())
+		t = &secs
+	}
+
+	return s.queries.SetNodeTTLByFingerprint(ctx, SetNodeTTLByFingerprintParams{Ttl: t, Fingerprint: fingerprint, LeaseID: leaseID})
+}
+
+// SetNodeLeaseTokenDigestByFingerprint sets the digest of a node's current lease token
+func (s *Store) SetNodeLeaseTokenDigestByFingerprint(ctx context.Context, fingerprint string, leaseID string, digest string) error {
+	return s.queries.SetNodeLeaseTokenDigestByFingerprint(ctx, SetNodeLeaseTokenDigestByFingerprintParams{LeaseTokenDigest: &digest, Fingerprint: fingerprint, LeaseID: leaseID})
+}
+
+// SetNodeMetadataByFingerprint sets the metadata reported by a node, or clears it when nil
+func (s *Store) SetNodeMetadataByFingerprint(ctx context.Context, fingerprint string, leaseID string, metadata *NodeMetadata) error {
+	params := SetNodeMetadataByFingerprintParams{Fingerprint: fingerprint, LeaseID: leaseID}
+
+	if metadata != nil {
+		params.Hostname = nullString(metadata.Hostname)
+		params.Platform = nullString(metadata.Platform)
+		params.Username = nullString(metadata.Username)
+		params.AppName = nullString(metadata.AppName)
+		params.AppVersion = nullString(metadata.AppVersion)
+
+		if len(metadata.Labels) > 0 {
+			labels, err := json.Marshal(metadata.Labels)
+			if err != nil {
+				return fmt.Errorf("failed to encode labels: %w", err)
+			}
+
+			params.Labels = nullString(string(labels))
+		}
+	}
+
+	return s.queries.SetNodeMetadataByFingerprint(ctx, params)
 }
 
 func (s *Store) CreatePool(ctx context.Context, name string) (*Pool, error) {
@@ -228,6 +290,95 @@ func (s *Store) DeletePoolByID(ctx context.Context, id int64) (*Pool, error) {
 	return &pool, nil
 }
 
+// UpdatePoolSettingsByID sets a pool's settings, where nil settings fall back to the
+// server's defaults
+func (s *Store) UpdatePoolSettingsByID(ctx context.Context, id int64, strategy *string, ttl *time.Duration, heartbeat *bool) (*Pool, error) {
+	var t *int64
+	if ttl != nil {
+		secs := int64(ttl.Seconds())
+		t = &secs
+	}
+
+	pool, err := s.queries.UpdatePoolSettingsByID(ctx, UpdatePoolSettingsByIDParams{Strategy: strategy, Ttl: t, Heartbeat: heartbeat, ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+func (s *Store) InsertAPIToken(ctx context.Context, pool *Pool, digest string) (*ApiToken, error) {
+	params := InsertAPITokenParams{Digest: digest}
+	if pool != nil {
+		params.PoolID = &pool.ID
+	}
+
+	token, err := s.queries.InsertAPIToken(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *Store) GetAPITokens(ctx context.Context) ([]ApiToken, error) {
+	return s.queries.GetAPITokens(ctx)
+}
+
+func (s *Store) GetAPITokenByDigest(ctx context.Context, digest string) (*ApiToken, error) {
+	token, err := s.queries.GetAPITokenByDigest(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// TouchAPITokenByID increments a token's usage count and sets its last used time
+func (s *Store) TouchAPITokenByID(ctx context.Context, id int64) (*ApiToken, error) {
+	token, err := s.queries.TouchAPITokenByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *Store) DeleteAPITokenByID(ctx context.Context, id int64) (*ApiToken, error) {
+	token, err := s.queries.DeleteAPITokenByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *Store) InsertPoolCIDR(ctx context.Context, pool *Pool, cidr string, action string) (*PoolCidr, error) {
+	poolCIDR, err := s.queries.InsertPoolCIDR(ctx, InsertPoolCIDRParams{PoolID: pool.ID, Cidr: cidr, Action: action})
+	if err != nil {
+		return nil, err
+	}
+
+	return &poolCIDR, nil
+}
+
+func (s *Store) GetPoolCIDRs(ctx context.Context) ([]PoolCidr, error) {
+	return s.queries.GetPoolCIDRs(ctx)
+}
+
+func (s *Store) GetPoolCIDRsByPool(ctx context.Context, pool *Pool) ([]PoolCidr, error) {
+	return s.queries.GetPoolCIDRsByPoolID(ctx, pool.ID)
+}
+
+func (s *Store) DeletePoolCIDR(ctx context.Context, pool *Pool, cidr string, action string) (*PoolCidr, error) {
+	poolCIDR, err := s.queries.DeletePoolCIDR(ctx, DeletePoolCIDRParams{PoolID: pool.ID, Cidr: cidr, Action: action})
+	if err != nil {
+		return nil, err
+	}
+
+	return &poolCIDR, nil
+}
+
 // TODO(ezekg) allow event data? e.g. license.lease_extended {from:x,to:y} or license.leased {node:n} or node.heartbeat_ping {count:n}
 //
 //	but doing so would pose problems for future aggregation...
@@ -250,6 +401,9 @@ type BulkInsertAuditLogParams struct {
 	EntityTypeID EntityTypeId
 	EntityID     int64
 	Pool         *Pool
+
+	// Metadata is a snapshot of the node's metadata at the time of the event, if any
+	Metadata *NodeMetadata
 }
 
 func (s *Store) BulkInsertAuditLogs(ctx context.Context, logs []BulkInsertAuditLogParams) error {
@@ -270,6 +424,15 @@ func (s *Store) BulkInsertAuditLogs(ctx context.Context, logs []BulkInsertAuditL
 			params.PoolID = &log.Pool.ID
 		}
 
+		if log.Metadata != nil {
+			metadata, err := json.Marshal(log.Metadata)
+			if err != nil {
+				return fmt.Errorf("failed to encode audit log metadata: %w", err)
+			}
+
+			params.Metadata = nullString(string(metadata))
+		}
+
 		if err := tx.queries.InsertAuditLog(ctx, params); err != nil {
 			return fmt.Errorf("failed to insert audit log: %w", err)
 		}
@@ -324,6 +487,50 @@ func (s *Store) ClaimLicenseByStrategy(ctx context.Context, strategy string, nod
 	return &license, nil
 }
 
+// ClaimLicenseByAffinity claims the free license most recently held by the node, i.e. by
+// the same fingerprint and lease ID, e.g. so that a restarted node gets the same license
+// back, falling back to claiming a license using the fallback strategy otherwise
+func (s *Store) ClaimLicenseByAffinity(ctx context.Context, fallback string, nodeID *int64, predicates ...LicensePredicateFunc) (*License, error) {
+	if fallback == "affinity" {
+		return nil, ErrBadStrategy
+	}
+
+	predicate := applyLicensePredicates(predicates...)
+
+	var license License
+	var err error
+
+	switch {
+	case predicate.pool == AnyPool:
+		return nil, ErrAnyPoolNotSupported
+	case predicate.pool != nil:
+		license, err = s.queries.ClaimLicenseWithPoolAffinity(ctx, ClaimLicenseWithPoolAffinityParams{nodeID, &predicate.pool.ID})
+	default:
+		license, err = s.queries.ClaimLicenseWithoutPoolAffinity(ctx, nodeID)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.ClaimLicenseByStrategy(ctx, fallback, nodeID, predicates...)
+		}
+
+		return nil, err
+	}
+
+	return &license, nil
+}
+
+// SetLicenseReservedFingerprintByID reserves a license for a node's fingerprint, so that
+// it's only ever claimed by that node, or removes its reservation when nil
+func (s *Store) SetLicenseReservedFingerprintByID(ctx context.Context, id int64, fingerprint *string) (*License, error) {
+	license, err := s.queries.SetLicenseReservedFingerprintByID(ctx, SetLicenseReservedFingerprintByIDParams{fingerprint, id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &license, nil
+}
+
 func (s *Store) GetLicenseByNodeID(ctx context.Context, nodeID *int64, predicates ...LicensePredicateFunc) (*License, error) {
 	predicate := applyLicensePredicates(predicates...)
 
@@ -346,10 +553,221 @@ func (s *Store) GetLicenseByNodeID(ctx context.Context, nodeID *int64, predicate
 	return &license, nil
 }
 
-func (s *Store) ReleaseLicensesFromDeadNodes(ctx context.Context, ttl time.Duration) ([]License, error) {
-	t := fmt.Sprintf("-%d seconds", int(ttl.Seconds()))
+// Lease represents an active node and the license it currently holds a lease on
+type Lease struct {
+	Node    Node
+	License License
+}
+
+func (s *Store) GetLeases(ctx context.Context, limit int64, offset int64, predicates ...LicensePredicateFunc) ([]Lease, error) {
+	predicate := applyLicensePredicates(predicates...)
 
-	licenses, err := s.queries.ReleaseLicensesFromDeadNodes(ctx, t)
+	var leases []Lease
+
+	switch {
+	case predicate.pool == AnyPool:
+		rows, err := s.queries.GetLeases(ctx, GetLeasesParams{limit, offset})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			leases = append(leases, Lease{row.Node, row.License})
+		}
+	case predicate.pool != nil:
+		rows, err := s.queries.GetLeasesWithPool(ctx, GetLeasesWithPoolParams{&predicate.pool.ID, limit, offset})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			leases = append(leases, Lease{row.Node, row.License})
+		}
+	default:
+		rows, err := s.queries.GetLeasesWithoutPool(ctx, GetLeasesWithoutPoolParams{limit, offset})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			leases = append(leases, Lease{row.Node, row.License})
+		}
+	}
+
+	return leases, nil
+}
+
+func (s *Store) GetLeaseByFingerprint(ctx context.Context, fingerprint string, leaseID string, predicates ...LicensePredicateFunc) (*Lease, error) {
+	predicate := applyLicensePredicates(predicates...)
+
+	switch {
+	case predicate.pool == AnyPool:
+		row, err := s.queries.GetLeaseByFingerprint(ctx, GetLeaseByFingerprintParams{fingerprint, leaseID})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Lease{row.Node, row.License}, nil
+	case predicate.pool != nil:
+		row, err := s.queries.GetLeaseWithPoolByFingerprint(ctx, GetLeaseWithPoolByFingerprintParams{fingerprint, leaseID, &predicate.pool.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Lease{row.Node, row.License}, nil
+	default:
+		row, err := s.queries.GetLeaseWithoutPoolByFingerprint(ctx, GetLeaseWithoutPoolByFingerprintParams{fingerprint, leaseID})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Lease{row.Node, row.License}, nil
+	}
+}
+
+// LeaseTTL returns the node's time-to-live, falling back to the given default when the
+// node did not request its own
+func (n Node) LeaseTTL(fallback time.Duration) time.Duration {
+	if n.Ttl == nil {
+		return fallback
+	}
+
+	return time.Duration(*n.Ttl) * time.Second
+}
+
+// NodeMetadata describes the host and application holding a node's lease, as reported
+// by the node when claiming or extending its lease
+type NodeMetadata struct {
+	Hostname   string            `json:"hostname,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	Username   string            `json:"username,omitempty"`
+	AppName    string            `json:"app_name,omitempty"`
+	AppVersion string            `json:"app_version,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// NodeMetadata returns the metadata reported by the node, or nil if it reported none
+func (n Node) NodeMetadata() *NodeMetadata {
+	if n.Hostname == nil && n.Platform == nil && n.Username == nil && n.AppName == nil && n.AppVersion == nil && n.Labels == nil {
+		return nil
+	}
+
+	metadata := &NodeMetadata{
+		Hostname:   derefString(n.Hostname),
+		Platform:   derefString(n.Platform),
+		Username:   derefString(n.Username),
+		AppName:    derefString(n.AppName),
+		AppVersion: derefString(n.AppVersion),
+	}
+
+	if n.Labels != nil {
+		if err := json.Unmarshal([]byte(*n.Labels), &metadata.Labels); err != nil {
+			logger.Warn("failed to decode node labels", "nodeID", n.ID, "error", err)
+		}
+	}
+
+	return metadata
+}
+
+// Event represents an audit log along with the names of its event type, entity type and
+// pool, and the license or node it refers to if it still exists
+type Event struct {
+	ID              int64
+	EventType       string
+	EntityType      string
+	EntityID        int64
+	PoolID          *int64
+	PoolName        *string
+	LicenseGuid     *string
+	NodeFingerprint *string
+	Metadata        *string
+	CreatedAt       int64
+}
+
+// NodeMetadata returns the snapshot of the node's metadata taken at the time of the
+// event, or nil if there is none
+func (e Event) NodeMetadata() *NodeMetadata {
+	if e.Metadata == nil {
+		return nil
+	}
+
+	var metadata NodeMetadata
+	if err := json.Unmarshal([]byte(*e.Metadata), &metadata); err != nil {
+		logger.Warn("failed to decode event metadata", "eventID", e.ID, "error", err)
+
+		return nil
+	}
+
+	return &metadata
+}
+
+// PoolStats represents license utilization for a pool, where a nil pool is the global pool
+type PoolStats struct {
+	Pool   *Pool
+	Total  int64
+	Leased int64
+}
+
+// Free returns the number of licenses in the pool that are available to be leased
+func (p PoolStats) Free() int64 {
+	return p.Total - p.Leased
+}
+
+// GetPoolStats returns license utilization for the global pool and every named pool,
+// including pools without any licenses
+func (s *Store) GetPoolStats(ctx context.Context) ([]PoolStats, error) {
+	pools, err := s.queries.GetPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.GetLicenseStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	global := PoolStats{}
+	counts := make(map[int64]GetLicenseStatsRow, len(rows))
+
+	for _, row := range rows {
+		if row.PoolID == nil {
+			global.Total, global.Leased = row.Total, row.Leased
+
+			continue
+		}
+
+		counts[*row.PoolID] = row
+	}
+
+	stats := make([]PoolStats, 0, len(pools)+1)
+	stats = append(stats, global)
+
+	for _, pool := range pools {
+		row := counts[pool.ID]
+
+		stats = append(stats, PoolStats{Pool: &pool, Total: row.Total, Leased: row.Leased})
+	}
+
+	return stats, nil
+}
+
+func (s *Store) CountActiveNodes(ctx context.Context) (int64, error) {
+	return s.queries.CountActiveNodes(ctx)
+}
+
+// CountLeasesByFingerprint counts a node's active leases, including its sub-leases
+func (s *Store) CountLeasesByFingerprint(ctx context.Context, fingerprint string) (int64, error) {
+	return s.queries.CountLeasesByFingerprint(ctx, fingerprint)
+}
+
+// ReleaseLicensesFromDeadNodes releases licenses from nodes that have not sent a heartbeat
+// within their TTL, falling back to the given TTL for nodes without their own. Licenses
+// in pools with heartbeats disabled are never released, where the given heartbeat is
+// the default for pools without their own setting.
+func (s *Store) ReleaseLicensesFromDeadNodes(ctx context.Context, ttl time.Duration, heartbeat bool) ([]License, error) {
+	t := int64(ttl.Seconds())
+
+	licenses, err := s.queries.ReleaseLicensesFromDeadNodes(ctx, ReleaseLicensesFromDeadNodesParams{DefaultTtl: &t, DefaultHeartbeat: heartbeat})
 	if err != nil {
 		logger.Error("failed to release licenses from dead nodes", "error", err)
 
@@ -359,15 +777,110 @@ func (s *Store) ReleaseLicensesFromDeadNodes(ctx context.Context, ttl time.Durat
 	return licenses, nil
 }
 
-func (s *Store) DeactivateDeadNodes(ctx context.Context, ttl time.Duration) ([]Node, error) {
-	t := fmt.Sprintf("-%d seconds", int(ttl.Seconds()))
+// DeadNode represents a deactivated node along with the pool of the license it last held,
+// where a nil pool is the global pool
+type DeadNode struct {
+	Node   Node
+	PoolID *int64
+}
+
+// DeactivateDeadNodes deactivates nodes that have not sent a heartbeat within their TTL,
+// falling back to the given TTL for nodes without their own, unless they still hold a
+// lease, i.e. in a pool with heartbeats disabled
+func (s *Store) DeactivateDeadNodes(ctx context.Context, ttl time.Duration) ([]DeadNode, error) {
+	t := int64(ttl.Seconds())
 
-	nodes, err := s.queries.DeactivateDeadNodes(ctx, t)
+	rows, err := s.queries.DeactivateDeadNodes(ctx, &t)
 	if err != nil {
 		logger.Error("failed to deactivate dead nodes", "error", err)
 
 		return nil, err
 	}
 
+	nodes := make([]DeadNode, 0, len(rows))
+	for _, row := range rows {
+		nodes = append(nodes, DeadNode{
+			Node: Node{
+				ID:               row.ID,
+				Fingerprint:      row.Fingerprint,
+				LeaseID:          row.LeaseID,
+				LastHeartbeatAt:  row.LastHeartbeatAt,
+				CreatedAt:        row.CreatedAt,
+				DeactivatedAt:    row.DeactivatedAt,
+				Ttl:              row.Ttl,
+				LeaseTokenDigest: row.LeaseTokenDigest,
+				Hostname:         row.Hostname,
+				Platform:         row.Platform,
+				Username:         row.Username,
+				AppName:          row.AppName,
+				AppVersion:       row.AppVersion,
+				Labels:           row.Labels,
+			},
+			PoolID: row.PoolID,
+		})
+	}
+
 	return nodes, nil
 }
+
+// GetEventsAfterID returns up to limit events with an ID greater than the given ID, in
+// the order they occurred
+func (s *Store) GetEventsAfterID(ctx context.Context, id int64, limit int64, predicates ...LicensePredicateFunc) ([]Event, error) {
+	predicate := applyLicensePredicates(predicates...)
+
+	var events []Event
+
+	switch {
+	case predicate.pool == AnyPool:
+		rows, err := s.queries.GetEventsAfterID(ctx, GetEventsAfterIDParams{id, limit})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			events = append(events, Event(row))
+		}
+	case predicate.pool != nil:
+		rows, err := s.queries.GetEventsAfterIDWithPool(ctx, GetEventsAfterIDWithPoolParams{id, &predicate.pool.ID, limit})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			events = append(events, Event(row))
+		}
+	default:
+		rows, err := s.queries.GetEventsAfterIDWithoutPool(ctx, GetEventsAfterIDWithoutPoolParams{id, limit})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			events = append(events, Event(row))
+		}
+	}
+
+	return events, nil
+}
+
+// GetLastEventID returns the ID of the most recent event, or 0 if there are none
+func (s *Store) GetLastEventID(ctx context.Context) (int64, error) {
+	return s.queries.GetLastAuditLogID(ctx)
+}
+
+// nullString returns nil for an empty string, so that it's stored as NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -187,10 +188,10 @@ func TestStore_ReleaseLicenseByNodeID(t *testing.T) {
 	require.NoError(t, err)
 
 	// create separate nodes since each node can only have one license
-	pooledNode, err := store.ActivateNode(ctx, "pooled-test-fingerprint")
+	pooledNode, err := store.ActivateNode(ctx, "pooled-test-fingerprint", "")
 	require.NoError(t, err)
 
-	unpooledNode, err := store.ActivateNode(ctx, "unpooled-test-fingerprint")
+	unpooledNode, err := store.ActivateNode(ctx, "unpooled-test-fingerprint", "")
 	require.NoError(t, err)
 
 	// create licenses and claim them
@@ -248,7 +249,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 	testPool, err := store.CreatePool(ctx, "test-pool")
 	require.NoError(t, err)
 
-	node, err := store.ActivateNode(ctx, "test-fingerprint")
+	node, err := store.ActivateNode(ctx, "test-fingerprint", "")
 	require.NoError(t, err)
 
 	// create available licenses
@@ -279,7 +280,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 
 	t.Run("without pool predicate", func(t *testing.T) {
 		// create another node for this test
-		node2, err := store.ActivateNode(ctx, "test-fingerprint-2")
+		node2, err := store.ActivateNode(ctx, "test-fingerprint-2", "")
 		require.NoError(t, err)
 
 		license, err := store.ClaimLicenseByStrategy(ctx, "fifo", &node2.ID, WithoutPool())
@@ -292,7 +293,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 
 	t.Run("strategies", func(t *testing.T) {
 		// create more licenses and test different strategies
-		node3, err := store.ActivateNode(ctx, "test-fingerprint-3")
+		node3, err := store.ActivateNode(ctx, "test-fingerprint-3", "")
 		require.NoError(t, err)
 
 		for i := range 5 {
@@ -306,7 +307,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 		assert.NotNil(t, license)
 
 		// test LIFO
-		node4, err := store.ActivateNode(ctx, "test-fingerprint-4")
+		node4, err := store.ActivateNode(ctx, "test-fingerprint-4", "")
 		require.NoError(t, err)
 
 		license, err = store.ClaimLicenseByStrategy(ctx, "lifo", &node4.ID, WithPool(testPool))
@@ -314,7 +315,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 		assert.NotNil(t, license)
 
 		// test random
-		node5, err := store.ActivateNode(ctx, "test-fingerprint-5")
+		node5, err := store.ActivateNode(ctx, "test-fingerprint-5", "")
 		require.NoError(t, err)
 
 		license, err = store.ClaimLicenseByStrategy(ctx, "rand", &node5.ID, WithPool(testPool))
@@ -322,7 +323,7 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 		assert.NotNil(t, license)
 
 		// invalid
-		node6, err := store.ActivateNode(ctx, "test-fingerprint-6")
+		node6, err := store.ActivateNode(ctx, "test-fingerprint-6", "")
 		require.NoError(t, err)
 
 		license, err = store.ClaimLicenseByStrategy(ctx, "invalid-strategy", &node6.ID, WithPool(testPool))
@@ -331,6 +332,262 @@ func TestStore_ClaimLicenseByStrategy(t *testing.T) {
 	})
 }
 
+func TestStore_ClaimLicenseByStrategy_Reservations(t *testing.T) {
+	for _, strategy := range []string{"fifo", "lifo", "rand"} {
+		for _, name := range []string{"", "test-pool"} {
+			t.Run(fmt.Sprintf("%s pool=%q", strategy, name), func(t *testing.T) {
+				store, conn := newMemoryStore(t)
+				defer closeMemoryStore(conn)
+				ctx := context.Background()
+
+				var pool *Pool
+				if name != "" {
+					p, err := store.CreatePool(ctx, name)
+					require.NoError(t, err)
+
+					pool = p
+				}
+
+				for i := range 3 {
+					_, err := store.InsertLicense(ctx, pool, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+					require.NoError(t, err)
+				}
+
+				reserved, err := store.GetLicenseByGUID(ctx, "guid-1")
+				require.NoError(t, err)
+
+				fingerprint := "reserved-fingerprint"
+
+				reserved, err = store.SetLicenseReservedFingerprintByID(ctx, reserved.ID, &fingerprint)
+				require.NoError(t, err)
+				assert.Equal(t, "reserved-fingerprint", *reserved.ReservedFingerprint)
+
+				// other nodes never claim the reserved license, even while it's free
+				for i := range 2 {
+					node, err := store.ActivateNode(ctx, fmt.Sprintf("other-fingerprint-%d", i), "")
+					require.NoError(t, err)
+
+					license, err := store.ClaimLicenseByStrategy(ctx, strategy, &node.ID, WithPool(pool))
+					require.NoError(t, err)
+					assert.NotEqual(t, "guid-1", license.Guid)
+				}
+
+				node, err := store.ActivateNode(ctx, "other-fingerprint-2", "")
+				require.NoError(t, err)
+
+				_, err = store.ClaimLicenseByStrategy(ctx, strategy, &node.ID, WithPool(pool))
+				assert.ErrorIs(t, err, sql.ErrNoRows)
+
+				// the reserved node claims its reserved license
+				node, err = store.ActivateNode(ctx, "reserved-fingerprint", "")
+				require.NoError(t, err)
+
+				license, err := store.ClaimLicenseByStrategy(ctx, strategy, &node.ID, WithPool(pool))
+				require.NoError(t, err)
+				assert.Equal(t, "guid-1", license.Guid)
+				assert.Equal(t, &node.ID, license.NodeID)
+			})
+		}
+	}
+
+	t.Run("reserved before unreserved", func(t *testing.T) {
+		store, conn := newMemoryStore(t)
+		defer closeMemoryStore(conn)
+		ctx := context.Background()
+
+		for i := range 3 {
+			_, err := store.InsertLicense(ctx, nil, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+			require.NoError(t, err)
+		}
+
+		reserved, err := store.GetLicenseByGUID(ctx, "guid-2")
+		require.NoError(t, err)
+
+		fingerprint := "reserved-fingerprint"
+
+		_, err = store.SetLicenseReservedFingerprintByID(ctx, reserved.ID, &fingerprint)
+		require.NoError(t, err)
+
+		node, err := store.ActivateNode(ctx, "reserved-fingerprint", "")
+		require.NoError(t, err)
+
+		// fifo would otherwise pick the oldest license
+		license, err := store.ClaimLicenseByStrategy(ctx, "fifo", &node.ID, WithoutPool())
+		require.NoError(t, err)
+		assert.Equal(t, "guid-2", license.Guid)
+
+		license, err = store.SetLicenseReservedFingerprintByID(ctx, reserved.ID, nil)
+		require.NoError(t, err)
+		assert.Nil(t, license.ReservedFingerprint)
+	})
+}
+
+func TestStore_ClaimLicenseByAffinity(t *testing.T) {
+	for _, name := range []string{"", "test-pool"} {
+		t.Run(fmt.Sprintf("pool=%q", name), func(t *testing.T) {
+			store, conn := newMemoryStore(t)
+			defer closeMemoryStore(conn)
+			ctx := context.Background()
+
+			var pool *Pool
+			if name != "" {
+				p, err := store.CreatePool(ctx, name)
+				require.NoError(t, err)
+
+				pool = p
+			}
+
+			for i := range 5 {
+				_, err := store.InsertLicense(ctx, pool, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+				require.NoError(t, err)
+			}
+
+			// restart releases the node's lease and claims a new one, like a node restarting
+			restart := func(t *testing.T, fingerprint string, leaseID string) *License {
+				t.Helper()
+
+				node, err := store.ActivateNode(ctx, fingerprint, leaseID)
+				require.NoError(t, err)
+
+				require.NoError(t, store.ReleaseLicenseByNodeID(ctx, &node.ID, WithPool(pool)))
+				require.NoError(t, store.DeactivateNodeByFingerprint(ctx, fingerprint, leaseID))
+
+				node, err = store.ActivateNode(ctx, fingerprint, leaseID)
+				require.NoError(t, err)
+
+				license, err := store.ClaimLicenseByAffinity(ctx, "rand", &node.ID, WithPool(pool))
+				require.NoError(t, err)
+
+				return license
+			}
+
+			// without a previously held license, the fallback strategy is used
+			node, err := store.ActivateNode(ctx, "node-a", "")
+			require.NoError(t, err)
+
+			first, err := store.ClaimLicenseByAffinity(ctx, "rand", &node.ID, WithPool(pool))
+			require.NoError(t, err)
+			assert.Equal(t, &node.ID, first.LastNodeID)
+
+			t.Run("restarts", func(t *testing.T) {
+				for range 10 {
+					license := restart(t, "node-a", "")
+					assert.Equal(t, first.Guid, license.Guid)
+				}
+			})
+
+			t.Run("restart after cull", func(t *testing.T) {
+				_, err := conn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-120 seconds') WHERE fingerprint = 'node-a'`)
+				require.NoError(t, err)
+
+				released, err := store.ReleaseLicensesFromDeadNodes(ctx, time.Minute, true)
+				require.NoError(t, err)
+				require.Len(t, released, 1)
+
+				_, err = store.DeactivateDeadNodes(ctx, time.Minute)
+				require.NoError(t, err)
+
+				node, err := store.ActivateNode(ctx, "node-a", "")
+				require.NoError(t, err)
+
+				license, err := store.ClaimLicenseByAffinity(ctx, "rand", &node.ID, WithPool(pool))
+				require.NoError(t, err)
+				assert.Equal(t, first.Guid, license.Guid)
+			})
+
+			t.Run("sub-leases have their own affinity", func(t *testing.T) {
+				node, err := store.ActivateNode(ctx, "node-a", "job-1")
+				require.NoError(t, err)
+
+				sub, err := store.ClaimLicenseByAffinity(ctx, "rand", &node.ID, WithPool(pool))
+				require.NoError(t, err)
+				assert.NotEqual(t, first.Guid, sub.Guid)
+
+				for range 5 {
+					assert.Equal(t, sub.Guid, restart(t, "node-a", "job-1").Guid)
+					assert.Equal(t, first.Guid, restart(t, "node-a", "").Guid)
+				}
+			})
+
+			t.Run("fallback when the previous license is taken", func(t *testing.T) {
+				node, err := store.ActivateNode(ctx, "node-a", "")
+				require.NoError(t, err)
+
+				require.NoError(t, store.ReleaseLicenseByNodeID(ctx, &node.ID, WithPool(pool)))
+				require.NoError(t, store.DeactivateNodeByFingerprint(ctx, "node-a", ""))
+
+				// another node claims the license while node-a is restarting
+				other, err := store.ActivateNode(ctx, "node-b", "")
+				require.NoError(t, err)
+
+				_, err = conn.ExecContext(ctx, `UPDATE licenses SET node_id = ?, last_node_id = ? WHERE guid = ?`, other.ID, other.ID, first.Guid)
+				require.NoError(t, err)
+
+				node, err = store.ActivateNode(ctx, "node-a", "")
+				require.NoError(t, err)
+
+				second, err := store.ClaimLicenseByAffinity(ctx, "rand", &node.ID, WithPool(pool))
+				require.NoError(t, err)
+				assert.NotEqual(t, first.Guid, second.Guid)
+
+				// the node now has an affinity for its new license
+				assert.Equal(t, second.Guid, restart(t, "node-a", "").Guid)
+			})
+
+			t.Run("invalid fallback", func(t *testing.T) {
+				node, err := store.ActivateNode(ctx, "node-c", "")
+				require.NoError(t, err)
+
+				_, err = store.ClaimLicenseByAffinity(ctx, "affinity", &node.ID, WithPool(pool))
+				assert.ErrorIs(t, err, ErrBadStrategy)
+
+				_, err = store.ClaimLicenseByAffinity(ctx, "invalid", &node.ID, WithPool(pool))
+				assert.ErrorIs(t, err, ErrBadStrategy)
+
+				_, err = store.ClaimLicenseByAffinity(ctx, "fifo", &node.ID, WithAnyPool())
+				assert.ErrorIs(t, err, ErrAnyPoolNotSupported)
+			})
+		})
+	}
+
+	t.Run("reservations before affinity", func(t *testing.T) {
+		store, conn := newMemoryStore(t)
+		defer closeMemoryStore(conn)
+		ctx := context.Background()
+
+		for i := range 2 {
+			_, err := store.InsertLicense(ctx, nil, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+			require.NoError(t, err)
+		}
+
+		node, err := store.ActivateNode(ctx, "node-a", "")
+		require.NoError(t, err)
+
+		first, err := store.ClaimLicenseByAffinity(ctx, "fifo", &node.ID, WithoutPool())
+		require.NoError(t, err)
+
+		require.NoError(t, store.ReleaseLicenseByNodeID(ctx, &node.ID, WithoutPool()))
+
+		guid := "guid-0"
+		if first.Guid == guid {
+			guid = "guid-1"
+		}
+
+		// reserve the license the node didn't previously hold
+		other, err := store.GetLicenseByGUID(ctx, guid)
+		require.NoError(t, err)
+
+		fingerprint := "node-a"
+
+		_, err = store.SetLicenseReservedFingerprintByID(ctx, other.ID, &fingerprint)
+		require.NoError(t, err)
+
+		license, err := store.ClaimLicenseByAffinity(ctx, "fifo", &node.ID, WithoutPool())
+		require.NoError(t, err)
+		assert.Equal(t, other.Guid, license.Guid)
+	})
+}
+
 func TestStore_GetLicenseByNodeID(t *testing.T) {
 	store, conn := newMemoryStore(t)
 	defer closeMemoryStore(conn)
@@ -340,10 +597,10 @@ func TestStore_GetLicenseByNodeID(t *testing.T) {
 	require.NoError(t, err)
 
 	// create separate nodes since each node can only have one license
-	pooledNode, err := store.ActivateNode(ctx, "pooled-node-fingerprint")
+	pooledNode, err := store.ActivateNode(ctx, "pooled-node-fingerprint", "")
 	require.NoError(t, err)
 
-	unpooledNode, err := store.ActivateNode(ctx, "unpooled-node-fingerprint")
+	unpooledNode, err := store.ActivateNode(ctx, "unpooled-node-fingerprint", "")
 	require.NoError(t, err)
 
 	// create and claim licenses
@@ -397,6 +654,209 @@ func TestStore_GetLicenseByNodeID(t *testing.T) {
 	})
 }
 
+func TestStore_GetLeases(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	testPool, err := store.CreatePool(ctx, "test-pool")
+	require.NoError(t, err)
+
+	pooledNode, err := store.ActivateNode(ctx, "pooled-node-fingerprint", "")
+	require.NoError(t, err)
+
+	unpooledNode, err := store.ActivateNode(ctx, "unpooled-node-fingerprint", "")
+	require.NoError(t, err)
+
+	idleNode, err := store.ActivateNode(ctx, "idle-node-fingerprint", "")
+	require.NoError(t, err)
+
+	pooledLicense, err := store.InsertLicense(ctx, testPool, "pooled-guid", []byte("pooled-file"), "pooled-key")
+	require.NoError(t, err)
+
+	unpooledLicense, err := store.InsertLicense(ctx, nil, "unpooled-guid", []byte("unpooled-file"), "unpooled-key")
+	require.NoError(t, err)
+
+	_, err = store.ClaimLicenseByStrategy(ctx, "fifo", &pooledNode.ID, WithPool(testPool))
+	require.NoError(t, err)
+
+	_, err = store.ClaimLicenseByStrategy(ctx, "fifo", &unpooledNode.ID, WithoutPool())
+	require.NoError(t, err)
+
+	t.Run("without any predicates", func(t *testing.T) {
+		leases, err := store.GetLeases(ctx, 10, 0)
+		require.NoError(t, err)
+		assert.Len(t, leases, 2)
+	})
+
+	t.Run("with named pool predicate", func(t *testing.T) {
+		leases, err := store.GetLeases(ctx, 10, 0, WithPool(testPool))
+		require.NoError(t, err)
+		assert.Len(t, leases, 1)
+		assert.Equal(t, pooledNode.ID, leases[0].Node.ID)
+		assert.Equal(t, pooledLicense.ID, leases[0].License.ID)
+	})
+
+	t.Run("without pool predicate", func(t *testing.T) {
+		leases, err := store.GetLeases(ctx, 10, 0, WithoutPool())
+		require.NoError(t, err)
+		assert.Len(t, leases, 1)
+		assert.Equal(t, unpooledNode.ID, leases[0].Node.ID)
+		assert.Equal(t, unpooledLicense.ID, leases[0].License.ID)
+	})
+
+	t.Run("with limit and offset", func(t *testing.T) {
+		leases, err := store.GetLeases(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Len(t, leases, 1)
+		assert.Equal(t, unpooledNode.ID, leases[0].Node.ID)
+	})
+
+	t.Run("by fingerprint", func(t *testing.T) {
+		lease, err := store.GetLeaseByFingerprint(ctx, pooledNode.Fingerprint, "")
+		require.NoError(t, err)
+		assert.Equal(t, pooledLicense.ID, lease.License.ID)
+
+		lease, err = store.GetLeaseByFingerprint(ctx, pooledNode.Fingerprint, "", WithPool(testPool))
+		require.NoError(t, err)
+		assert.Equal(t, pooledLicense.ID, lease.License.ID)
+
+		_, err = store.GetLeaseByFingerprint(ctx, pooledNode.Fingerprint, "", WithoutPool())
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("node without a lease", func(t *testing.T) {
+		_, err := store.GetLeaseByFingerprint(ctx, idleNode.Fingerprint, "")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestStore_GetPoolStats(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	testPool, err := store.CreatePool(ctx, "test-pool")
+	require.NoError(t, err)
+
+	emptyPool, err := store.CreatePool(ctx, "empty-pool")
+	require.NoError(t, err)
+
+	for i := range 3 {
+		_, err := store.InsertLicense(ctx, testPool, fmt.Sprintf("pooled-guid-%d", i), []byte(fmt.Sprintf("pooled-file-%d", i)), fmt.Sprintf("pooled-key-%d", i))
+		require.NoError(t, err)
+	}
+
+	_, err = store.InsertLicense(ctx, nil, "unpooled-guid", []byte("unpooled-file"), "unpooled-key")
+	require.NoError(t, err)
+
+	node, err := store.ActivateNode(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+
+	_, err = store.ClaimLicenseByStrategy(ctx, "fifo", &node.ID, WithPool(testPool))
+	require.NoError(t, err)
+
+	_, err = store.ActivateNode(ctx, "idle-node-fingerprint", "")
+	require.NoError(t, err)
+
+	stats, err := store.GetPoolStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+
+	// global pool
+	assert.Nil(t, stats[0].Pool)
+	assert.Equal(t, int64(1), stats[0].Total)
+	assert.Equal(t, int64(0), stats[0].Leased)
+	assert.Equal(t, int64(1), stats[0].Free())
+
+	assert.Equal(t, testPool.ID, stats[1].Pool.ID)
+	assert.Equal(t, int64(3), stats[1].Total)
+	assert.Equal(t, int64(1), stats[1].Leased)
+	assert.Equal(t, int64(2), stats[1].Free())
+
+	assert.Equal(t, emptyPool.ID, stats[2].Pool.ID)
+	assert.Equal(t, int64(0), stats[2].Total)
+	assert.Equal(t, int64(0), stats[2].Free())
+
+	count, err := store.CountActiveNodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	err = store.DeactivateNodeByFingerprint(ctx, "idle-node-fingerprint", "")
+	require.NoError(t, err)
+
+	count, err = store.CountActiveNodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestStore_GetEventsAfterID(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	lastID, err := store.GetLastEventID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), lastID)
+
+	pool, err := store.CreatePool(ctx, "prod")
+	require.NoError(t, err)
+
+	license, err := store.InsertLicense(ctx, pool, "pooled-guid", []byte("pooled-file"), "pooled-key")
+	require.NoError(t, err)
+
+	node, err := store.ActivateNode(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+
+	require.NoError(t, store.InsertAuditLog(ctx, pool, EventTypePoolAdded, EntityTypePool, pool.ID))
+	require.NoError(t, store.InsertAuditLog(ctx, pool, EventTypeLicenseLeased, EntityTypeLicense, license.ID))
+	require.NoError(t, store.InsertAuditLog(ctx, nil, EventTypeNodeCulled, EntityTypeNode, node.ID))
+
+	events, err := store.GetEventsAfterID(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "pool.added", events[0].EventType)
+	assert.Equal(t, "pool", events[0].EntityType)
+	assert.Equal(t, "prod", *events[0].PoolName)
+	assert.Nil(t, events[0].LicenseGuid)
+	assert.Nil(t, events[0].NodeFingerprint)
+
+	assert.Equal(t, "license.leased", events[1].EventType)
+	assert.Equal(t, "pooled-guid", *events[1].LicenseGuid)
+	assert.Nil(t, events[1].NodeFingerprint)
+
+	assert.Equal(t, "node.culled", events[2].EventType)
+	assert.Equal(t, "node-fingerprint", *events[2].NodeFingerprint)
+	assert.Nil(t, events[2].LicenseGuid)
+	assert.Nil(t, events[2].PoolName)
+
+	// events are ordered by id and resumable
+	assert.Less(t, events[0].ID, events[1].ID)
+
+	resumed, err := store.GetEventsAfterID(ctx, events[0].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, resumed, 2)
+	assert.Equal(t, events[1].ID, resumed[0].ID)
+
+	limited, err := store.GetEventsAfterID(ctx, 0, 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	pooled, err := store.GetEventsAfterID(ctx, 0, 10, WithPool(pool))
+	require.NoError(t, err)
+	assert.Len(t, pooled, 2)
+
+	unpooled, err := store.GetEventsAfterID(ctx, 0, 10, WithoutPool())
+	require.NoError(t, err)
+	require.Len(t, unpooled, 1)
+	assert.Equal(t, "node.culled", unpooled[0].EventType)
+
+	lastID, err = store.GetLastEventID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, events[2].ID, lastID)
+}
+
 func TestStore_AdditionalMethods(t *testing.T) {
 	store, conn := newMemoryStore(t)
 	defer closeMemoryStore(conn)
@@ -437,25 +897,25 @@ func TestStore_AdditionalMethods(t *testing.T) {
 
 	t.Run("Nodes", func(t *testing.T) {
 		// activate node
-		node, err := store.ActivateNode(ctx, "node-test-fingerprint")
+		node, err := store.ActivateNode(ctx, "node-test-fingerprint", "")
 		require.NoError(t, err)
 		assert.Equal(t, "node-test-fingerprint", node.Fingerprint)
 
 		// get node
-		retrievedNode, err := store.GetNodeByFingerprint(ctx, "node-test-fingerprint")
+		retrievedNode, err := store.GetNodeByFingerprint(ctx, "node-test-fingerprint", "")
 		require.NoError(t, err)
 		assert.Equal(t, node.ID, retrievedNode.ID)
 
 		// ping heartbeat
-		err = store.PingNodeHeartbeatByFingerprint(ctx, "node-test-fingerprint")
+		err = store.PingNodeHeartbeatByFingerprint(ctx, "node-test-fingerprint", "")
 		require.NoError(t, err)
 
 		// deactivate node
-		err = store.DeactivateNodeByFingerprint(ctx, "node-test-fingerprint")
+		err = store.DeactivateNodeByFingerprint(ctx, "node-test-fingerprint", "")
 		require.NoError(t, err)
 
 		// should not be able to get it now
-		_, err = store.GetNodeByFingerprint(ctx, "node-test-fingerprint")
+		_, err = store.GetNodeByFingerprint(ctx, "node-test-fingerprint", "")
 		assert.Error(t, err)
 	})
 
@@ -490,3 +950,181 @@ func TestStore_AdditionalMethods(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestStore_SubLeases(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	for i := range 3 {
+		_, err := store.InsertLicense(ctx, nil, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+		require.NoError(t, err)
+	}
+
+	node, err := store.ActivateNode(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+
+	job1, err := store.ActivateNode(ctx, "node-fingerprint", "job-1")
+	require.NoError(t, err)
+
+	job2, err := store.ActivateNode(ctx, "node-fingerprint", "job-2")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, node.ID, job1.ID)
+	assert.NotEqual(t, job1.ID, job2.ID)
+	assert.Equal(t, "job-1", job1.LeaseID)
+
+	for _, n := range []*Node{node, job1, job2} {
+		_, err := store.ClaimLicenseByStrategy(ctx, "fifo", &n.ID, WithoutPool())
+		require.NoError(t, err)
+
+		require.NoError(t, store.PingNodeHeartbeatByFingerprint(ctx, n.Fingerprint, n.LeaseID))
+	}
+
+	t.Run("lookup", func(t *testing.T) {
+		lease, err := store.GetLeaseByFingerprint(ctx, "node-fingerprint", "job-2")
+		require.NoError(t, err)
+		assert.Equal(t, job2.ID, lease.Node.ID)
+		assert.Equal(t, "guid-2", lease.License.Guid)
+
+		_, err = store.GetLeaseByFingerprint(ctx, "node-fingerprint", "job-3")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		count, err := store.CountLeasesByFingerprint(ctx, "node-fingerprint")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// sub-leases don't count as additional nodes
+		count, err = store.CountActiveNodes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("cull", func(t *testing.T) {
+		_, err := conn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-120 seconds') WHERE id = ?`, job1.ID)
+		require.NoError(t, err)
+
+		released, err := store.ReleaseLicensesFromDeadNodes(ctx, time.Minute, true)
+		require.NoError(t, err)
+		require.Len(t, released, 1)
+		assert.Equal(t, "guid-1", released[0].Guid)
+
+		culled, err := store.DeactivateDeadNodes(ctx, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, culled, 1)
+		assert.Equal(t, job1.ID, culled[0].Node.ID)
+		assert.Nil(t, culled[0].PoolID)
+
+		count, err := store.CountLeasesByFingerprint(ctx, "node-fingerprint")
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+}
+
+func TestStore_PoolHeartbeats(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	enabled, err := store.CreatePool(ctx, "enabled")
+	require.NoError(t, err)
+
+	disabled, err := store.CreatePool(ctx, "disabled")
+	require.NoError(t, err)
+
+	ttl := time.Hour
+
+	strategy, on, off := "lifo", true, false
+
+	enabled, err = store.UpdatePoolSettingsByID(ctx, enabled.ID, nil, nil, &on)
+	require.NoError(t, err)
+	assert.Nil(t, enabled.Strategy)
+	assert.Nil(t, enabled.Ttl)
+	assert.True(t, *enabled.Heartbeat)
+
+	disabled, err = store.UpdatePoolSettingsByID(ctx, disabled.ID, &strategy, &ttl, &off)
+	require.NoError(t, err)
+	assert.Equal(t, "lifo", *disabled.Strategy)
+	assert.Equal(t, int64(3600), *disabled.Ttl)
+	assert.False(t, *disabled.Heartbeat)
+
+	nodes := map[string]*Node{}
+
+	for i, pool := range []*Pool{nil, enabled, disabled} {
+		license, err := store.InsertLicense(ctx, pool, fmt.Sprintf("guid-%d", i), []byte(fmt.Sprintf("file-%d", i)), fmt.Sprintf("key-%d", i))
+		require.NoError(t, err)
+
+		node, err := store.ActivateNode(ctx, fmt.Sprintf("node-%d", i), "")
+		require.NoError(t, err)
+
+		_, err = store.ClaimLicenseByStrategy(ctx, "fifo", &node.ID, WithPool(pool))
+		require.NoError(t, err)
+
+		nodes[license.Guid] = node
+	}
+
+	_, err = conn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-120 seconds')`)
+	require.NoError(t, err)
+
+	// pools without a heartbeat setting fall back to the default, which is disabled here
+	released, err := store.ReleaseLicensesFromDeadNodes(ctx, time.Minute, false)
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	assert.Equal(t, "guid-1", released[0].Guid)
+
+	// nodes still holding a lease are never deactivated
+	culled, err := store.DeactivateDeadNodes(ctx, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, culled, 1)
+	assert.Equal(t, nodes["guid-1"].ID, culled[0].Node.ID)
+	require.NotNil(t, culled[0].PoolID)
+	assert.Equal(t, enabled.ID, *culled[0].PoolID)
+
+	released, err = store.ReleaseLicensesFromDeadNodes(ctx, time.Minute, true)
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	assert.Equal(t, "guid-0", released[0].Guid)
+}
+
+func TestStore_NodeMetadata(t *testing.T) {
+	store, conn := newMemoryStore(t)
+	defer closeMemoryStore(conn)
+	ctx := context.Background()
+
+	node, err := store.ActivateNode(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+	assert.Nil(t, node.NodeMetadata())
+
+	metadata := &NodeMetadata{Hostname: "build-01", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}
+	require.NoError(t, store.SetNodeMetadataByFingerprint(ctx, "node-fingerprint", "", metadata))
+
+	node, err = store.GetNodeByFingerprint(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+	assert.Equal(t, "build-01", *node.Hostname)
+	assert.Nil(t, node.Platform)
+	assert.Equal(t, metadata, node.NodeMetadata())
+
+	node, err = store.GetNodeByID(ctx, node.ID)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, node.NodeMetadata())
+
+	require.NoError(t, store.BulkInsertAuditLogs(ctx, []BulkInsertAuditLogParams{
+		{EventTypeID: EventTypeNodeHeartbeatPing, EntityTypeID: EntityTypeNode, EntityID: node.ID, Metadata: node.NodeMetadata()},
+		{EventTypeID: EventTypeNodeCulled, EntityTypeID: EntityTypeNode, EntityID: node.ID},
+	}))
+
+	// clearing the node's metadata doesn't change the audit trail's snapshot
+	require.NoError(t, store.SetNodeMetadataByFingerprint(ctx, "node-fingerprint", "", nil))
+
+	node, err = store.GetNodeByFingerprint(ctx, "node-fingerprint", "")
+	require.NoError(t, err)
+	assert.Nil(t, node.NodeMetadata())
+
+	events, err := store.GetEventsAfterID(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, metadata, events[0].NodeMetadata())
+	assert.Nil(t, events[1].NodeMetadata())
+}
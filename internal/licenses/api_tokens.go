@@ -0,0 +1,131 @@
+package licenses
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+var (
+	ErrAPITokenInvalid      = errors.New("api token is invalid")
+	ErrAPITokenPoolMismatch = errors.New("api token is not valid for pool")
+	ErrAPITokenNotFound     = errors.New("api token not found")
+)
+
+const (
+	// apiTokenPrefix makes api tokens recognizable e.g. by secret scanners
+	apiTokenPrefix = "relay_"
+
+	// apiTokenLength is the number of random bytes in an api token
+	apiTokenLength = 32
+)
+
+// newAPIToken generates a pool-scoped api token, returning the token along with its
+// digest, which is the only form the token is ever stored in
+func newAPIToken() (string, string, error) {
+	b := make([]byte, apiTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+
+	token := apiTokenPrefix + hex.EncodeToString(b)
+
+	return token, tokenDigest(token), nil
+}
+
+// CreateAPIToken creates an api token scoped to a pool, or to licenses without a pool
+// when the pool is nil. The raw token is only ever returned here.
+func (m *manager) CreateAPIToken(ctx context.Context, poolName *string) (string, *db.ApiToken, error) {
+	logger.Debug("starting to create api token", "pool", poolName)
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, digest, err := newAPIToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiToken, err := m.store.InsertAPIToken(ctx, pool, digest)
+	if err != nil {
+		logger.Error("failed to insert api token", "pool", poolName, "error", err)
+
+		return "", nil, fmt.Errorf("failed to insert api token: %w", err)
+	}
+
+	logger.Debug("created api token successfully", "id", apiToken.ID, "pool", poolName)
+
+	return token, apiToken, nil
+}
+
+func (m *manager) ListAPITokens(ctx context.Context) ([]db.ApiToken, error) {
+	tokens, err := m.store.GetAPITokens(ctx)
+	if err != nil {
+		logger.Error("failed to get api tokens", "error", err)
+
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (m *manager) DeleteAPIToken(ctx context.Context, id int64) error {
+	logger.Debug("starting to delete api token", "id", id)
+
+	if _, err := m.store.DeleteAPITokenByID(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("api token %d: %w", id, ErrAPITokenNotFound)
+		}
+
+		logger.Error("failed to delete api token", "id", id, "error", err)
+
+		return fmt.Errorf("failed to delete api token: %w", err)
+	}
+
+	logger.Debug("deleted api token successfully", "id", id)
+
+	return nil
+}
+
+// AuthenticateAPIToken ensures the given token exists and is scoped to the pool, where a
+// nil pool only matches tokens without a pool, recording the token's usage on success
+func (m *manager) AuthenticateAPIToken(ctx context.Context, poolName *string, token string) (*db.ApiToken, error) {
+	apiToken, err := m.store.GetAPITokenByDigest(ctx, tokenDigest(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPITokenInvalid
+		}
+
+		return nil, fmt.Errorf("failed to fetch api token: %w", err)
+	}
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		if errors.Is(err, ErrBadPool) {
+			return nil, fmt.Errorf("api token %d: %w", apiToken.ID, ErrAPITokenPoolMismatch)
+		}
+
+		return nil, err
+	}
+
+	switch {
+	case pool == nil && apiToken.PoolID != nil,
+		pool != nil && (apiToken.PoolID == nil || *apiToken.PoolID != pool.ID):
+		return nil, fmt.Errorf("api token %d: %w", apiToken.ID, ErrAPITokenPoolMismatch)
+	}
+
+	apiToken, err = m.store.TouchAPITokenByID(ctx, apiToken.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to touch api token: %w", err)
+	}
+
+	return apiToken, nil
+}
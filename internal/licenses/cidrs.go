@@ -0,0 +1,181 @@
+package licenses
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+// CIDRAction is whether a pool's CIDR range allows or denies access to the pool
+type CIDRAction string
+
+const (
+	CIDRActionAllow CIDRAction = "allow"
+	CIDRActionDeny  CIDRAction = "deny"
+)
+
+var (
+	ErrAccessDenied = errors.New("access denied")
+	ErrBadCIDR      = errors.New("invalid cidr range")
+	ErrCIDRExists   = errors.New("cidr range already exists")
+	ErrCIDRNotFound = errors.New("cidr range not found")
+)
+
+// ParseCIDR parses a CIDR range, or a single IP address as a range containing only that
+// address, returning the range in its canonical form e.g. 10.20.0.0/16
+func ParseCIDR(cidr string) (*net.IPNet, error) {
+	if ip := net.ParseIP(cidr); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("cidr %s: %w", cidr, ErrBadCIDR)
+	}
+
+	return ipnet, nil
+}
+
+func (m *manager) AddPoolCIDR(ctx context.Context, poolName string, cidr string, action CIDRAction) (*db.PoolCidr, error) {
+	logger.Debug("starting to add pool cidr", "poolName", poolName, "cidr", cidr, "action", action)
+
+	ipnet, err := ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := m.resolvePool(ctx, &poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	poolCIDR, err := m.store.InsertPoolCIDR(ctx, pool, ipnet.String(), string(action))
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, fmt.Errorf("cidr %s: %w", ipnet, ErrCIDRExists)
+		}
+
+		logger.Error("failed to insert pool cidr", "poolName", poolName, "cidr", ipnet, "error", err)
+
+		return nil, fmt.Errorf("failed to insert pool cidr: %w", err)
+	}
+
+	logger.Debug("added pool cidr successfully", "poolName", poolName, "cidr", ipnet, "action", action)
+
+	return poolCIDR, nil
+}
+
+func (m *manager) RemovePoolCIDR(ctx context.Context, poolName string, cidr string, action CIDRAction) error {
+	logger.Debug("starting to remove pool cidr", "poolName", poolName, "cidr", cidr, "action", action)
+
+	ipnet, err := ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	pool, err := m.resolvePool(ctx, &poolName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.store.DeletePoolCIDR(ctx, pool, ipnet.String(), string(action)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("cidr %s: %w", ipnet, ErrCIDRNotFound)
+		}
+
+		logger.Error("failed to delete pool cidr", "poolName", poolName, "cidr", ipnet, "error", err)
+
+		return fmt.Errorf("failed to delete pool cidr: %w", err)
+	}
+
+	logger.Debug("removed pool cidr successfully", "poolName", poolName, "cidr", ipnet, "action", action)
+
+	return nil
+}
+
+func (m *manager) ListPoolCIDRs(ctx context.Context) ([]db.PoolCidr, error) {
+	cidrs, err := m.store.GetPoolCIDRs(ctx)
+	if err != nil {
+		logger.Error("failed to get pool cidrs", "error", err)
+
+		return nil, err
+	}
+
+	return cidrs, nil
+}
+
+// CheckPoolAccess ensures an IP address may claim from a pool. Denied ranges take
+// precedence over allowed ranges, and when a pool has allowed ranges, the address must
+// be within one of them. Pools without ranges, as well as the global pool, allow all.
+func (m *manager) CheckPoolAccess(ctx context.Context, poolName *string, ip net.IP) error {
+	if poolName == nil {
+		return nil
+	}
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		// unknown pools are handled by the claim itself
+		if errors.Is(err, ErrBadPool) {
+			return nil
+		}
+
+		return err
+	}
+
+	cidrs, err := m.store.GetPoolCIDRsByPool(ctx, pool)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pool cidrs: %w", err)
+	}
+
+	if len(cidrs) == 0 {
+		return nil
+	}
+
+	var (
+		allowed    bool
+		restricted bool
+
+		// a client without an ip address can't be matched against the pool's cidrs, so
+		// it's denied rather than slipping past any denied ranges
+		denied = ip == nil
+	)
+
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr.Cidr)
+		if err != nil {
+			logger.Warn("skipping invalid pool cidr", "poolName", pool.Name, "cidr", cidr.Cidr, "error", err)
+
+			continue
+		}
+
+		switch CIDRAction(cidr.Action) {
+		case CIDRActionAllow:
+			restricted = true
+			allowed = allowed || ipnet.Contains(ip)
+		case CIDRActionDeny:
+			denied = denied || ipnet.Contains(ip)
+		}
+	}
+
+	if denied || (restricted && !allowed) {
+		if m.config.EnabledAudit {
+			if err := m.store.InsertAuditLog(ctx, pool, db.EventTypePoolAccessDenied, db.EntityTypePool, pool.ID); err != nil {
+				logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
+			}
+		}
+
+		return fmt.Errorf("ip %s: %w", ip, ErrAccessDenied)
+	}
+
+	return nil
+}
@@ -9,8 +9,16 @@ type Config struct {
 	TTL               time.Duration
 	EnabledAudit      bool
 	ExtendOnHeartbeat bool
+
+	// AffinityFallback is the strategy used by the affinity strategy when a node has no
+	// previously held license to claim, where empty falls back to fifo
+	AffinityFallback string
+
+	// MaxLeasesPerNode is the maximum number of leases, including sub-leases, a node may
+	// hold at once, where 0 is unlimited
+	MaxLeasesPerNode int
 }
 
 func NewConfig() *Config {
-	return &Config{Strategy: "fifo"}
+	return &Config{Strategy: "fifo", AffinityFallback: "fifo", ExtendOnHeartbeat: true}
 }
@@ -5,10 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
-	"github.com/keygen-sh/keygen-go/v3"
 	"github.com/keygen-sh/keygen-relay/internal/db"
 	"github.com/keygen-sh/keygen-relay/internal/logger"
 	"github.com/mattn/go-sqlite3"
@@ -29,27 +29,57 @@ var (
 	ErrNoLicenses      = errors.New("license pool is empty")
 	ErrLicenseNotFound = errors.New("license not found")
 	ErrBadPool         = errors.New("pool not found")
+	ErrLeaseNotFound   = errors.New("lease not found")
+	ErrPoolExists      = errors.New("pool already exists")
+	ErrPoolNotEmpty    = errors.New("pool is not empty")
+	ErrTooManyLeases   = errors.New("node has reached its maximum number of leases")
 )
 
 type LicenseOperationResult struct {
 	License *db.License
+	Node    *db.Node
 	Status  OperationStatus
+
+	// LeaseToken is the plaintext lease token, only available when a lease is created
+	LeaseToken string
 }
 
 type FileReaderFunc func(filename string) ([]byte, error)
 
 type Manager interface {
-	AddLicense(ctx context.Context, pool *string, licenseFilePath string, licenseKey string, publicKeyPath string) (*db.License, error)
+	AddLicense(ctx context.Context, pool *string, licenseFilePath string, licenseKey string) (*db.License, error)
 	RemoveLicense(ctx context.Context, pool *string, id string) error
 	ListLicenses(ctx context.Context, pool *string) ([]db.License, error)
 	GetLicenseByGUID(ctx context.Context, pool *string, id string) (*db.License, error)
 	AttachStore(store db.Store)
-	ClaimLicense(ctx context.Context, pool *string, fingerprint string) (*LicenseOperationResult, error)
-	ReleaseLicense(ctx context.Context, pool *string, fingerprint string) (*LicenseOperationResult, error)
+	ClaimLicense(ctx context.Context, pool *string, fingerprint string, opts ...LeaseOptionFunc) (*LicenseOperationResult, error)
+	ReleaseLicense(ctx context.Context, pool *string, fingerprint string, opts ...LeaseOptionFunc) (*LicenseOperationResult, error)
 	Config() *Config
 	CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.Node, error)
 	GetPools(ctx context.Context) ([]db.Pool, error)
 	GetPoolByID(ctx context.Context, id int64) (*db.Pool, error)
+	CreatePool(ctx context.Context, name string) (*db.Pool, error)
+	DeletePool(ctx context.Context, name string) error
+	UpdatePool(ctx context.Context, name string, settings PoolSettings) (*db.Pool, error)
+	ResetPool(ctx context.Context, name string) (*db.Pool, error)
+	GetPoolStats(ctx context.Context) ([]db.PoolStats, error)
+	Ping(ctx context.Context) error
+	CountActiveNodes(ctx context.Context) (int64, error)
+	GetLease(ctx context.Context, pool *string, fingerprint string, opts ...LeaseOptionFunc) (*db.Lease, error)
+	GetNodeByID(ctx context.Context, id int64) (*db.Node, error)
+	ListLeases(ctx context.Context, pool *string, limit int64, offset int64, opts ...ListOptionFunc) ([]db.Lease, error)
+	ListEvents(ctx context.Context, pool *string, afterID int64, limit int64, opts ...ListOptionFunc) ([]db.Event, error)
+	LastEventID(ctx context.Context) (int64, error)
+	CreateAPIToken(ctx context.Context, pool *string) (string, *db.ApiToken, error)
+	ListAPITokens(ctx context.Context) ([]db.ApiToken, error)
+	DeleteAPIToken(ctx context.Context, id int64) error
+	AuthenticateAPIToken(ctx context.Context, pool *string, token string) (*db.ApiToken, error)
+	AddPoolCIDR(ctx context.Context, pool string, cidr string, action CIDRAction) (*db.PoolCidr, error)
+	RemovePoolCIDR(ctx context.Context, pool string, cidr string, action CIDRAction) error
+	ListPoolCIDRs(ctx context.Context) ([]db.PoolCidr, error)
+	CheckPoolAccess(ctx context.Context, pool *string, ip net.IP) error
+	ReserveLicense(ctx context.Context, id string, fingerprint string) (*db.License, error)
+	UnreserveLicense(ctx context.Context, id string) (*db.License, error)
 }
 
 type manager struct {
@@ -71,7 +101,9 @@ func (m *manager) AttachStore(store db.Store) {
 	m.store = store
 }
 
-func (m *manager) AddLicense(ctx context.Context, poolName *string, licenseFilePath string, licenseKey string, publicKey string) (*db.License, error) {
+// AddLicense verifies and adds a license file to a pool, where the license file is verified
+// using the global keygen.PublicKey, which must be set once at startup
+func (m *manager) AddLicense(ctx context.Context, poolName *string, licenseFilePath string, licenseKey string) (*db.License, error) {
 	logger.Debug("starting to add a new license", "pool", poolName, "filePath", licenseFilePath)
 
 	cert, err := m.dataReader(licenseFilePath)
@@ -90,8 +122,6 @@ func (m *manager) AddLicense(ctx context.Context, poolName *string, licenseFileP
 	logger.Debug("successfully read the license file", "filePath", licenseFilePath)
 
 	lic := m.verifier(cert)
-	keygen.PublicKey = publicKey
-
 	if err := lic.Verify(); err != nil {
 		return nil, fmt.Errorf("license verification failed: %w", err)
 	}
@@ -239,7 +269,9 @@ func (m *manager) GetLicenseByGUID(ctx context.Context, poolName *string, guid s
 	return license, nil
 }
 
-func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprint string) (*LicenseOperationResult, error) {
+func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprint string, opts ...LeaseOptionFunc) (*LicenseOperationResult, error) {
+	options := ApplyLeaseOptions(opts...)
+
 	tx, err := m.store.BeginTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -251,7 +283,9 @@ func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprin
 		return nil, err
 	}
 
-	node, err := m.findOrActivateNode(ctx, tx, pool, fingerprint)
+	leaseID := options.LeaseID()
+
+	node, err := m.findOrActivateNode(ctx, tx, pool, fingerprint, leaseID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find or activate node: %w", err)
 	}
@@ -261,24 +295,51 @@ func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprin
 
 	// extend the lease if the node already has a lease on a license
 	if err == nil {
-		if !m.config.ExtendOnHeartbeat { // if heartbeat is disabled, we can't extend the claimed license
+		if !m.heartbeatFor(pool) { // if heartbeat is disabled, we can't extend the claimed license
 			logger.Warn("failed to claim license due to conflict due to heartbeat disabled", "nodeID", node.ID, "nodeFingerprint", node.Fingerprint)
 
 			return &LicenseOperationResult{Status: OperationStatusConflict}, nil
 		}
 
-		if err := tx.PingNodeHeartbeatByFingerprint(ctx, fingerprint); err != nil {
+		if token := options.LeaseToken(); token != nil {
+			if err := verifyLeaseToken(node, *token); err != nil {
+				logger.Warn("failed to extend lease due to lease token mismatch", "nodeID", node.ID, "nodeFingerprint", node.Fingerprint)
+
+				return nil, err
+			}
+		}
+
+		if err := tx.PingNodeHeartbeatByFingerprint(ctx, fingerprint, leaseID); err != nil {
 			return nil, fmt.Errorf("failed to update node heartbeat: %w", err)
 		}
 
+		// only update the node's ttl when requested, otherwise keep the ttl it leased with
+		if ttl := options.TTL(); ttl != nil {
+			if err := tx.SetNodeTTLByFingerprint(ctx, fingerprint, leaseID, ttl); err != nil {
+				return nil, fmt.Errorf("failed to update node ttl: %w", err)
+			}
+		}
+
+		// only update the node's metadata when reported, otherwise keep what it leased with
+		if metadata := options.Metadata(); metadata != nil {
+			if err := tx.SetNodeMetadataByFingerprint(ctx, fingerprint, leaseID, metadata); err != nil {
+				return nil, fmt.Errorf("failed to update node metadata: %w", err)
+			}
+		}
+
+		node, err = tx.GetNodeByFingerprint(ctx, fingerprint, leaseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch node: %w", err)
+		}
+
 		if err := tx.Commit(); err != nil {
 			return nil, fmt.Errorf("failed to commit transaction: %w", err)
 		}
 
 		if m.config.EnabledAudit {
 			if err := m.store.BulkInsertAuditLogs(ctx, []db.BulkInsertAuditLogParams{
-				{EventTypeID: db.EventTypeLicenseLeaseExtended, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID, Pool: pool},
-				{EventTypeID: db.EventTypeNodeHeartbeatPing, EntityTypeID: db.EntityTypeNode, EntityID: node.ID, Pool: pool},
+				{EventTypeID: db.EventTypeLicenseLeaseExtended, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID, Pool: pool, Metadata: node.NodeMetadata()},
+				{EventTypeID: db.EventTypeNodeHeartbeatPing, EntityTypeID: db.EntityTypeNode, EntityID: node.ID, Pool: pool, Metadata: node.NodeMetadata()},
 			}); err != nil {
 				logger.Warn("failed to insert audit logs", "error", err)
 			}
@@ -288,12 +349,37 @@ func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprin
 
 		return &LicenseOperationResult{
 			License: license,
+			Node:    node,
 			Status:  OperationStatusExtended,
 		}, nil
 	}
 
+	if options.ExtendOnly() {
+		logger.Debug("node has no lease to extend", "nodeId", node.ID, "nodeFingerprint", node.Fingerprint)
+
+		return &LicenseOperationResult{Status: OperationStatusNoLicensesAvailable}, nil
+	}
+
+	// sub-leases count towards the node's maximum along with its primary lease
+	if limit := m.config.MaxLeasesPerNode; limit > 0 {
+		count, err := tx.CountLeasesByFingerprint(ctx, fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count node leases: %w", err)
+		}
+
+		if count >= int64(limit) {
+			logger.Warn("failed to claim license due to node lease limit", "nodeId", node.ID, "nodeFingerprint", node.Fingerprint, "leaseId", leaseID, "leases", count)
+
+			return nil, fmt.Errorf("node %s: %w", fingerprint, ErrTooManyLeases)
+		}
+	}
+
 	// claim a new lease on a license if node doesn't have a lease
-	license, err = tx.ClaimLicenseByStrategy(ctx, m.config.Strategy, &node.ID, db.WithPool(pool))
+	if strategy := m.strategyFor(pool); strategy == "affinity" {
+		license, err = tx.ClaimLicenseByAffinity(ctx, m.affinityFallback(), &node.ID, db.WithPool(pool))
+	} else {
+		license, err = tx.ClaimLicenseByStrategy(ctx, strategy, &node.ID, db.WithPool(pool))
+	}
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			logger.Warn("no licenses available in pool", "nodeId", node.ID, "nodeFingerprint", node.Fingerprint)
@@ -308,18 +394,51 @@ func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprin
 		return nil, fmt.Errorf("failed to claim license: %w", err)
 	}
 
-	if err := tx.PingNodeHeartbeatByFingerprint(ctx, fingerprint); err != nil {
+	if err := tx.PingNodeHeartbeatByFingerprint(ctx, fingerprint, leaseID); err != nil {
 		return nil, fmt.Errorf("failed to update node claim: %w", err)
 	}
 
+	// a new lease always resets the node's ttl, since it may have been reactivated, where
+	// the pool's ttl is used unless the node requested its own
+	ttl := options.TTL()
+	if ttl == nil {
+		ttl = poolTTL(pool)
+	}
+
+	if err := tx.SetNodeTTLByFingerprint(ctx, fingerprint, leaseID, ttl); err != nil {
+		return nil, fmt.Errorf("failed to update node ttl: %w", err)
+	}
+
+	// a new lease always replaces the node's metadata, since it may have been reactivated
+	// by another host using the same fingerprint
+	if err := tx.SetNodeMetadataByFingerprint(ctx, fingerprint, leaseID, options.Metadata()); err != nil {
+		return nil, fmt.Errorf("failed to update node metadata: %w", err)
+	}
+
+	// a new lease always rotates the node's lease token, so that a previous holder of the
+	// fingerprint can't act on the new lease
+	token, digest, err := newLeaseToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.SetNodeLeaseTokenDigestByFingerprint(ctx, fingerprint, leaseID, digest); err != nil {
+		return nil, fmt.Errorf("failed to update node lease token: %w", err)
+	}
+
+	node, err = tx.GetNodeByFingerprint(ctx, fingerprint, leaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node: %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	if m.config.EnabledAudit {
 		if err := m.store.BulkInsertAuditLogs(ctx, []db.BulkInsertAuditLogParams{
-			{Pool: pool, EventTypeID: db.EventTypeLicenseLeased, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID},
-			{Pool: pool, EventTypeID: db.EventTypeNodeHeartbeatPing, EntityTypeID: db.EntityTypeNode, EntityID: node.ID},
+			{Pool: pool, EventTypeID: db.EventTypeLicenseLeased, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID, Metadata: node.NodeMetadata()},
+			{Pool: pool, EventTypeID: db.EventTypeNodeHeartbeatPing, EntityTypeID: db.EntityTypeNode, EntityID: node.ID, Metadata: node.NodeMetadata()},
 		}); err != nil {
 			logger.Warn("failed to insert audit logs", "error", err)
 		}
@@ -328,22 +447,28 @@ func (m *manager) ClaimLicense(ctx context.Context, poolName *string, fingerprin
 	logger.Info("new lease claimed successfully", "licenseGuid", license.Guid)
 
 	return &LicenseOperationResult{
-		License: license,
-		Status:  OperationStatusCreated,
+		License:    license,
+		Node:       node,
+		Status:     OperationStatusCreated,
+		LeaseToken: token,
 	}, nil
 }
 
-func (m *manager) ReleaseLicense(ctx context.Context, poolName *string, fingerprint string) (*LicenseOperationResult, error) {
+func (m *manager) ReleaseLicense(ctx context.Context, poolName *string, fingerprint string, opts ...LeaseOptionFunc) (*LicenseOperationResult, error) {
+	options := ApplyLeaseOptions(opts...)
+
 	tx, err := m.store.BeginTx(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	node, err := tx.GetNodeByFingerprint(ctx, fingerprint)
+	leaseID := options.LeaseID()
+
+	node, err := tx.GetNodeByFingerprint(ctx, fingerprint, leaseID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			logger.Warn("license release failed - node not found", "fingerprint", fingerprint)
+			logger.Warn("license release failed - node not found", "fingerprint", fingerprint, "leaseId", leaseID)
 
 			return &LicenseOperationResult{Status: OperationStatusNotFound}, nil
 		}
@@ -369,12 +494,20 @@ func (m *manager) ReleaseLicense(ctx context.Context, poolName *string, fingerpr
 		return nil, fmt.Errorf("failed to fetch claimed license: %w", err)
 	}
 
+	if token := options.LeaseToken(); token != nil {
+		if err := verifyLeaseToken(node, *token); err != nil {
+			logger.Warn("license release failed - lease token mismatch", "nodeFingerprint", node.Fingerprint)
+
+			return nil, err
+		}
+	}
+
 	err = tx.ReleaseLicenseByNodeID(ctx, &node.ID, db.WithPool(pool))
 	if err != nil {
 		return nil, fmt.Errorf("failed to release license: %w", err)
 	}
 
-	if err := tx.DeactivateNodeByFingerprint(ctx, fingerprint); err != nil {
+	if err := tx.DeactivateNodeByFingerprint(ctx, fingerprint, leaseID); err != nil {
 		return nil, fmt.Errorf("failed to deactivate node: %w", err)
 	}
 
@@ -384,8 +517,8 @@ func (m *manager) ReleaseLicense(ctx context.Context, poolName *string, fingerpr
 
 	if m.config.EnabledAudit {
 		if err := m.store.BulkInsertAuditLogs(ctx, []db.BulkInsertAuditLogParams{
-			{Pool: pool, EventTypeID: db.EventTypeLicenseReleased, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID},
-			{Pool: pool, EventTypeID: db.EventTypeNodeDeactivated, EntityTypeID: db.EntityTypeNode, EntityID: node.ID},
+			{Pool: pool, EventTypeID: db.EventTypeLicenseReleased, EntityTypeID: db.EntityTypeLicense, EntityID: license.ID, Metadata: node.NodeMetadata()},
+			{Pool: pool, EventTypeID: db.EventTypeNodeDeactivated, EntityTypeID: db.EntityTypeNode, EntityID: node.ID, Metadata: node.NodeMetadata()},
 		}); err != nil {
 			logger.Warn("failed to insert audit logs", "error", err)
 		}
@@ -396,6 +529,99 @@ func (m *manager) ReleaseLicense(ctx context.Context, poolName *string, fingerpr
 	return &LicenseOperationResult{Status: OperationStatusSuccess}, nil
 }
 
+func (m *manager) GetLease(ctx context.Context, poolName *string, fingerprint string, opts ...LeaseOptionFunc) (*db.Lease, error) {
+	options := ApplyLeaseOptions(opts...)
+	leaseID := options.LeaseID()
+
+	logger.Debug("fetching lease", "pool", poolName, "nodeFingerprint", fingerprint, "leaseId", leaseID)
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var lease *db.Lease
+	if pool != nil {
+		lease, err = m.store.GetLeaseByFingerprint(ctx, fingerprint, leaseID, db.WithPool(pool))
+	} else {
+		lease, err = m.store.GetLeaseByFingerprint(ctx, fingerprint, leaseID) // query across all pools
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Debug("lease not found", "nodeFingerprint", fingerprint)
+
+			return nil, fmt.Errorf("node %s: %w", fingerprint, ErrLeaseNotFound)
+		}
+
+		logger.Debug("failed to fetch lease", "nodeFingerprint", fingerprint, "error", err)
+
+		return nil, err
+	}
+
+	logger.Debug("fetched lease successfully", "nodeFingerprint", fingerprint, "licenseGuid", lease.License.Guid)
+
+	return lease, nil
+}
+
+func (m *manager) ListLeases(ctx context.Context, poolName *string, limit int64, offset int64, opts ...ListOptionFunc) ([]db.Lease, error) {
+	options := ApplyListOptions(opts...)
+
+	logger.Debug("fetching leases", "pool", poolName, "limit", limit, "offset", offset)
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var leases []db.Lease
+	switch {
+	case pool != nil:
+		leases, err = m.store.GetLeases(ctx, limit, offset, db.WithPool(pool))
+	case options.WithoutPool():
+		leases, err = m.store.GetLeases(ctx, limit, offset, db.WithoutPool())
+	default:
+		leases, err = m.store.GetLeases(ctx, limit, offset) // list all leases
+	}
+	if err != nil {
+		logger.Debug("failed to fetch leases", "error", err)
+
+		return nil, err
+	}
+
+	logger.Debug("fetched leases successfully", "count", len(leases))
+
+	return leases, nil
+}
+
+func (m *manager) ListEvents(ctx context.Context, poolName *string, afterID int64, limit int64, opts ...ListOptionFunc) ([]db.Event, error) {
+	options := ApplyListOptions(opts...)
+
+	pool, err := m.resolvePool(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []db.Event
+	switch {
+	case pool != nil:
+		events, err = m.store.GetEventsAfterID(ctx, afterID, limit, db.WithPool(pool))
+	case options.WithoutPool():
+		events, err = m.store.GetEventsAfterID(ctx, afterID, limit, db.WithoutPool())
+	default:
+		events, err = m.store.GetEventsAfterID(ctx, afterID, limit) // list events across all pools
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (m *manager) LastEventID(ctx context.Context) (int64, error) {
+	return m.store.GetLastEventID(ctx)
+}
+
 func (m *manager) Config() *Config {
 	return m.config
 }
@@ -412,7 +638,7 @@ func (m *manager) findOrCreatePool(ctx context.Context, tx *db.TxStore, name str
 			}
 
 			if m.config.EnabledAudit {
-				if err := tx.InsertAuditLog(ctx, nil, db.EventTypePoolAdded, db.EntityTypePool, pool.ID); err != nil {
+				if err := tx.InsertAuditLog(ctx, pool, db.EventTypePoolAdded, db.EntityTypePool, pool.ID); err != nil {
 					logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
 				}
 			}
@@ -426,11 +652,11 @@ func (m *manager) findOrCreatePool(ctx context.Context, tx *db.TxStore, name str
 	return pool, nil
 }
 
-func (m *manager) findOrActivateNode(ctx context.Context, tx *db.TxStore, pool *db.Pool, fingerprint string) (*db.Node, error) {
-	node, err := tx.GetNodeByFingerprint(ctx, fingerprint)
+func (m *manager) findOrActivateNode(ctx context.Context, tx *db.TxStore, pool *db.Pool, fingerprint string, leaseID string) (*db.Node, error) {
+	node, err := tx.GetNodeByFingerprint(ctx, fingerprint, leaseID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			node, err = tx.ActivateNode(ctx, fingerprint)
+			node, err = tx.ActivateNode(ctx, fingerprint, leaseID)
 			if err != nil {
 				logger.Error("failed to insert node", "nodeFingerprint", fingerprint, "error", err)
 
@@ -459,7 +685,7 @@ func (m *manager) CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.No
 	}
 	defer tx.Rollback()
 
-	licenses, err := tx.ReleaseLicensesFromDeadNodes(ctx, ttl)
+	licenses, err := tx.ReleaseLicensesFromDeadNodes(ctx, ttl, m.config.ExtendOnHeartbeat)
 	if err != nil {
 		logger.Error("failed to release licenses from dead nodes", "error", err)
 
@@ -479,7 +705,17 @@ func (m *manager) CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.No
 		return nil, err
 	}
 
-	if m.config.EnabledAudit {
+	culled := make([]db.Node, 0, len(nodes))
+	for _, node := range nodes {
+		culled = append(culled, node.Node)
+	}
+
+	if m.config.EnabledAudit && (len(licenses) > 0 || len(nodes) > 0) {
+		pools, err := m.poolsByID(ctx)
+		if err != nil {
+			logger.Warn("failed to fetch pools", "error", err)
+		}
+
 		var logs []db.BulkInsertAuditLogParams
 
 		for _, license := range licenses {
@@ -487,6 +723,7 @@ func (m *manager) CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.No
 				EventTypeID:  db.EventTypeLicenseLeaseExpired,
 				EntityTypeID: db.EntityTypeLicense,
 				EntityID:     license.ID,
+				Pool:         pools.lookup(license.PoolID),
 			})
 		}
 
@@ -494,7 +731,9 @@ func (m *manager) CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.No
 			logs = append(logs, db.BulkInsertAuditLogParams{
 				EventTypeID:  db.EventTypeNodeCulled,
 				EntityTypeID: db.EntityTypeNode,
-				EntityID:     node.ID,
+				EntityID:     node.Node.ID,
+				Pool:         pools.lookup(node.PoolID),
+				Metadata:     node.Node.NodeMetadata(),
 			})
 		}
 
@@ -503,7 +742,7 @@ func (m *manager) CullDeadNodes(ctx context.Context, ttl time.Duration) ([]db.No
 		}
 	}
 
-	return nodes, nil
+	return culled, nil
 }
 
 // resolvePool resolves a pool name to a Pool object, returning nil if poolName is nil
@@ -560,6 +799,120 @@ func (m *manager) GetPoolByID(ctx context.Context, id int64) (*db.Pool, error) {
 	return pool, nil
 }
 
+func (m *manager) GetNodeByID(ctx context.Context, id int64) (*db.Node, error) {
+	node, err := m.store.GetNodeByID(ctx, id)
+	if err != nil {
+		logger.Debug("failed to get node by ID", "id", id, "error", err)
+
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (m *manager) CreatePool(ctx context.Context, name string) (*db.Pool, error) {
+	logger.Debug("starting to create pool", "poolName", name)
+
+	pool, err := m.store.CreatePool(ctx, name)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, fmt.Errorf("pool %s: %w", name, ErrPoolExists)
+		}
+
+		logger.Error("failed to insert pool", "poolName", name, "error", err)
+
+		return nil, fmt.Errorf("failed to insert pool: %w", err)
+	}
+
+	if m.config.EnabledAudit {
+		if err := m.store.InsertAuditLog(ctx, pool, db.EventTypePoolAdded, db.EntityTypePool, pool.ID); err != nil {
+			logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
+		}
+	}
+
+	logger.Debug("created pool successfully", "poolName", name)
+
+	return pool, nil
+}
+
+func (m *manager) DeletePool(ctx context.Context, name string) error {
+	logger.Debug("starting to delete pool", "poolName", name)
+
+	tx, err := m.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	pool, err := m.resolvePoolWithTx(ctx, tx, &name)
+	if err != nil {
+		return err
+	}
+
+	// deleting a pool would otherwise move its licenses into the global pool
+	licenses, err := tx.GetLicenses(ctx, db.WithPool(pool))
+	if err != nil {
+		return fmt.Errorf("failed to fetch licenses: %w", err)
+	}
+
+	if len(licenses) > 0 {
+		return fmt.Errorf("pool %s: %w", name, ErrPoolNotEmpty)
+	}
+
+	if _, err := tx.DeletePoolByID(ctx, pool.ID); err != nil {
+		logger.Debug("failed to delete pool", "poolName", name, "error", err)
+
+		return fmt.Errorf("failed to delete pool: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if m.config.EnabledAudit {
+		if err := m.store.InsertAuditLog(ctx, pool, db.EventTypePoolRemoved, db.EntityTypePool, pool.ID); err != nil {
+			logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
+		}
+	}
+
+	logger.Debug("deleted pool successfully", "poolName", name)
+
+	return nil
+}
+
+func (m *manager) GetPoolStats(ctx context.Context) ([]db.PoolStats, error) {
+	stats, err := m.store.GetPoolStats(ctx)
+	if err != nil {
+		logger.Error("failed to get pool stats", "error", err)
+
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Ping verifies the database is reachable
+func (m *manager) Ping(ctx context.Context) error {
+	if err := m.store.Ping(ctx); err != nil {
+		logger.Error("failed to ping database", "error", err)
+
+		return err
+	}
+
+	return nil
+}
+
+func (m *manager) CountActiveNodes(ctx context.Context) (int64, error) {
+	count, err := m.store.CountActiveNodes(ctx)
+	if err != nil {
+		logger.Error("failed to count active nodes", "error", err)
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func isUniqueConstraintError(err error) bool {
 	var sqliteErr sqlite3.Error
 
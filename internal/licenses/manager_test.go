@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"testing"
 	"time"
@@ -32,7 +34,7 @@ func TestAddLicense_Success(t *testing.T) {
 
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	license, err := manager.GetLicenseByGUID(context.Background(), nil, "license_test_key")
@@ -58,10 +60,10 @@ func TestAddLicense_Failure(t *testing.T) {
 
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
-	_, err = manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key", "test_public_key")
+	_, err = manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "license with the provided key already exists")
 }
@@ -84,7 +86,7 @@ func TestAddLicense_FileNotFound(t *testing.T) {
 
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(context.Background(), nil, "non_existent.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), nil, "non_existent.lic", "test_key")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "license file not found at 'non_existent.lic'")
@@ -109,7 +111,7 @@ func TestAddPooledLicense_Success(t *testing.T) {
 	manager.AttachStore(*store)
 
 	poolName := "test-pool"
-	_, err := manager.AddLicense(context.Background(), &poolName, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), &poolName, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	license, err := manager.GetLicenseByGUID(context.Background(), &poolName, "license_test_key")
@@ -138,7 +140,7 @@ func TestRemoveLicense_Success(t *testing.T) {
 	manager.AttachStore(*store)
 
 	// add a license that to be deleted
-	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key")
 	assert.NoError(t, err, "failed to add license")
 
 	// check that the license was created
@@ -177,7 +179,7 @@ func TestRemovePooledLicense_Success(t *testing.T) {
 	poolName := "test-pool"
 
 	// add a license that to be deleted
-	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key")
 	assert.NoError(t, err, "failed to add license")
 
 	// check that the license was created
@@ -238,9 +240,9 @@ func TestListLicenses_Success(t *testing.T) {
 
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(context.Background(), nil, "test_license_1.lic", "test_key_1", "test_public_key_1")
+	_, err := manager.AddLicense(context.Background(), nil, "test_license_1.lic", "test_key_1")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(context.Background(), nil, "test_license_2.lic", "test_key_2", "test_public_key_2")
+	_, err = manager.AddLicense(context.Background(), nil, "test_license_2.lic", "test_key_2")
 	assert.NoError(t, err)
 
 	licenseList, err := manager.ListLicenses(context.Background(), nil)
@@ -285,19 +287,19 @@ func TestListPooledLicenses_Isolation(t *testing.T) {
 	pool2 := "pool-2"
 
 	// Add licenses to pool-1
-	_, err := manager.AddLicense(context.Background(), &pool1, "license_pool1_1.lic", "key_pool1_1", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), &pool1, "license_pool1_1.lic", "key_pool1_1")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(context.Background(), &pool1, "license_pool1_2.lic", "key_pool1_2", "test_public_key")
+	_, err = manager.AddLicense(context.Background(), &pool1, "license_pool1_2.lic", "key_pool1_2")
 	assert.NoError(t, err)
 
 	// Add license to pool-2
-	_, err = manager.AddLicense(context.Background(), &pool2, "license_pool2_1.lic", "key_pool2_1", "test_public_key")
+	_, err = manager.AddLicense(context.Background(), &pool2, "license_pool2_1.lic", "key_pool2_1")
 	assert.NoError(t, err)
 
 	// Add licenses to default pool (nil)
-	_, err = manager.AddLicense(context.Background(), nil, "license_default_1.lic", "key_default_1", "test_public_key")
+	_, err = manager.AddLicense(context.Background(), nil, "license_default_1.lic", "key_default_1")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(context.Background(), nil, "license_default_2.lic", "key_default_2", "test_public_key")
+	_, err = manager.AddLicense(context.Background(), nil, "license_default_2.lic", "key_default_2")
 	assert.NoError(t, err)
 
 	// List pool-1 licenses - should have 2
@@ -347,7 +349,7 @@ func TestGetLicenseByGUID_Success(t *testing.T) {
 
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(context.Background(), nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	license, err := manager.GetLicenseByGUID(context.Background(), nil, "license_test_key")
@@ -398,7 +400,7 @@ func TestClaimLicense_Success(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint")
@@ -431,7 +433,7 @@ func TestClaimPooledLicense_Success(t *testing.T) {
 	manager.AttachStore(*store)
 
 	poolName := "test-pool"
-	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	result, err := manager.ClaimLicense(ctx, &poolName, "test_fingerprint")
@@ -489,7 +491,7 @@ func TestClaimLicense_AlreadyClaimed_WithHeartbeatEnabled(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	// First getting the license
@@ -526,7 +528,7 @@ func TestClaimLicense_AlreadyClaimed_WithHeartbeatDisabled(t *testing.T) {
 	manager.AttachStore(*store)
 
 	// add the license
-	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	// first getting the license
@@ -570,11 +572,11 @@ func TestClaimLicense_FIFO_Strategy(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(ctx, nil, "license1.lic", "key1", "public_key")
+	_, err := manager.AddLicense(ctx, nil, "license1.lic", "key1")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(ctx, nil, "license2.lic", "key2", "public_key")
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "key2")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(ctx, nil, "license3.lic", "key3", "public_key")
+	_, err = manager.AddLicense(ctx, nil, "license3.lic", "key3")
 	assert.NoError(t, err)
 
 	// we need to update created_at manually, because in tests the records are created very quickly in the same time
@@ -626,13 +628,13 @@ func TestClaimLicense_LIFO_Strategy(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(ctx, nil, "license1.lic", "key1", "public_key")
+	_, err := manager.AddLicense(ctx, nil, "license1.lic", "key1")
 	assert.NoError(t, err)
 
-	_, err = manager.AddLicense(ctx, nil, "license2.lic", "key2", "public_key")
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "key2")
 	assert.NoError(t, err)
 
-	_, err = manager.AddLicense(ctx, nil, "license3.lic", "key3", "public_key")
+	_, err = manager.AddLicense(ctx, nil, "license3.lic", "key3")
 	assert.NoError(t, err)
 
 	_, err = dbConn.ExecContext(ctx, `UPDATE licenses SET created_at = strftime('%s', 'now', '-3 seconds') WHERE guid = 'license_key1'`)
@@ -653,6 +655,62 @@ func TestClaimLicense_LIFO_Strategy(t *testing.T) {
 	assert.Equal(t, "key3", result.License.Key)
 }
 
+func TestClaimLicense_Affinity_Strategy(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{
+			Strategy:          "affinity",
+			AffinityFallback:  "lifo",
+			EnabledAudit:      true,
+			ExtendOnHeartbeat: true,
+		},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	for i := 1; i <= 3; i++ {
+		_, err := manager.AddLicense(ctx, nil, fmt.Sprintf("license%d.lic", i), fmt.Sprintf("key%d", i))
+		assert.NoError(t, err)
+	}
+
+	_, err := dbConn.ExecContext(ctx, `UPDATE licenses SET created_at = strftime('%s', 'now', '-3 seconds') WHERE guid = 'license_key1'`)
+	assert.NoError(t, err)
+
+	_, err = dbConn.ExecContext(ctx, `UPDATE licenses SET created_at = strftime('%s', 'now', '-2 seconds') WHERE guid = 'license_key2'`)
+	assert.NoError(t, err)
+
+	_, err = dbConn.ExecContext(ctx, `UPDATE licenses SET created_at = strftime('%s', 'now', '-1 seconds') WHERE guid = 'license_key3'`)
+	assert.NoError(t, err)
+
+	// the fallback is used for a node's first claim
+	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, "license_key3", result.License.Guid)
+
+	result, err = manager.ClaimLicense(ctx, nil, "other_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, "license_key2", result.License.Guid)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+
+	// a restarted node gets its previous license back, even though the fallback would
+	// pick another
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, "license_key3", result.License.Guid)
+}
+
 func TestReleaseLicense_Success(t *testing.T) {
 	ctx := context.Background()
 	store, dbConn := testutils.NewMemoryStore(t)
@@ -673,7 +731,7 @@ func TestReleaseLicense_Success(t *testing.T) {
 	manager.AttachStore(*store)
 
 	// adding the license and then getting it
-	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint")
@@ -687,7 +745,7 @@ func TestReleaseLicense_Success(t *testing.T) {
 	assert.Equal(t, releaseResult.Status, licenses.OperationStatusSuccess)
 
 	// checking the empty node
-	_, err = store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	_, err = store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, sql.ErrNoRows))
 
@@ -720,7 +778,7 @@ func TestReleasePooledLicense_Success(t *testing.T) {
 	poolName := "test-pool"
 
 	// adding the license and then getting it
-	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, &poolName, "test_license.lic", "test_key")
 	assert.NoError(t, err)
 
 	result, err := manager.ClaimLicense(ctx, &poolName, "test_fingerprint")
@@ -734,7 +792,7 @@ func TestReleasePooledLicense_Success(t *testing.T) {
 	assert.Equal(t, releaseResult.Status, licenses.OperationStatusSuccess)
 
 	// checking the empty node
-	_, err = store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	_, err = store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, sql.ErrNoRows))
 
@@ -788,7 +846,7 @@ func TestReleaseLicense_LicenseNotFound(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := store.ActivateNode(ctx, "test_fingerprint")
+	_, err := store.ActivateNode(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 
 	result, err := manager.ReleaseLicense(ctx, nil, "test_fingerprint")
@@ -813,29 +871,29 @@ func TestActivateNode_ActivateDeactivateReactivate(t *testing.T) {
 	manager.AttachStore(*store)
 
 	// activate
-	node, err := store.ActivateNode(ctx, "test_fingerprint")
+	node, err := store.ActivateNode(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 	assert.NotNil(t, node)
 	assert.Equal(t, node.Fingerprint, "test_fingerprint")
 
-	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 	assert.NotNil(t, node)
 
 	// deactivate
-	err = store.DeactivateNodeByFingerprint(ctx, "test_fingerprint")
+	err = store.DeactivateNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 
-	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.Error(t, err)
 	assert.Nil(t, node)
 
 	// reactivate
-	node, err = store.ActivateNode(ctx, "test_fingerprint")
+	node, err = store.ActivateNode(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 	assert.NotNil(t, node)
 
-	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	node, err = store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.NoError(t, err)
 	assert.NotNil(t, node)
 }
@@ -859,9 +917,9 @@ func TestCullDeadNodes(t *testing.T) {
 	)
 	manager.AttachStore(*store)
 
-	_, err := manager.AddLicense(ctx, nil, "license1.lic", "test_key", "test_public_key")
+	_, err := manager.AddLicense(ctx, nil, "license1.lic", "test_key")
 	assert.NoError(t, err)
-	_, err = manager.AddLicense(ctx, nil, "license2.lic", "test_key_2", "test_public_key")
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "test_key_2")
 	assert.NoError(t, err)
 
 	// сlaim the first license
@@ -896,11 +954,989 @@ func TestCullDeadNodes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, license2.NodeID)
 
-	node, err := store.GetNodeByFingerprint(ctx, "test_fingerprint")
+	node, err := store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
 	assert.Error(t, err)
 	assert.Nil(t, node)
 
-	node2, err := store.GetNodeByFingerprint(ctx, "test_fingerprint_2")
+	node2, err := store.GetNodeByFingerprint(ctx, "test_fingerprint_2", "")
 	assert.NoError(t, err)
 	assert.Equal(t, node2.Fingerprint, "test_fingerprint_2")
 }
+
+func TestClaimLicense_TTL(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	_, err := manager.AddLicense(ctx, nil, "license1.lic", "test_key_1")
+	assert.NoError(t, err)
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "test_key_2")
+	assert.NoError(t, err)
+
+	// a new lease persists the requested ttl
+	result, err := manager.ClaimLicense(ctx, nil, "short_fingerprint", licenses.WithTTL(30*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, 30*time.Second, result.Node.LeaseTTL(time.Minute))
+
+	result, err = manager.ClaimLicense(ctx, nil, "long_fingerprint", licenses.WithTTL(10*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, 10*time.Minute, result.Node.LeaseTTL(time.Minute))
+
+	// an extension without a ttl keeps the node's ttl
+	result, err = manager.ClaimLicense(ctx, nil, "long_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+	assert.Equal(t, 10*time.Minute, result.Node.LeaseTTL(time.Minute))
+
+	// an extension with a ttl updates the node's ttl
+	result, err = manager.ClaimLicense(ctx, nil, "long_fingerprint", licenses.WithTTL(5*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+	assert.Equal(t, 5*time.Minute, result.Node.LeaseTTL(time.Minute))
+
+	// both nodes missed heartbeats for 2 minutes, but only the short-lived node is dead
+	_, err = dbConn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-120 seconds')`)
+	assert.NoError(t, err)
+
+	nodes, err := manager.CullDeadNodes(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "short_fingerprint", nodes[0].Fingerprint)
+
+	node, err := store.GetNodeByFingerprint(ctx, "long_fingerprint", "")
+	assert.NoError(t, err)
+	assert.Nil(t, node.DeactivatedAt)
+}
+
+func TestClaimLicense_LeaseToken(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate"), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	_, err := manager.AddLicense(ctx, nil, "license.lic", "test_key")
+	assert.NoError(t, err)
+
+	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Len(t, result.LeaseToken, 64)
+
+	// only the digest of the token is stored
+	node, err := store.GetNodeByFingerprint(ctx, "test_fingerprint", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, node.LeaseTokenDigest)
+	assert.NotEqual(t, result.LeaseToken, *node.LeaseTokenDigest)
+
+	token := result.LeaseToken
+
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken("invalid_token"))
+	assert.ErrorIs(t, err, licenses.ErrLeaseTokenMismatch)
+
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken(""))
+	assert.ErrorIs(t, err, licenses.ErrLeaseTokenMismatch)
+
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken(token))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+	assert.Empty(t, result.LeaseToken)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken("invalid_token"))
+	assert.ErrorIs(t, err, licenses.ErrLeaseTokenMismatch)
+
+	result, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken(token))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusSuccess, result.Status)
+
+	// a new lease rotates the token
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.NotEqual(t, token, result.LeaseToken)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseToken(token))
+	assert.ErrorIs(t, err, licenses.ErrLeaseTokenMismatch)
+
+	// releasing without verification e.g. by an admin
+	result, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusSuccess, result.Status)
+}
+
+func TestClaimLicense_Metadata(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true, EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate"), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	_, err := manager.AddLicense(ctx, nil, "license.lic", "test_key")
+	assert.NoError(t, err)
+
+	metadata := &db.NodeMetadata{Hostname: "build-01", Platform: "linux/amd64", Username: "ci", AppName: "builder", AppVersion: "1.0.0", Labels: map[string]string{"team": "platform"}}
+
+	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithMetadata(metadata))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, metadata, result.Node.NodeMetadata())
+
+	// heartbeats update the metadata when reported
+	upgraded := &db.NodeMetadata{Hostname: "build-01", AppName: "builder", AppVersion: "1.1.0"}
+
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithMetadata(upgraded))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+	assert.Equal(t, upgraded, result.Node.NodeMetadata())
+
+	// and otherwise keep it
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, upgraded, result.Node.NodeMetadata())
+
+	// the audit trail keeps a snapshot of the metadata at the time of each event
+	events, err := manager.ListEvents(ctx, nil, 0, 100)
+	assert.NoError(t, err)
+
+	var leased, extended []*db.NodeMetadata
+	for _, event := range events {
+		switch event.EventType {
+		case "license.leased":
+			leased = append(leased, event.NodeMetadata())
+		case "license.lease_extended":
+			extended = append(extended, event.NodeMetadata())
+		}
+	}
+
+	assert.Equal(t, []*db.NodeMetadata{metadata}, leased)
+	assert.Equal(t, []*db.NodeMetadata{upgraded, upgraded}, extended)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+
+	// a new lease replaces the previous holder's metadata
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Nil(t, result.Node.NodeMetadata())
+}
+
+func TestClaimLicense_SubLeases(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true, MaxLeasesPerNode: 2},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	for i := range 3 {
+		_, err := manager.AddLicense(ctx, nil, fmt.Sprintf("license_%d.lic", i), fmt.Sprintf("test_key_%d", i))
+		assert.NoError(t, err)
+	}
+
+	primary, err := manager.ClaimLicense(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, primary.Status)
+
+	job, err := manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, job.Status)
+	assert.Equal(t, "job_1", job.Node.LeaseID)
+	assert.NotEqual(t, primary.License.ID, job.License.ID)
+
+	// each sub-lease has its own lease token
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"), licenses.WithLeaseToken(primary.LeaseToken))
+	assert.ErrorIs(t, err, licenses.ErrLeaseTokenMismatch)
+
+	result, err := manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"), licenses.WithLeaseToken(job.LeaseToken))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+
+	// the node has reached its maximum number of leases
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_2"))
+	assert.ErrorIs(t, err, licenses.ErrTooManyLeases)
+
+	// other nodes are unaffected
+	result, err = manager.ClaimLicense(ctx, nil, "test_fingerprint_2")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+
+	lease, err := manager.GetLease(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"))
+	assert.NoError(t, err)
+	assert.Equal(t, job.License.ID, lease.License.ID)
+
+	// releasing a sub-lease leaves the node's other leases intact
+	result, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"), licenses.WithLeaseToken(job.LeaseToken))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusSuccess, result.Status)
+
+	_, err = manager.GetLease(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"))
+	assert.ErrorIs(t, err, licenses.ErrLeaseNotFound)
+
+	lease, err = manager.GetLease(ctx, nil, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, primary.License.ID, lease.License.ID)
+
+	result, err = manager.ReleaseLicense(ctx, nil, "test_fingerprint", licenses.WithLeaseID("job_1"))
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusNotFound, result.Status)
+}
+
+func TestGetLease(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{
+			Strategy:          "fifo",
+			ExtendOnHeartbeat: true,
+		},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	poolName := "test-pool"
+	_, err := manager.AddLicense(ctx, &poolName, "license1.lic", "test_key")
+	assert.NoError(t, err)
+
+	result, err := manager.ClaimLicense(ctx, &poolName, "test_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+
+	t.Run("any pool", func(t *testing.T) {
+		lease, err := manager.GetLease(ctx, nil, "test_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, "test_fingerprint", lease.Node.Fingerprint)
+		assert.Equal(t, result.License.Guid, lease.License.Guid)
+		assert.NotNil(t, lease.Node.LastHeartbeatAt)
+	})
+
+	t.Run("named pool", func(t *testing.T) {
+		lease, err := manager.GetLease(ctx, &poolName, "test_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, result.License.Guid, lease.License.Guid)
+	})
+
+	t.Run("unknown pool", func(t *testing.T) {
+		unknownPool := "unknown-pool"
+
+		_, err := manager.GetLease(ctx, &unknownPool, "test_fingerprint")
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		_, err := manager.GetLease(ctx, nil, "unknown_fingerprint")
+		assert.ErrorIs(t, err, licenses.ErrLeaseNotFound)
+	})
+
+	t.Run("released node", func(t *testing.T) {
+		_, err := manager.ReleaseLicense(ctx, &poolName, "test_fingerprint")
+		assert.NoError(t, err)
+
+		_, err = manager.GetLease(ctx, nil, "test_fingerprint")
+		assert.ErrorIs(t, err, licenses.ErrLeaseNotFound)
+	})
+}
+
+func TestListLeases(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{
+			Strategy:          "fifo",
+			ExtendOnHeartbeat: true,
+		},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	poolName := "test-pool"
+	_, err := manager.AddLicense(ctx, &poolName, "license1.lic", "test_key_1")
+	assert.NoError(t, err)
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "test_key_2")
+	assert.NoError(t, err)
+	_, err = manager.AddLicense(ctx, nil, "license3.lic", "test_key_3")
+	assert.NoError(t, err)
+
+	_, err = manager.ClaimLicense(ctx, &poolName, "test_fingerprint_1")
+	assert.NoError(t, err)
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint_2")
+	assert.NoError(t, err)
+	_, err = manager.ClaimLicense(ctx, nil, "test_fingerprint_3")
+	assert.NoError(t, err)
+
+	leases, err := manager.ListLeases(ctx, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, leases, 3)
+
+	leases, err = manager.ListLeases(ctx, &poolName, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, leases, 1)
+	assert.Equal(t, "test_fingerprint_1", leases[0].Node.Fingerprint)
+
+	leases, err = manager.ListLeases(ctx, nil, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, leases, 1)
+	assert.Equal(t, "test_fingerprint_2", leases[0].Node.Fingerprint)
+}
+
+func TestCreatePool(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	pool, err := manager.CreatePool(ctx, "test-pool")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-pool", pool.Name)
+
+	pools, err := manager.GetPools(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pools, 1)
+
+	_, err = manager.CreatePool(ctx, "test-pool")
+	assert.ErrorIs(t, err, licenses.ErrPoolExists)
+}
+
+func TestDeletePool(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	poolName := "test-pool"
+	_, err := manager.AddLicense(ctx, &poolName, "license1.lic", "test_key")
+	assert.NoError(t, err)
+
+	t.Run("unknown pool", func(t *testing.T) {
+		err := manager.DeletePool(ctx, "unknown-pool")
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+	})
+
+	t.Run("non-empty pool", func(t *testing.T) {
+		err := manager.DeletePool(ctx, poolName)
+		assert.ErrorIs(t, err, licenses.ErrPoolNotEmpty)
+	})
+
+	t.Run("empty pool", func(t *testing.T) {
+		err := manager.RemoveLicense(ctx, &poolName, "license_test_key")
+		assert.NoError(t, err)
+
+		err = manager.DeletePool(ctx, poolName)
+		assert.NoError(t, err)
+
+		pools, err := manager.GetPools(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, pools)
+	})
+}
+
+func TestAPITokens(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	prod, dev := "prod", "dev"
+	_, err := manager.CreatePool(ctx, prod)
+	assert.NoError(t, err)
+	_, err = manager.CreatePool(ctx, dev)
+	assert.NoError(t, err)
+
+	prodToken, apiToken, err := manager.CreateAPIToken(ctx, &prod)
+	assert.NoError(t, err)
+	assert.NotNil(t, apiToken.PoolID)
+	assert.NotContains(t, apiToken.Digest, prodToken)
+	assert.Regexp(t, "^relay_[0-9a-f]{64}$", prodToken)
+
+	globalToken, _, err := manager.CreateAPIToken(ctx, nil)
+	assert.NoError(t, err)
+
+	t.Run("unknown pool", func(t *testing.T) {
+		unknown := "unknown"
+		_, _, err := manager.CreateAPIToken(ctx, &unknown)
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+	})
+
+	t.Run("matching pool", func(t *testing.T) {
+		token, err := manager.AuthenticateAPIToken(ctx, &prod, prodToken)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), token.Uses)
+		assert.NotNil(t, token.LastUsedAt)
+
+		token, err = manager.AuthenticateAPIToken(ctx, nil, globalToken)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), token.Uses)
+	})
+
+	t.Run("mismatched pool", func(t *testing.T) {
+		_, err := manager.AuthenticateAPIToken(ctx, &dev, prodToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenPoolMismatch)
+
+		_, err = manager.AuthenticateAPIToken(ctx, nil, prodToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenPoolMismatch)
+
+		_, err = manager.AuthenticateAPIToken(ctx, &prod, globalToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenPoolMismatch)
+
+		unknown := "unknown"
+		_, err = manager.AuthenticateAPIToken(ctx, &unknown, prodToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenPoolMismatch)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := manager.AuthenticateAPIToken(ctx, &prod, "relay_invalid")
+		assert.ErrorIs(t, err, licenses.ErrAPITokenInvalid)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		tokens, err := manager.ListAPITokens(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, tokens, 2)
+
+		// rejected attempts aren't counted as usage
+		assert.Equal(t, int64(1), tokens[0].Uses)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := manager.DeleteAPIToken(ctx, apiToken.ID)
+		assert.NoError(t, err)
+
+		_, err = manager.AuthenticateAPIToken(ctx, &prod, prodToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenInvalid)
+
+		err = manager.DeleteAPIToken(ctx, apiToken.ID)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenNotFound)
+	})
+
+	t.Run("delete pool", func(t *testing.T) {
+		devToken, _, err := manager.CreateAPIToken(ctx, &dev)
+		assert.NoError(t, err)
+
+		err = manager.DeletePool(ctx, dev)
+		assert.NoError(t, err)
+
+		_, err = manager.AuthenticateAPIToken(ctx, &dev, devToken)
+		assert.ErrorIs(t, err, licenses.ErrAPITokenInvalid)
+	})
+}
+
+func TestPoolCIDRs(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	prod, dev := "prod", "dev"
+	_, err := manager.CreatePool(ctx, prod)
+	assert.NoError(t, err)
+	_, err = manager.CreatePool(ctx, dev)
+	assert.NoError(t, err)
+
+	t.Run("unrestricted pool", func(t *testing.T) {
+		err := manager.CheckPoolAccess(ctx, &prod, net.ParseIP("192.0.2.1"))
+		assert.NoError(t, err)
+
+		err = manager.CheckPoolAccess(ctx, nil, net.ParseIP("192.0.2.1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		cidr, err := manager.AddPoolCIDR(ctx, prod, "10.20.1.2/16", licenses.CIDRActionAllow)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.20.0.0/16", cidr.Cidr)
+		assert.Equal(t, "allow", cidr.Action)
+
+		cidr, err = manager.AddPoolCIDR(ctx, prod, "10.20.5.7", licenses.CIDRActionDeny)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.20.5.7/32", cidr.Cidr)
+
+		_, err = manager.AddPoolCIDR(ctx, prod, "10.20.0.0/16", licenses.CIDRActionAllow)
+		assert.ErrorIs(t, err, licenses.ErrCIDRExists)
+
+		_, err = manager.AddPoolCIDR(ctx, prod, "10.20.0.0/33", licenses.CIDRActionAllow)
+		assert.ErrorIs(t, err, licenses.ErrBadCIDR)
+
+		_, err = manager.AddPoolCIDR(ctx, "unknown", "10.20.0.0/16", licenses.CIDRActionAllow)
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+
+		cidrs, err := manager.ListPoolCIDRs(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, cidrs, 2)
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		err := manager.CheckPoolAccess(ctx, &prod, net.ParseIP("10.20.1.1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		err := manager.CheckPoolAccess(ctx, &prod, net.ParseIP("192.0.2.1"))
+		assert.ErrorIs(t, err, licenses.ErrAccessDenied)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		// denied ranges take precedence over allowed ranges
+		err := manager.CheckPoolAccess(ctx, &prod, net.ParseIP("10.20.5.7"))
+		assert.ErrorIs(t, err, licenses.ErrAccessDenied)
+	})
+
+	t.Run("other pool", func(t *testing.T) {
+		err := manager.CheckPoolAccess(ctx, &dev, net.ParseIP("192.0.2.1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		events, err := manager.ListEvents(ctx, &prod, 0, 10)
+		assert.NoError(t, err)
+
+		// the pool's creation is logged under the pool itself
+		var types []string
+		for _, event := range events {
+			assert.Equal(t, "pool", event.EntityType)
+
+			types = append(types, event.EventType)
+		}
+
+		assert.Equal(t, []string{"pool.added", "pool.access_denied", "pool.access_denied"}, types)
+	})
+
+	t.Run("no ip", func(t *testing.T) {
+		err := manager.CheckPoolAccess(ctx, &prod, nil)
+		assert.ErrorIs(t, err, licenses.ErrAccessDenied)
+
+		// even when the pool only denies ranges
+		_, err = manager.AddPoolCIDR(ctx, dev, "198.51.100.0/24", licenses.CIDRActionDeny)
+		assert.NoError(t, err)
+
+		err = manager.CheckPoolAccess(ctx, &dev, nil)
+		assert.ErrorIs(t, err, licenses.ErrAccessDenied)
+
+		err = manager.RemovePoolCIDR(ctx, dev, "198.51.100.0/24", licenses.CIDRActionDeny)
+		assert.NoError(t, err)
+
+		// but only when the pool has cidrs
+		err = manager.CheckPoolAccess(ctx, &dev, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		err := manager.RemovePoolCIDR(ctx, prod, "10.20.5.7", licenses.CIDRActionDeny)
+		assert.NoError(t, err)
+
+		err = manager.CheckPoolAccess(ctx, &prod, net.ParseIP("10.20.5.7"))
+		assert.NoError(t, err)
+
+		err = manager.RemovePoolCIDR(ctx, prod, "10.20.5.7", licenses.CIDRActionDeny)
+		assert.ErrorIs(t, err, licenses.ErrCIDRNotFound)
+	})
+
+	t.Run("delete pool", func(t *testing.T) {
+		_, err := manager.AddPoolCIDR(ctx, dev, "192.0.2.0/24", licenses.CIDRActionDeny)
+		assert.NoError(t, err)
+
+		err = manager.DeletePool(ctx, dev)
+		assert.NoError(t, err)
+
+		cidrs, err := manager.ListPoolCIDRs(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, cidrs, 1)
+	})
+}
+
+func TestUpdatePool(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	_, err := manager.CreatePool(ctx, "prod")
+	assert.NoError(t, err)
+
+	lifo, bogus := "lifo", "bogus"
+
+	t.Run("unknown pool", func(t *testing.T) {
+		_, err := manager.UpdatePool(ctx, "unknown-pool", licenses.PoolSettings{Strategy: &lifo})
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		_, err := manager.UpdatePool(ctx, "prod", licenses.PoolSettings{Strategy: &bogus})
+		assert.ErrorIs(t, err, licenses.ErrBadStrategy)
+	})
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		ttl := time.Duration(0)
+
+		_, err := manager.UpdatePool(ctx, "prod", licenses.PoolSettings{TTL: &ttl})
+		assert.ErrorIs(t, err, licenses.ErrBadTTL)
+	})
+
+	t.Run("ttl below minimum", func(t *testing.T) {
+		ttl := 5 * time.Second
+
+		_, err := manager.UpdatePool(ctx, "prod", licenses.PoolSettings{TTL: &ttl})
+		assert.EqualError(t, err, "time-to-live value must be at least 30s")
+	})
+
+	t.Run("partial updates", func(t *testing.T) {
+		ttl := 8 * time.Hour
+
+		pool, err := manager.UpdatePool(ctx, "prod", licenses.PoolSettings{Strategy: &lifo, TTL: &ttl})
+		assert.NoError(t, err)
+		assert.Equal(t, "lifo", *pool.Strategy)
+		assert.Equal(t, int64(8*60*60), *pool.Ttl)
+		assert.Nil(t, pool.Heartbeat)
+
+		// settings that aren't given are left unchanged
+		heartbeat := false
+
+		pool, err = manager.UpdatePool(ctx, "prod", licenses.PoolSettings{Heartbeat: &heartbeat})
+		assert.NoError(t, err)
+		assert.Equal(t, "lifo", *pool.Strategy)
+		assert.Equal(t, int64(8*60*60), *pool.Ttl)
+		assert.False(t, *pool.Heartbeat)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		pool, err := manager.ResetPool(ctx, "prod")
+		assert.NoError(t, err)
+		assert.Nil(t, pool.Strategy)
+		assert.Nil(t, pool.Ttl)
+		assert.Nil(t, pool.Heartbeat)
+
+		_, err = manager.ResetPool(ctx, "unknown-pool")
+		assert.ErrorIs(t, err, licenses.ErrBadPool)
+	})
+
+	prod := "prod"
+
+	// updates are logged under the pool, so that they're streamed to its subscribers
+	events, err := manager.ListEvents(ctx, &prod, 0, 100)
+	assert.NoError(t, err)
+
+	var updated int
+	for _, event := range events {
+		if event.EventType == "pool.updated" {
+			updated++
+		}
+	}
+
+	assert.Equal(t, 3, updated)
+}
+
+func TestClaimLicense_PoolSettings(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate_" + filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	prod, dev, ci := "prod", "dev", "ci"
+
+	for _, pool := range []*string{&prod, &dev, &ci} {
+		for i := 1; i <= 2; i++ {
+			_, err := manager.AddLicense(ctx, pool, fmt.Sprintf("%s_license%d.lic", *pool, i), fmt.Sprintf("%s_key_%d", *pool, i))
+			assert.NoError(t, err)
+		}
+	}
+
+	_, err := dbConn.ExecContext(ctx, `UPDATE licenses SET created_at = strftime('%s', 'now', '-1 seconds') WHERE key LIKE '%_key_1'`)
+	assert.NoError(t, err)
+
+	lifo := "lifo"
+	ttl := 8 * time.Hour
+	heartbeat := false
+
+	_, err = manager.UpdatePool(ctx, prod, licenses.PoolSettings{Strategy: &lifo, TTL: &ttl})
+	assert.NoError(t, err)
+
+	_, err = manager.UpdatePool(ctx, ci, licenses.PoolSettings{Heartbeat: &heartbeat})
+	assert.NoError(t, err)
+
+	t.Run("strategy", func(t *testing.T) {
+		// prod hands out its newest license, while dev falls back to the default strategy
+		result, err := manager.ClaimLicense(ctx, &prod, "prod_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, "license_prod_key_2", result.License.Guid)
+
+		result, err = manager.ClaimLicense(ctx, &dev, "dev_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, "license_dev_key_1", result.License.Guid)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		lease, err := manager.GetLease(ctx, &prod, "prod_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, 8*time.Hour, lease.Node.LeaseTTL(time.Minute))
+
+		lease, err = manager.GetLease(ctx, &dev, "dev_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, lease.Node.LeaseTTL(time.Minute))
+
+		// a requested ttl takes precedence over the pool's ttl
+		result, err := manager.ClaimLicense(ctx, &prod, "prod_fingerprint_2", licenses.WithTTL(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour, result.Node.LeaseTTL(time.Minute))
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		result, err := manager.ClaimLicense(ctx, &ci, "ci_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+
+		// leases can't be extended in a pool with heartbeats disabled
+		result, err = manager.ClaimLicense(ctx, &ci, "ci_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, licenses.OperationStatusConflict, result.Status)
+
+		result, err = manager.ClaimLicense(ctx, &dev, "dev_fingerprint")
+		assert.NoError(t, err)
+		assert.Equal(t, licenses.OperationStatusExtended, result.Status)
+	})
+
+	t.Run("cull", func(t *testing.T) {
+		_, err := dbConn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-2 hours')`)
+		assert.NoError(t, err)
+
+		// prod's leases outlive the default ttl, and ci's leases never expire
+		nodes, err := manager.CullDeadNodes(ctx, time.Minute)
+		assert.NoError(t, err)
+		assert.Len(t, nodes, 2)
+
+		var culled []string
+		for _, node := range nodes {
+			culled = append(culled, node.Fingerprint)
+		}
+
+		assert.ElementsMatch(t, []string{"dev_fingerprint", "prod_fingerprint_2"}, culled)
+
+		lease, err := manager.GetLease(ctx, &ci, "ci_fingerprint")
+		assert.NoError(t, err)
+		assert.Nil(t, lease.Node.DeactivatedAt)
+
+		_, err = manager.GetLease(ctx, &prod, "prod_fingerprint")
+		assert.NoError(t, err)
+	})
+}
+
+func TestReserveLicense(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true, EnabledAudit: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate"), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	license, err := manager.AddLicense(ctx, nil, "license.lic", "test_key")
+	assert.NoError(t, err)
+
+	_, err = manager.ReserveLicense(ctx, license.Guid, "")
+	assert.ErrorIs(t, err, licenses.ErrBadFingerprint)
+
+	_, err = manager.ReserveLicense(ctx, "invalid_guid", "reserved_fingerprint")
+	assert.ErrorIs(t, err, licenses.ErrLicenseNotFound)
+
+	_, err = manager.UnreserveLicense(ctx, license.Guid)
+	assert.ErrorIs(t, err, licenses.ErrLicenseNotReserved)
+
+	reserved, err := manager.ReserveLicense(ctx, license.Guid, "reserved_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, "reserved_fingerprint", *reserved.ReservedFingerprint)
+
+	// the license is never handed to another node, even while it's free
+	result, err := manager.ClaimLicense(ctx, nil, "other_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusNoLicensesAvailable, result.Status)
+
+	result, err = manager.ClaimLicense(ctx, nil, "reserved_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+	assert.Equal(t, license.Guid, result.License.Guid)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "reserved_fingerprint")
+	assert.NoError(t, err)
+
+	unreserved, err := manager.UnreserveLicense(ctx, license.Guid)
+	assert.NoError(t, err)
+	assert.Nil(t, unreserved.ReservedFingerprint)
+
+	result, err = manager.ClaimLicense(ctx, nil, "other_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+
+	events, err := manager.ListEvents(ctx, nil, 0, 100)
+	assert.NoError(t, err)
+
+	var reservations []string
+	for _, event := range events {
+		if event.EventType == "license.reserved" || event.EventType == "license.unreserved" {
+			reservations = append(reservations, event.EventType)
+		}
+	}
+
+	assert.Equal(t, []string{"license.reserved", "license.unreserved"}, reservations)
+}
+
+func TestReserveLicense_Leased(t *testing.T) {
+	ctx := context.Background()
+	store, dbConn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(dbConn)
+
+	manager := licenses.NewManager(
+		&licenses.Config{Strategy: "fifo", ExtendOnHeartbeat: true},
+		func(filename string) ([]byte, error) {
+			return []byte("mock_certificate"), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	license, err := manager.AddLicense(ctx, nil, "license.lic", "test_key")
+	assert.NoError(t, err)
+
+	result, err := manager.ClaimLicense(ctx, nil, "leased_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, licenses.OperationStatusCreated, result.Status)
+
+	// a license leased to another node can't be reserved out from under it
+	_, err = manager.ReserveLicense(ctx, license.Guid, "reserved_fingerprint")
+	assert.ErrorIs(t, err, licenses.ErrLicenseLeased)
+
+	unreserved, err := manager.GetLicenseByGUID(ctx, nil, license.Guid)
+	assert.NoError(t, err)
+	assert.Nil(t, unreserved.ReservedFingerprint)
+
+	// but it can be reserved for the node it's leased to
+	reserved, err := manager.ReserveLicense(ctx, license.Guid, "leased_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, "leased_fingerprint", *reserved.ReservedFingerprint)
+
+	_, err = manager.ReleaseLicense(ctx, nil, "leased_fingerprint")
+	assert.NoError(t, err)
+
+	reserved, err = manager.ReserveLicense(ctx, license.Guid, "reserved_fingerprint")
+	assert.NoError(t, err)
+	assert.Equal(t, "reserved_fingerprint", *reserved.ReservedFingerprint)
+}
@@ -0,0 +1,137 @@
+package licenses
+
+import (
+	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+)
+
+// LeaseOptionFunc is a functional option for claiming a lease
+type LeaseOptionFunc func(*LeaseOptions)
+
+type LeaseOptions struct {
+	// ttl is the node's requested time-to-live, or nil to use the server default
+	ttl *time.Duration
+
+	// leaseToken is the token presented by the node, or nil to skip verification e.g.
+	// for administrative operations
+	leaseToken *string
+
+	// leaseID identifies one of the node's sub-leases, or is empty for its primary lease
+	leaseID string
+
+	// extendOnly only allows extending an existing lease, i.e. a new lease won't be claimed
+	extendOnly bool
+
+	// metadata is the node's reported metadata, or nil if it reported none
+	metadata *db.NodeMetadata
+}
+
+// TTL returns the requested time-to-live, or nil if none was requested
+func (o *LeaseOptions) TTL() *time.Duration {
+	return o.ttl
+}
+
+// WithTTL requests a time-to-live for the lease, which is persisted on the node so that
+// the reaper can cull it according to its own TTL rather than the server default
+func WithTTL(ttl time.Duration) LeaseOptionFunc {
+	return func(options *LeaseOptions) {
+		options.ttl = &ttl
+	}
+}
+
+// LeaseToken returns the presented lease token, or nil if none was presented
+func (o *LeaseOptions) LeaseToken() *string {
+	return o.leaseToken
+}
+
+// WithLeaseToken requires the node's current lease token to match the given token in
+// order to extend or release its lease
+func WithLeaseToken(token string) LeaseOptionFunc {
+	return func(options *LeaseOptions) {
+		options.leaseToken = &token
+	}
+}
+
+// LeaseID returns the sub-lease ID, or an empty string for the node's primary lease
+func (o *LeaseOptions) LeaseID() string {
+	return o.leaseID
+}
+
+// WithLeaseID acts on one of the node's sub-leases, e.g. for a node running several
+// licensed processes at once, each with its own heartbeat, expiry and lease token
+func WithLeaseID(id string) LeaseOptionFunc {
+	return func(options *LeaseOptions) {
+		options.leaseID = id
+	}
+}
+
+// ExtendOnly returns true if only existing leases should be extended
+func (o *LeaseOptions) ExtendOnly() bool {
+	return o.extendOnly
+}
+
+// WithExtendOnly extends the node's existing lease, but reports no licenses available
+// rather than claiming a new lease, e.g. so that a claim can't jump a wait queue
+func WithExtendOnly() LeaseOptionFunc {
+	return func(options *LeaseOptions) {
+		options.extendOnly = true
+	}
+}
+
+// Metadata returns the node's reported metadata, or nil if none was reported
+func (o *LeaseOptions) Metadata() *db.NodeMetadata {
+	return o.metadata
+}
+
+// WithMetadata stores the node's reported metadata, e.g. its hostname, on the node when
+// claiming a new lease or extending an existing lease
+func WithMetadata(metadata *db.NodeMetadata) LeaseOptionFunc {
+	return func(options *LeaseOptions) {
+		options.metadata = metadata
+	}
+}
+
+// ApplyLeaseOptions applies the given options, e.g. to inspect them in a fake manager
+func ApplyLeaseOptions(fns ...LeaseOptionFunc) *LeaseOptions {
+	options := &LeaseOptions{}
+
+	for _, fn := range fns {
+		fn(options)
+	}
+
+	return options
+}
+
+// ListOptionFunc is a functional option for listing leases and events
+type ListOptionFunc func(*ListOptions)
+
+type ListOptions struct {
+	// withoutPool lists only leases or events without a pool when no pool is given,
+	// rather than across all pools
+	withoutPool bool
+}
+
+// WithoutPool returns true if only leases or events without a pool should be listed
+func (o *ListOptions) WithoutPool() bool {
+	return o.withoutPool
+}
+
+// WithoutPool lists only leases or events without a pool when no pool is given, e.g. for
+// an API token that isn't scoped to a pool, rather than across all pools
+func WithoutPool() ListOptionFunc {
+	return func(options *ListOptions) {
+		options.withoutPool = true
+	}
+}
+
+// ApplyListOptions applies the given options, e.g. to inspect them in a fake manager
+func ApplyListOptions(fns ...ListOptionFunc) *ListOptions {
+	options := &ListOptions{}
+
+	for _, fn := range fns {
+		fn(options)
+	}
+
+	return options
+}
@@ -0,0 +1,188 @@
+package licenses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+// MinTTL is the shortest time-to-live allowed for a lease, shared by the server and pools
+const MinTTL = 30 * time.Second
+
+var (
+	ErrBadStrategy = errors.New("invalid strategy")
+	ErrBadTTL      = fmt.Errorf("time-to-live value must be at least %s", MinTTL)
+)
+
+// PoolSettings override the server's default strategy, TTL and heartbeat setting for a
+// pool, where nil settings are left unchanged when updating a pool
+type PoolSettings struct {
+	Strategy  *string
+	TTL       *time.Duration
+	Heartbeat *bool
+}
+
+func isValidStrategy(strategy string) bool {
+	switch strategy {
+	case "fifo", "lifo", "rand", "affinity":
+		return true
+	default:
+		return false
+	}
+}
+
+// UpdatePool updates a pool's settings, leaving settings that aren't given unchanged
+func (m *manager) UpdatePool(ctx context.Context, name string, settings PoolSettings) (*db.Pool, error) {
+	logger.Debug("starting to update pool", "poolName", name)
+
+	if settings.Strategy != nil && !isValidStrategy(*settings.Strategy) {
+		return nil, fmt.Errorf("strategy %s: %w", *settings.Strategy, ErrBadStrategy)
+	}
+
+	if settings.TTL != nil && *settings.TTL < MinTTL {
+		return nil, ErrBadTTL
+	}
+
+	tx, err := m.store.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	pool, err := m.resolvePoolWithTx(ctx, tx, &name)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, ttl, heartbeat := pool.Strategy, poolTTL(pool), pool.Heartbeat
+	if settings.Strategy != nil {
+		strategy = settings.Strategy
+	}
+
+	if settings.TTL != nil {
+		ttl = settings.TTL
+	}
+
+	if settings.Heartbeat != nil {
+		heartbeat = settings.Heartbeat
+	}
+
+	pool, err = tx.UpdatePoolSettingsByID(ctx, pool.ID, strategy, ttl, heartbeat)
+	if err != nil {
+		logger.Error("failed to update pool", "poolName", name, "error", err)
+
+		return nil, fmt.Errorf("failed to update pool: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if m.config.EnabledAudit {
+		if err := m.store.InsertAuditLog(ctx, pool, db.EventTypePoolUpdated, db.EntityTypePool, pool.ID); err != nil {
+			logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
+		}
+	}
+
+	logger.Debug("updated pool successfully", "poolName", name)
+
+	return pool, nil
+}
+
+// ResetPool resets a pool's settings, so that the pool falls back to the server's defaults
+func (m *manager) ResetPool(ctx context.Context, name string) (*db.Pool, error) {
+	logger.Debug("starting to reset pool", "poolName", name)
+
+	pool, err := m.resolvePool(ctx, &name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err = m.store.UpdatePoolSettingsByID(ctx, pool.ID, nil, nil, nil)
+	if err != nil {
+		logger.Error("failed to reset pool", "poolName", name, "error", err)
+
+		return nil, fmt.Errorf("failed to reset pool: %w", err)
+	}
+
+	if m.config.EnabledAudit {
+		if err := m.store.InsertAuditLog(ctx, pool, db.EventTypePoolUpdated, db.EntityTypePool, pool.ID); err != nil {
+			logger.Warn("failed to insert audit log", "poolID", pool.ID, "poolName", pool.Name, "error", err)
+		}
+	}
+
+	logger.Debug("reset pool successfully", "poolName", name)
+
+	return pool, nil
+}
+
+// strategyFor returns the pool's strategy, falling back to the default strategy
+func (m *manager) strategyFor(pool *db.Pool) string {
+	if pool != nil && pool.Strategy != nil {
+		return *pool.Strategy
+	}
+
+	return m.config.Strategy
+}
+
+// affinityFallback returns the strategy used by the affinity strategy when a node has no
+// previously held license to claim, defaulting to fifo
+func (m *manager) affinityFallback() string {
+	if m.config.AffinityFallback == "" {
+		return "fifo"
+	}
+
+	return m.config.AffinityFallback
+}
+
+// heartbeatFor returns whether or not the pool's leases can be extended by heartbeats,
+// falling back to the default
+func (m *manager) heartbeatFor(pool *db.Pool) bool {
+	if pool != nil && pool.Heartbeat != nil {
+		return *pool.Heartbeat
+	}
+
+	return m.config.ExtendOnHeartbeat
+}
+
+// poolTTL returns the pool's TTL, or nil when the pool falls back to the default TTL
+func poolTTL(pool *db.Pool) *time.Duration {
+	if pool == nil || pool.Ttl == nil {
+		return nil
+	}
+
+	ttl := time.Duration(*pool.Ttl) * time.Second
+
+	return &ttl
+}
+
+// poolIndex maps pool IDs to their pools
+type poolIndex map[int64]*db.Pool
+
+// lookup returns the pool with the given ID, or nil for the global pool
+func (idx poolIndex) lookup(id *int64) *db.Pool {
+	if id == nil {
+		return nil
+	}
+
+	return idx[*id]
+}
+
+// poolsByID returns an index of every pool by its ID
+func (m *manager) poolsByID(ctx context.Context) (poolIndex, error) {
+	pools, err := m.store.GetPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := make(poolIndex, len(pools))
+	for i := range pools {
+		idx[pools[i].ID] = &pools[i]
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,109 @@
+package licenses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+var (
+	ErrBadFingerprint     = errors.New("invalid fingerprint")
+	ErrLicenseNotReserved = errors.New("license is not reserved")
+	ErrLicenseLeased      = errors.New("license is leased to another node")
+)
+
+// ReserveLicense reserves a license for a node's fingerprint, so that the license is
+// only ever claimed by that node, replacing any existing reservation for the license.
+// A license leased to another node can't be reserved until its lease is released.
+func (m *manager) ReserveLicense(ctx context.Context, guid string, fingerprint string) (*db.License, error) {
+	logger.Debug("starting to reserve license", "licenseGuid", guid, "nodeFingerprint", fingerprint)
+
+	if fingerprint == "" {
+		return nil, fmt.Errorf("fingerprint must not be empty: %w", ErrBadFingerprint)
+	}
+
+	license, err := m.GetLicenseByGUID(ctx, nil, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if license.NodeID != nil {
+		node, err := m.store.GetNodeByID(ctx, *license.NodeID)
+		if err != nil {
+			logger.Error("failed to fetch license node", "licenseGuid", guid, "error", err)
+
+			return nil, fmt.Errorf("failed to fetch license node: %w", err)
+		}
+
+		if node.Fingerprint != fingerprint {
+			return nil, fmt.Errorf("license %s: %w", guid, ErrLicenseLeased)
+		}
+	}
+
+	license, err = m.store.SetLicenseReservedFingerprintByID(ctx, license.ID, &fingerprint)
+	if err != nil {
+		logger.Error("failed to reserve license", "licenseGuid", guid, "nodeFingerprint", fingerprint, "error", err)
+
+		return nil, fmt.Errorf("failed to reserve license: %w", err)
+	}
+
+	m.insertReservationAuditLog(ctx, license, db.EventTypeLicenseReserved)
+
+	logger.Debug("reserved license successfully", "licenseGuid", guid, "nodeFingerprint", fingerprint)
+
+	return license, nil
+}
+
+// UnreserveLicense removes a license's reservation, so that it can be claimed by any node
+func (m *manager) UnreserveLicense(ctx context.Context, guid string) (*db.License, error) {
+	logger.Debug("starting to unreserve license", "licenseGuid", guid)
+
+	license, err := m.GetLicenseByGUID(ctx, nil, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if license.ReservedFingerprint == nil {
+		return nil, fmt.Errorf("license %s: %w", guid, ErrLicenseNotReserved)
+	}
+
+	license, err = m.store.SetLicenseReservedFingerprintByID(ctx, license.ID, nil)
+	if err != nil {
+		logger.Error("failed to unreserve license", "licenseGuid", guid, "error", err)
+
+		return nil, fmt.Errorf("failed to unreserve license: %w", err)
+	}
+
+	m.insertReservationAuditLog(ctx, license, db.EventTypeLicenseUnreserved)
+
+	logger.Debug("unreserved license successfully", "licenseGuid", guid)
+
+	return license, nil
+}
+
+// insertReservationAuditLog logs a change to a license's reservation, under the license's
+// pool so that the event is streamed to the pool's subscribers
+func (m *manager) insertReservationAuditLog(ctx context.Context, license *db.License, eventType db.EventTypeId) {
+	if !m.config.EnabledAudit {
+		return
+	}
+
+	var pool *db.Pool
+	if license.PoolID != nil {
+		p, err := m.store.GetPoolByID(ctx, *license.PoolID)
+		if err != nil {
+			logger.Warn("failed to fetch license pool", "licenseGuid", license.Guid, "error", err)
+
+			return
+		}
+
+		pool = p
+	}
+
+	if err := m.store.InsertAuditLog(ctx, pool, eventType, db.EntityTypeLicense, license.ID); err != nil {
+		logger.Warn("failed to insert audit log", "licenseGuid", license.Guid, "error", err)
+	}
+}
@@ -0,0 +1,52 @@
+package licenses
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/keygen-sh/keygen-relay/internal/db"
+)
+
+var ErrLeaseTokenMismatch = errors.New("lease token mismatch")
+
+// leaseTokenLength is the number of random bytes in a lease token
+const leaseTokenLength = 32
+
+// newLeaseToken generates an opaque lease token, returning the token along with its
+// digest, which is the only form the token is ever stored in
+func newLeaseToken() (string, string, error) {
+	b := make([]byte, leaseTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate lease token: %w", err)
+	}
+
+	token := hex.EncodeToString(b)
+
+	return token, tokenDigest(token), nil
+}
+
+// tokenDigest returns the sha256 digest of a token, used for lease and api tokens
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyLeaseToken ensures the given token matches the node's current lease. Nodes that
+// leased before lease tokens existed have no digest and are allowed through.
+func verifyLeaseToken(node *db.Node, token string) error {
+	if node.LeaseTokenDigest == nil {
+		return nil
+	}
+
+	digest := tokenDigest(token)
+	if subtle.ConstantTimeCompare([]byte(digest), []byte(*node.LeaseTokenDigest)) != 1 {
+		return fmt.Errorf("node %s: %w", node.Fingerprint, ErrLeaseTokenMismatch)
+	}
+
+	return nil
+}
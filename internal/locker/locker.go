@@ -14,14 +14,15 @@ import (
 // locks Relay to a specific machine, depending on provided attributes. Relay will
 // error on mismatch, e.g. underlying IP address is different than expected IP.
 var (
-	PublicKey     string // required
-	Fingerprint   string // required
-	Platform      string // optional
-	Hostname      string // optional
-	IP            string // optional
-	Addr          string // optional
-	Port          string // optional
-	SigningSecret string // optional
+	PublicKey      string // required
+	Fingerprint    string // required
+	Platform       string // optional
+	Hostname       string // optional
+	IP             string // optional
+	Addr           string // optional
+	Port           string // optional
+	SigningSecret  string // optional
+	SigningSecrets string // optional e.g. kid=secret,kid=secret
 )
 
 func init() {
@@ -69,6 +70,12 @@ func LockedSigningSecret() bool {
 	return SigningSecret != ""
 }
 
+// LockedSigningSecrets returns a boolean whether or not Relay's signing secrets with key IDs
+// are locked
+func LockedSigningSecrets() bool {
+	return SigningSecrets != ""
+}
+
 // Unlock attempts to unlock Relay via a machine file and license key using the
 // current machine's fingerprint
 func Unlock(config Config) (*keygen.MachineFileDataset, error) {
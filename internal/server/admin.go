@@ -0,0 +1,454 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+// maxLicenseUploadSize is the maximum size of a multipart license upload
+const maxLicenseUploadSize = 10 << 20
+
+type LicenseResponse struct {
+	ID                  string  `json:"id"`
+	Pool                *string `json:"pool"`
+	Claims              int64   `json:"claims"`
+	NodeID              *int64  `json:"node_id"`
+	ReservedFingerprint *string `json:"reserved_fingerprint"`
+	LastClaimedAt       *int64  `json:"last_claimed_at"`
+	LastReleasedAt      *int64  `json:"last_released_at"`
+	CreatedAt           int64   `json:"created_at"`
+}
+
+type ListLicensesResponse struct {
+	Licenses []LicenseResponse `json:"licenses"`
+}
+
+type PoolResponse struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type ListPoolsResponse struct {
+	Pools []PoolResponse `json:"pools"`
+}
+
+type CreatePoolRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *handler) AdminListLicenses(w http.ResponseWriter, r *http.Request) {
+	var pool *string
+	if p := r.URL.Query().Get("pool"); p != "" {
+		pool = &p
+	}
+
+	list, err := h.manager.ListLicenses(r.Context(), pool)
+	if err != nil && !errors.Is(err, licenses.ErrNoLicenses) {
+		if errors.Is(err, licenses.ErrBadPool) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid pool"})
+			return
+		}
+
+		logger.Error("failed to list licenses", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to list licenses"})
+		return
+	}
+
+	pools, err := h.poolsByID(r)
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to list licenses"})
+		return
+	}
+
+	resp := ListLicensesResponse{
+		Licenses: make([]LicenseResponse, 0, len(list)),
+	}
+
+	for _, license := range list {
+		resp.Licenses = append(resp.Licenses, licenseResponse(license, pools))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *handler) AdminAddLicense(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxLicenseUploadSize)
+
+	if err := r.ParseMultipartForm(maxLicenseUploadSize); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid multipart form"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	key := strings.TrimSpace(r.FormValue("key"))
+	if key == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "key is required"})
+		return
+	}
+
+	var pool *string
+	if p := r.FormValue("pool"); p != "" {
+		pool = &p
+	}
+
+	// the manager reads license files from disk, so we spool the upload to a temp file
+	path, err := spoolLicenseFile(file)
+	if err != nil {
+		logger.Error("failed to spool license file", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to add license"})
+		return
+	}
+	defer os.Remove(path)
+
+	license, err := h.manager.AddLicense(r.Context(), pool, path, key)
+	if err != nil {
+		logger.Warn("failed to add license", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.queue.Notify(pool)
+
+	pools, err := h.poolsByID(r)
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to add license"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(licenseResponse(*license, pools))
+}
+
+func (h *handler) AdminGetLicense(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	license, err := h.manager.GetLicenseByGUID(r.Context(), nil, id)
+	if err != nil {
+		if errors.Is(err, licenses.ErrLicenseNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "license not found"})
+			return
+		}
+
+		logger.Error("failed to get license", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to get license"})
+		return
+	}
+
+	pools, err := h.poolsByID(r)
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to get license"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(licenseResponse(*license, pools))
+}
+
+func (h *handler) AdminRemoveLicense(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	license, err := h.manager.GetLicenseByGUID(r.Context(), nil, id)
+	if err != nil {
+		if errors.Is(err, licenses.ErrLicenseNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "license not found"})
+			return
+		}
+
+		logger.Error("failed to get license", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to remove license"})
+		return
+	}
+
+	pool, err := h.poolName(r.Context(), license.PoolID)
+	if err != nil {
+		logger.Error("failed to get pool", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to remove license"})
+		return
+	}
+
+	if err := h.manager.RemoveLicense(r.Context(), pool, id); err != nil {
+		logger.Error("failed to remove license", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to remove license"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) AdminListPools(w http.ResponseWriter, r *http.Request) {
+	pools, err := h.manager.GetPools(r.Context())
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to list pools"})
+		return
+	}
+
+	resp := ListPoolsResponse{
+		Pools: make([]PoolResponse, 0, len(pools)),
+	}
+
+	for _, pool := range pools {
+		resp.Pools = append(resp.Pools, poolResponse(pool))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *handler) AdminCreatePool(w http.ResponseWriter, r *http.Request) {
+	var req CreatePoolRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "name is required"})
+		return
+	}
+
+	pool, err := h.manager.CreatePool(r.Context(), name)
+	if err != nil {
+		if errors.Is(err, licenses.ErrPoolExists) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "pool already exists"})
+			return
+		}
+
+		logger.Error("failed to create pool", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to create pool"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(poolResponse(*pool))
+}
+
+func (h *handler) AdminDeletePool(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["pool"]
+
+	if err := h.manager.DeletePool(r.Context(), name); err != nil {
+		switch {
+		case errors.Is(err, licenses.ErrBadPool):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "pool not found"})
+		case errors.Is(err, licenses.ErrPoolNotEmpty):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "pool is not empty"})
+		default:
+			logger.Error("failed to delete pool", "error", err)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to delete pool"})
+		}
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminReleaseLicense forcefully releases a node's lease, regardless of the pool it was
+// leased from, e.g. to free up a license held by a node that is known to be dead
+func (h *handler) AdminReleaseLicense(w http.ResponseWriter, r *http.Request) {
+	fingerprint := mux.Vars(r)["fingerprint"]
+	leaseID := mux.Vars(r)["lease_id"]
+
+	lease, err := h.manager.GetLease(r.Context(), nil, fingerprint, licenses.WithLeaseID(leaseID))
+	if err != nil {
+		if errors.Is(err, licenses.ErrLeaseNotFound) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "lease not found"})
+			return
+		}
+
+		logger.Error("failed to get node", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to release license"})
+		return
+	}
+
+	pool, err := h.poolName(r.Context(), lease.License.PoolID)
+	if err != nil {
+		logger.Error("failed to get pool", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to release license"})
+		return
+	}
+
+	result, err := h.manager.ReleaseLicense(r.Context(), pool, fingerprint, licenses.WithLeaseID(leaseID))
+	if err != nil {
+		logger.Error("failed to release license", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to release license"})
+		return
+	}
+
+	h.metrics.ObserveRelease(pool, result.Status)
+
+	switch result.Status {
+	case licenses.OperationStatusSuccess:
+		logger.Info("license forcefully released", "nodeFingerprint", fingerprint, "leaseId", leaseID, "licenseGuid", lease.License.Guid)
+
+		h.queue.Notify(pool)
+
+		w.WriteHeader(http.StatusNoContent)
+	case licenses.OperationStatusNotFound:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "lease not found"})
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unknown release status"})
+	}
+}
+
+// poolName resolves a pool ID to its name, returning nil for the global pool
+func (h *handler) poolName(ctx context.Context, id *int64) (*string, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	pool, err := h.manager.GetPoolByID(ctx, *id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pool.Name, nil
+}
+
+func spoolLicenseFile(r io.Reader) (string, error) {
+	f, err := os.CreateTemp("", "relay-*.lic")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+func licenseResponse(license db.License, pools map[int64]db.Pool) LicenseResponse {
+	resp := LicenseResponse{
+		ID:                  license.Guid,
+		Claims:              license.Claims,
+		NodeID:              license.NodeID,
+		ReservedFingerprint: license.ReservedFingerprint,
+		LastClaimedAt:       license.LastClaimedAt,
+		LastReleasedAt:      license.LastReleasedAt,
+		CreatedAt:           license.CreatedAt,
+	}
+
+	if license.PoolID != nil {
+		if pool, ok := pools[*license.PoolID]; ok {
+			resp.Pool = &pool.Name
+		}
+	}
+
+	return resp
+}
+
+func poolResponse(pool db.Pool) PoolResponse {
+	return PoolResponse{
+		ID:        pool.ID,
+		Name:      pool.Name,
+		CreatedAt: pool.CreatedAt,
+	}
+}
@@ -0,0 +1,447 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminRouter(cfg *server.Config, manager *testutils.FakeManager) *mux.Router {
+	srv := testutils.NewMockServer(cfg, manager)
+	handler := server.NewHandler(srv)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	return router
+}
+
+func newAdminConfig() *server.Config {
+	cfg := server.NewConfig()
+	token := "admin_secret"
+	cfg.AdminToken = &token
+
+	return cfg
+}
+
+func TestAdmin_Disabled(t *testing.T) {
+	router := newAdminRouter(server.NewConfig(), &testutils.FakeManager{})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/licenses", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "admin api is disabled")
+}
+
+func TestAdmin_Unauthorized(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{})
+
+	for _, header := range []string{"", "Bearer wrong_secret", "admin_secret", "Basic admin_secret"} {
+		t.Run(fmt.Sprintf("authorization=%q", header), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/licenses", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Equal(t, `Bearer realm="relay"`, rr.Header().Get("WWW-Authenticate"))
+			assert.Contains(t, rr.Body.String(), "unauthorized")
+		})
+	}
+}
+
+func TestAdminListLicenses_Success(t *testing.T) {
+	poolID := int64(1)
+	nodeID := int64(7)
+
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		ListLicensesFn: func(ctx context.Context, pool *string) ([]db.License, error) {
+			assert.Equal(t, "prod", *pool)
+
+			return []db.License{
+				{Guid: "license_1", PoolID: &poolID, NodeID: &nodeID, Claims: 3, File: []byte("secret_file"), Key: "secret_key"},
+			}, nil
+		},
+		GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+			return []db.Pool{{ID: poolID, Name: "prod"}}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/licenses?pool=prod", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "secret_key")
+
+	var resp server.ListLicensesResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Licenses, 1)
+	assert.Equal(t, "license_1", resp.Licenses[0].ID)
+	assert.Equal(t, "prod", *resp.Licenses[0].Pool)
+	assert.Equal(t, int64(3), resp.Licenses[0].Claims)
+	assert.Equal(t, nodeID, *resp.Licenses[0].NodeID)
+}
+
+func TestAdminListLicenses_InvalidPool(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		ListLicensesFn: func(ctx context.Context, pool *string) ([]db.License, error) {
+			return nil, licenses.ErrBadPool
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/licenses?pool=unknown", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid pool")
+}
+
+func TestAdminAddLicense_Success(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
+			cert, err := os.ReadFile(filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, "test_license_file", string(cert))
+			assert.Equal(t, "test_license_key", key)
+			assert.Equal(t, "prod", *pool)
+
+			return &db.License{Guid: "license_1"}, nil
+		},
+	})
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "license.lic")
+	_, _ = part.Write([]byte("test_license_file"))
+	_ = form.WriteField("key", "test_license_key")
+	_ = form.WriteField("pool", "prod")
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/licenses", body)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var resp server.LicenseResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "license_1", resp.ID)
+}
+
+func TestAdminAddLicense_Concurrent(t *testing.T) {
+	store, conn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(conn)
+
+	// each connection to an in-memory database is its own database
+	conn.SetMaxOpenConns(1)
+
+	manager := licenses.NewManager(licenses.NewConfig(), os.ReadFile, func(cert []byte) licenses.LicenseVerifier {
+		return &testutils.FakeLicenseVerifier{}
+	})
+	manager.AttachStore(*store)
+
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		AddLicenseFn: manager.AddLicense,
+	})
+
+	// licenses are verified concurrently, so this must pass under -race
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			body := &bytes.Buffer{}
+			form := multipart.NewWriter(body)
+			part, _ := form.CreateFormFile("file", "license.lic")
+			_, _ = fmt.Fprintf(part, "test_license_file_%d", i)
+			_ = form.WriteField("key", fmt.Sprintf("test_license_key_%d", i))
+			_ = form.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/licenses", body)
+			req.Header.Set("Authorization", "Bearer admin_secret")
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		}()
+	}
+
+	wg.Wait()
+
+	added, err := manager.ListLicenses(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Len(t, added, 10)
+}
+
+func TestAdminAddLicense_MissingKey(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{})
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "license.lic")
+	_, _ = part.Write([]byte("test_license_file"))
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/licenses", body)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "key is required")
+}
+
+func TestAdminAddLicense_VerificationFailed(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		AddLicenseFn: func(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
+			return nil, fmt.Errorf("license verification failed: %w", fmt.Errorf("bad signature"))
+		},
+	})
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "license.lic")
+	_, _ = part.Write([]byte("test_license_file"))
+	_ = form.WriteField("key", "test_license_key")
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/licenses", body)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "license verification failed")
+}
+
+func TestAdminGetLicense_NotFound(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		GetLicenseByGUIDFn: func(ctx context.Context, pool *string, id string) (*db.License, error) {
+			return nil, fmt.Errorf("license %s: %w", id, licenses.ErrLicenseNotFound)
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/licenses/unknown", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "license not found")
+}
+
+func TestAdminRemoveLicense_Success(t *testing.T) {
+	poolID := int64(1)
+
+	var removed bool
+
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		GetLicenseByGUIDFn: func(ctx context.Context, pool *string, id string) (*db.License, error) {
+			return &db.License{Guid: id, PoolID: &poolID}, nil
+		},
+		GetPoolByIDFn: func(ctx context.Context, id int64) (*db.Pool, error) {
+			return &db.Pool{ID: id, Name: "prod"}, nil
+		},
+		RemoveLicenseFn: func(ctx context.Context, pool *string, id string) error {
+			assert.Equal(t, "prod", *pool)
+			assert.Equal(t, "license_1", id)
+
+			removed = true
+
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/licenses/license_1", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.True(t, removed)
+}
+
+func TestAdminListPools_Success(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+			return []db.Pool{{ID: 1, Name: "prod"}, {ID: 2, Name: "dev"}}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/pools", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp server.ListPoolsResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Pools, 2)
+	assert.Equal(t, "prod", resp.Pools[0].Name)
+	assert.Equal(t, "dev", resp.Pools[1].Name)
+}
+
+func TestAdminCreatePool(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		expected int
+	}{
+		{name: "success", body: `{"name":"prod"}`, expected: http.StatusCreated},
+		{name: "missing name", body: `{}`, expected: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, expected: http.StatusBadRequest},
+		{name: "conflict", body: `{"name":"prod"}`, err: licenses.ErrPoolExists, expected: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+				CreatePoolFn: func(ctx context.Context, name string) (*db.Pool, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+
+					return &db.Pool{ID: 1, Name: name}, nil
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/pools", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer admin_secret")
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestAdminDeletePool(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", expected: http.StatusNoContent},
+		{name: "not found", err: licenses.ErrBadPool, expected: http.StatusNotFound},
+		{name: "not empty", err: licenses.ErrPoolNotEmpty, expected: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+				DeletePoolFn: func(ctx context.Context, name string) error {
+					assert.Equal(t, "prod", name)
+
+					return tt.err
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/admin/pools/prod", nil)
+			req.Header.Set("Authorization", "Bearer admin_secret")
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestAdminReleaseLicense_Success(t *testing.T) {
+	poolID := int64(1)
+
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+			assert.Nil(t, pool)
+
+			return &db.Lease{
+				Node:    db.Node{Fingerprint: fingerprint},
+				License: db.License{Guid: "license_1", PoolID: &poolID},
+			}, nil
+		},
+		GetPoolByIDFn: func(ctx context.Context, id int64) (*db.Pool, error) {
+			return &db.Pool{ID: id, Name: "prod"}, nil
+		},
+		ReleaseLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+			assert.Equal(t, "prod", *pool)
+			assert.Equal(t, "test_fingerprint", fingerprint)
+
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/nodes/test_fingerprint", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
+
+func TestAdminReleaseLicense_NotFound(t *testing.T) {
+	router := newAdminRouter(newAdminConfig(), &testutils.FakeManager{
+		GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+			return nil, fmt.Errorf("node %s: %w", fingerprint, licenses.ErrLeaseNotFound)
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/admin/nodes/test_fingerprint", nil)
+	req.Header.Set("Authorization", "Bearer admin_secret")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "lease not found")
+}
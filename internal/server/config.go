@@ -1,8 +1,14 @@
 package server
 
 import (
+	"crypto/ed25519"
 	"errors"
+	"net"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
 )
 
 type StrategyType string
@@ -11,6 +17,7 @@ const (
 	LIFO      StrategyType = "lifo"
 	FIFO      StrategyType = "fifo"
 	RandOrder StrategyType = "rand"
+	Affinity  StrategyType = "affinity"
 )
 
 func (e *StrategyType) String() string {
@@ -24,7 +31,7 @@ func (e *StrategyType) Set(v string) error {
 		return nil
 	}
 
-	return errors.New(`must be one of "lifo", "fifo", or "rand"`)
+	return errors.New(`must be one of "lifo", "fifo", "rand", or "affinity"`)
 }
 
 func (e *StrategyType) Type() string {
@@ -33,22 +40,113 @@ func (e *StrategyType) Type() string {
 
 func isValidStrategy(v string) bool {
 	switch StrategyType(v) {
-	case LIFO, FIFO, RandOrder:
+	case LIFO, FIFO, RandOrder, Affinity:
 		return true
 	default:
 		return false
 	}
 }
 
+// TTLBounds are the minimum and maximum lease TTL a node may request
+type TTLBounds struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Clamp returns the given TTL clamped to the bounds
+func (b TTLBounds) Clamp(ttl time.Duration) time.Duration {
+	return min(max(ttl, b.Min), b.Max)
+}
+
+// SigningSecret is an HMAC signing secret identified by a key ID
+type SigningSecret struct {
+	KeyID  string
+	Secret string
+}
+
 type Config struct {
 	ServerAddr       string
 	ServerPort       int
 	EnabledHeartbeat bool
 	TTL              time.Duration
+	MinTTL           time.Duration
+	MaxTTL           time.Duration
 	Strategy         StrategyType
+	AffinityFallback StrategyType
 	CullInterval     time.Duration
+	MaxWait          time.Duration
+	ShutdownTimeout  time.Duration
+	MaxLeasesPerNode int
 	Pool             *string
 	SigningSecret    *string
+	AdminToken       *string
+	PublicKey        string
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSClientCAFile  string
+
+	// GRPCPort is the port to serve the gRPC API on, where 0 disables the gRPC API
+	GRPCPort int
+
+	// SocketMode and SocketOwner e.g. relay:relay set the file mode and owner of the unix
+	// socket when bound to a unix:// address, where zero values leave them unchanged
+	SocketMode  os.FileMode
+	SocketOwner string
+
+	// SigningSecrets are HMAC secrets identified by a key ID, in addition to or instead of
+	// the SigningSecret, where each active secret signs every response
+	SigningSecrets []SigningSecret
+
+	// SigningKey is an Ed25519 private key for signing responses, in addition to or
+	// instead of the SigningSecret
+	SigningKey ed25519.PrivateKey
+
+	// PoolTTLBounds overrides the TTL bounds for individual pools
+	PoolTTLBounds map[string]TTLBounds
+
+	// FingerprintRateLimit and IPRateLimit throttle requests per node fingerprint and per
+	// client IP address, respectively
+	FingerprintRateLimit RateLimit
+	IPRateLimit          RateLimit
+
+	// ForwardedHeader is a header e.g. X-Forwarded-For containing the client's IP address,
+	// which is only trusted for requests from one of the TrustedProxies
+	ForwardedHeader string
+	TrustedProxies  []*net.IPNet
+
+	// RequireAPITokens requires claims and releases to present a pool-scoped API token
+	RequireAPITokens bool
+
+	// TLSClientFingerprint binds node fingerprints to the subject of the client certificate
+	TLSClientFingerprint bool
+}
+
+// TLSEnabled returns true if the server is configured to serve over TLS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// UnixSocket returns the path of the unix socket to bind to, and whether or not the
+// server is bound to a unix socket, e.g. unix:///run/relay.sock, rather than a TCP port
+func (c *Config) UnixSocket() (string, bool) {
+	return strings.CutPrefix(c.ServerAddr, "unix://")
+}
+
+// TTLBoundsFor returns the TTL bounds for a pool, falling back to the server's bounds,
+// where a zero max TTL means nodes cannot request a TTL longer than the default
+func (c *Config) TTLBoundsFor(pool *string) TTLBounds {
+	if pool != nil {
+		if bounds, ok := c.PoolTTLBounds[*pool]; ok {
+			return bounds
+		}
+	}
+
+	bounds := TTLBounds{Min: c.MinTTL, Max: c.MaxTTL}
+	if bounds.Max == 0 {
+		bounds.Max = max(c.TTL, bounds.Min)
+	}
+
+	return bounds
 }
 
 func NewConfig() *Config {
@@ -56,8 +154,13 @@ func NewConfig() *Config {
 		ServerAddr:       "0.0.0.0",
 		ServerPort:       6349,
 		TTL:              1 * time.Minute,
+		MinTTL:           licenses.MinTTL,
 		EnabledHeartbeat: true,
 		Strategy:         FIFO,
+		AffinityFallback: FIFO,
 		CullInterval:     15 * time.Second,
+		MaxWait:          5 * time.Minute,
+		ShutdownTimeout:  30 * time.Second,
+		SocketMode:       0660,
 	}
 }
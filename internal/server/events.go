@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+const (
+	eventsRouteName     = "events"
+	eventsBatchSize     = 100
+	eventsPollInterval  = 1 * time.Second
+	eventsKeepAlive     = 15 * time.Second
+	eventsRetryInterval = 5 * time.Second
+)
+
+// streamedEvents are the lease lifecycle events sent to event stream subscribers, while
+// noisier events e.g. node.heartbeat_ping are only recorded as audit logs
+var streamedEvents = map[string]bool{
+	"license.leased":         true,
+	"license.lease_extended": true,
+	"license.released":       true,
+	"license.lease_expired":  true,
+	"node.culled":            true,
+	"pool.added":             true,
+	"pool.removed":           true,
+}
+
+type EventResponse struct {
+	ID          int64         `json:"id"`
+	Event       string        `json:"event"`
+	EntityType  string        `json:"entity_type"`
+	LicenseID   *string       `json:"license_id"`
+	Fingerprint *string       `json:"fingerprint"`
+	Pool        *string       `json:"pool"`
+	Metadata    *NodeMetadata `json:"metadata"`
+	CreatedAt   int64         `json:"created_at"`
+}
+
+// StreamEvents streams lease lifecycle events as server-sent events. Events are backed by
+// audit logs, so a client can resume from where it left off via Last-Event-ID.
+func (h *handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if !h.manager.Config().EnabledAudit {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "events are disabled when audit logs are disabled"})
+		return
+	}
+
+	pool, err := h.requestEventsPool(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+		return
+	}
+
+	if err := h.verifyAPIToken(r.Context(), r.Header.Get("Authorization"), pool); err != nil {
+		writeAPITokenError(w, err, "", pool, r.RemoteAddr)
+		return
+	}
+
+	lastEventID, err := requestLastEventID(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// only stream new events unless the client is resuming
+	if lastEventID == nil {
+		id, err := h.manager.LastEventID(r.Context())
+		if err != nil {
+			logger.Error("failed to get last event id", "error", err)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to stream events"})
+			return
+		}
+
+		lastEventID = &id
+	}
+
+	cursor := *lastEventID
+
+	// fetch the first batch before committing to a stream so that errors e.g. an invalid
+	// pool can be reported with a proper status code
+	events, err := h.manager.ListEvents(r.Context(), pool, cursor, eventsBatchSize, h.listOptions(pool)...)
+	if err != nil {
+		if errors.Is(err, licenses.ErrBadPool) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid pool header"})
+			return
+		}
+
+		logger.Error("failed to list events", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to stream events"})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable buffering by reverse proxies
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetryInterval.Milliseconds())
+
+	logger.Debug("event stream opened", "pool", pool, "lastEventId", cursor, "remote_addr", r.RemoteAddr)
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		for _, event := range events {
+			cursor = event.ID
+
+			if !streamedEvents[event.EventType] {
+				continue
+			}
+
+			if err := writeEvent(w, event); err != nil {
+				logger.Debug("failed to write event", "error", err)
+
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			logger.Debug("failed to flush event stream", "error", err)
+
+			return
+		}
+
+		// keep reading without waiting if there may be more events to catch up on
+		if len(events) < eventsBatchSize {
+		wait:
+			for {
+				select {
+				case <-r.Context().Done():
+					logger.Debug("event stream closed", "remote_addr", r.RemoteAddr)
+
+					return
+				case <-h.server.Done():
+					return
+				case <-keepAlive.C:
+					if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+						return
+					}
+
+					if err := rc.Flush(); err != nil {
+						return
+					}
+				case <-poll.C:
+					break wait
+				}
+			}
+		}
+
+		events, err = h.manager.ListEvents(r.Context(), pool, cursor, eventsBatchSize, h.listOptions(pool)...)
+		if err != nil {
+			logger.Error("failed to list events", "error", err)
+
+			return
+		}
+	}
+}
+
+// requestEventsPool resolves the pool to filter events by, where a pool query parameter
+// is accepted in addition to the Relay-Pool header since browsers can't set headers
+// for an EventSource
+func (h *handler) requestEventsPool(r *http.Request) (*string, error) {
+	if p := r.URL.Query().Get("pool"); p != "" {
+		if h.config.Pool != nil && *h.config.Pool != p {
+			return nil, errUnsupportedPool
+		}
+
+		return &p, nil
+	}
+
+	return h.requestPool(r)
+}
+
+// requestLastEventID parses the ID to resume the stream after, either from the
+// Last-Event-ID header sent by reconnecting clients or a last_event_id query parameter,
+// returning nil when the client isn't resuming
+func requestLastEventID(r *http.Request) (*int64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+
+	if v == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return nil, errors.New("last event id must be a positive integer")
+	}
+
+	return &id, nil
+}
+
+func writeEvent(w io.Writer, event db.Event) error {
+	data, err := json.Marshal(EventResponse{
+		ID:          event.ID,
+		Event:       event.EventType,
+		EntityType:  event.EntityType,
+		LicenseID:   event.LicenseGuid,
+		Fingerprint: event.NodeFingerprint,
+		Pool:        event.PoolName,
+		Metadata:    nodeMetadataResponse(event.NodeMetadata()),
+		CreatedAt:   event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.EventType, data)
+
+	return err
+}
+
+// isStreaming returns true if the request was routed to a streaming endpoint
+func isStreaming(r *http.Request) bool {
+	if route := mux.CurrentRoute(r); route != nil {
+		return route.GetName() == eventsRouteName
+	}
+
+	return false
+}
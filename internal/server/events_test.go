@@ -0,0 +1,310 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEvents is an in-memory audit log for exercising event streams
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []db.Event
+}
+
+func (f *fakeEvents) append(event string, pool *string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fingerprint := "test_fingerprint"
+
+	f.events = append(f.events, db.Event{
+		ID:              int64(len(f.events) + 1),
+		EventType:       event,
+		EntityType:      strings.Split(event, ".")[0],
+		PoolName:        pool,
+		NodeFingerprint: &fingerprint,
+		CreatedAt:       time.Now().Unix(),
+	})
+}
+
+func newEventsManager(events *fakeEvents, audit bool) *testutils.FakeManager {
+	return &testutils.FakeManager{
+		ConfigFn: func() *licenses.Config {
+			return &licenses.Config{EnabledAudit: audit}
+		},
+		LastEventIDFn: func(ctx context.Context) (int64, error) {
+			events.mu.Lock()
+			defer events.mu.Unlock()
+
+			return int64(len(events.events)), nil
+		},
+		ListEventsFn: func(ctx context.Context, pool *string, afterID int64, limit int64) ([]db.Event, error) {
+			events.mu.Lock()
+			defer events.mu.Unlock()
+
+			if pool != nil && *pool == "unknown" {
+				return nil, licenses.ErrBadPool
+			}
+
+			var result []db.Event
+			for _, event := range events.events {
+				if event.ID <= afterID {
+					continue
+				}
+
+				if pool != nil && (event.PoolName == nil || *event.PoolName != *pool) {
+					continue
+				}
+
+				result = append(result, event)
+				if int64(len(result)) == limit {
+					break
+				}
+			}
+
+			return result, nil
+		},
+	}
+}
+
+func newEventsServer(t *testing.T, srv *testutils.FakeServer) *httptest.Server {
+	t.Helper()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.Use(server.SigningMiddleware(srv.Config()))
+	router.Use(server.LoggingMiddleware(srv.Metrics()))
+
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// readEvent reads the next event from a stream, skipping comments and retry hints
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	event := map[string]string{}
+
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if _, ok := event["event"]; ok {
+				return event
+			}
+
+			continue
+		}
+
+		if k, v, ok := strings.Cut(line, ": "); ok && k != "" {
+			event[k] = v
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	events := &fakeEvents{}
+	events.append("pool.added", nil)
+
+	cfg := server.NewConfig()
+	cfg.SigningSecret = ptr("hunter2")
+
+	srv := testutils.NewMockServer(cfg, newEventsManager(events, true))
+	ts := newEventsServer(t, srv)
+
+	resp, err := http.Get(ts.URL + "/v1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Relay-Signature"))
+
+	// only events after connecting are streamed, and noisy events are skipped
+	events.append("node.heartbeat_ping", nil)
+	events.append("license.leased", nil)
+
+	body := bufio.NewReader(resp.Body)
+
+	event := readEvent(t, body)
+	assert.Equal(t, "3", event["id"])
+	assert.Equal(t, "license.leased", event["event"])
+	assert.Contains(t, event["data"], `"event":"license.leased"`)
+	assert.Contains(t, event["data"], `"fingerprint":"test_fingerprint"`)
+}
+
+func TestStreamEvents_Resume(t *testing.T) {
+	events := &fakeEvents{}
+	events.append("license.leased", nil)
+	events.append("license.lease_extended", nil)
+	events.append("license.released", nil)
+
+	srv := testutils.NewMockServer(server.NewConfig(), newEventsManager(events, true))
+	ts := newEventsServer(t, srv)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+
+	assert.Equal(t, "license.lease_extended", readEvent(t, body)["event"])
+	assert.Equal(t, "license.released", readEvent(t, body)["event"])
+}
+
+func TestStreamEvents_Pool(t *testing.T) {
+	events := &fakeEvents{}
+	events.append("license.leased", nil)
+	events.append("license.leased", ptr("dev"))
+	events.append("license.leased", ptr("prod"))
+
+	srv := testutils.NewMockServer(server.NewConfig(), newEventsManager(events, true))
+	ts := newEventsServer(t, srv)
+
+	resp, err := http.Get(ts.URL + "/v1/events?pool=prod&last_event_id=0")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	event := readEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "3", event["id"])
+	assert.Contains(t, event["data"], `"pool":"prod"`)
+
+	resp, err = http.Get(ts.URL + "/v1/events?pool=unknown")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStreamEvents_PoolCull(t *testing.T) {
+	ctx := context.Background()
+	store, conn := testutils.NewMemoryStore(t)
+	defer testutils.CloseMemoryStore(conn)
+
+	config := licenses.NewConfig()
+	config.EnabledAudit = true
+
+	manager := licenses.NewManager(
+		config,
+		func(filename string) ([]byte, error) {
+			return []byte(filename), nil
+		},
+		func(cert []byte) licenses.LicenseVerifier {
+			return &testutils.FakeLicenseVerifier{}
+		},
+	)
+	manager.AttachStore(*store)
+
+	dev := "dev"
+
+	_, err := manager.AddLicense(ctx, &dev, "license1.lic", "key1")
+	require.NoError(t, err)
+	_, err = manager.AddLicense(ctx, nil, "license2.lic", "key2")
+	require.NoError(t, err)
+
+	_, err = manager.ClaimLicense(ctx, &dev, "dev_fingerprint")
+	require.NoError(t, err)
+	_, err = manager.ClaimLicense(ctx, nil, "global_fingerprint")
+	require.NoError(t, err)
+
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{
+		ConfigFn:      manager.Config,
+		LastEventIDFn: manager.LastEventID,
+		ListEventsFn: func(ctx context.Context, pool *string, afterID int64, limit int64) ([]db.Event, error) {
+			return manager.ListEvents(ctx, pool, afterID, limit)
+		},
+	})
+	ts := newEventsServer(t, srv)
+
+	resp, err := http.Get(ts.URL + "/v1/events?pool=dev")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	_, err = conn.ExecContext(ctx, `UPDATE nodes SET last_heartbeat_at = strftime('%s', 'now', '-2 hours')`)
+	require.NoError(t, err)
+
+	nodes, err := manager.CullDeadNodes(ctx, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	// only the pool's half of the cull is streamed
+	body := bufio.NewReader(resp.Body)
+
+	event := readEvent(t, body)
+	assert.Equal(t, "license.lease_expired", event["event"])
+	assert.Contains(t, event["data"], `"pool":"dev"`)
+
+	event = readEvent(t, body)
+	assert.Equal(t, "node.culled", event["event"])
+	assert.Contains(t, event["data"], `"pool":"dev"`)
+	assert.Contains(t, event["data"], `"fingerprint":"dev_fingerprint"`)
+}
+
+func TestStreamEvents_Shutdown(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newEventsManager(&fakeEvents{}, true))
+	ts := newEventsServer(t, srv)
+
+	resp, err := http.Get(ts.URL + "/v1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	srv.Shutdown()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected stream to end on shutdown")
+	}
+}
+
+func TestStreamEvents_Errors(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newEventsManager(&fakeEvents{}, false))
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/events", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	srv = testutils.NewMockServer(server.NewConfig(), newEventsManager(&fakeEvents{}, true))
+
+	router = mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,651 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	relayv1 "github.com/keygen-sh/keygen-relay/api/relay/v1"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCSignatureMethod is the method of a gRPC response's signing envelope, since every
+// gRPC request is an HTTP/2 POST
+const GRPCSignatureMethod = "POST"
+
+// minWatchLeaseInterval is the minimum time between heartbeats for a watched lease
+const minWatchLeaseInterval = 1 * time.Second
+
+// grpcHandler serves the gRPC API using the same manager, wait queue and checks as the
+// REST handler, so that both front-ends behave the same
+type grpcHandler struct {
+	relayv1.UnimplementedRelayServer
+
+	handler *handler
+}
+
+// NewGRPCServer creates a gRPC server for the Relay service. Unary responses are signed
+// the same way as REST responses, when signing is enabled, and requests share the REST
+// API's rate limits.
+func NewGRPCServer(server Server, opts ...grpc.ServerOption) *grpc.Server {
+	h := newHandler(server)
+	limiters := server.RateLimiters()
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(grpcLoggingInterceptor, grpcSigningInterceptor(h.config), grpcRateLimitInterceptor(h.config, h.metrics, limiters)),
+		grpc.ChainStreamInterceptor(grpcStreamLoggingInterceptor, grpcStreamClockInterceptor, grpcStreamRateLimitInterceptor(h.config, h.metrics, limiters)),
+	)
+
+	srv := grpc.NewServer(opts...)
+	relayv1.RegisterRelayServer(srv, &grpcHandler{handler: h})
+
+	return srv
+}
+
+func (g *grpcHandler) Claim(ctx context.Context, req *relayv1.ClaimRequest) (*relayv1.ClaimResponse, error) {
+	h := g.handler
+
+	pool, err := g.authorize(ctx, req.Fingerprint, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must be a positive integer")
+	}
+
+	if req.Wait < 0 {
+		return nil, status.Error(codes.InvalidArgument, "wait must be a positive duration")
+	}
+
+	opts := []licenses.LeaseOptionFunc{
+		licenses.WithLeaseID(req.LeaseId),
+		licenses.WithLeaseToken(req.LeaseToken),
+	}
+
+	if req.Ttl > 0 {
+		opts = append(opts, licenses.WithTTL(h.requestTTL(ctx, pool, req.Fingerprint, req.Ttl)))
+	}
+
+	node, err := grpcNodeMetadata(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if node != nil {
+		opts = append(opts, licenses.WithMetadata(node))
+	}
+
+	wait := min(time.Duration(req.Wait)*time.Second, h.config.MaxWait)
+
+	// new claims can't jump ahead of claims already waiting on the pool, but existing
+	// leases can still be extended
+	claimOpts := opts
+	if h.queue.Len(pool) > 0 {
+		claimOpts = append(slices.Clone(opts), licenses.WithExtendOnly())
+	}
+
+	var position int
+
+	result, err := h.manager.ClaimLicense(ctx, pool, req.Fingerprint, claimOpts...)
+	if err == nil && result.Status == licenses.OperationStatusNoLicensesAvailable && wait > 0 {
+		result, position, err = h.waitForLicense(ctx, pool, req.Fingerprint, wait, opts)
+	}
+
+	if err != nil {
+		return nil, grpcClaimError(req.Fingerprint, err)
+	}
+
+	h.metrics.ObserveClaim(pool, result.Status)
+
+	ttl := g.leaseTTL(result)
+
+	switch result.Status {
+	case licenses.OperationStatusCreated:
+		resp := &relayv1.ClaimResponse{
+			Status:        relayv1.ClaimStatus_CLAIM_STATUS_CREATED,
+			LeaseToken:    result.LeaseToken,
+			ExpiresAt:     time.Now().Add(ttl).Unix(),
+			ExpiresIn:     int64(ttl.Seconds()),
+			QueuePosition: int64(position),
+		}
+
+		if result.License != nil {
+			resp.LicenseFile = result.License.File
+			resp.LicenseKey = result.License.Key
+		}
+
+		return resp, nil
+	case licenses.OperationStatusExtended:
+		return &relayv1.ClaimResponse{
+			Status:        relayv1.ClaimStatus_CLAIM_STATUS_EXTENDED,
+			ExpiresAt:     time.Now().Add(ttl).Unix(),
+			ExpiresIn:     int64(ttl.Seconds()),
+			QueuePosition: int64(position),
+		}, nil
+	default:
+		return nil, grpcClaimStatusError(result.Status)
+	}
+}
+
+func (g *grpcHandler) Extend(ctx context.Context, req *relayv1.ExtendRequest) (*relayv1.ExtendResponse, error) {
+	h := g.handler
+
+	pool, err := g.authorize(ctx, req.Fingerprint, true)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := g.extendOptions(ctx, pool, req.Fingerprint, req.LeaseId, req.LeaseToken, req.Ttl, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.manager.ClaimLicense(ctx, pool, req.Fingerprint, opts...)
+	if err != nil {
+		return nil, grpcClaimError(req.Fingerprint, err)
+	}
+
+	h.metrics.ObserveClaim(pool, result.Status)
+
+	if result.Status != licenses.OperationStatusExtended {
+		return nil, grpcExtendStatusError(result.Status)
+	}
+
+	ttl := g.leaseTTL(result)
+
+	return &relayv1.ExtendResponse{
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+		ExpiresIn: int64(ttl.Seconds()),
+	}, nil
+}
+
+func (g *grpcHandler) Release(ctx context.Context, req *relayv1.ReleaseRequest) (*relayv1.ReleaseResponse, error) {
+	h := g.handler
+
+	pool, err := g.authorize(ctx, req.Fingerprint, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.manager.ReleaseLicense(ctx, pool, req.Fingerprint,
+		licenses.WithLeaseID(req.LeaseId),
+		licenses.WithLeaseToken(req.LeaseToken),
+	)
+	if err != nil {
+		if errors.Is(err, licenses.ErrLeaseTokenMismatch) {
+			logger.Warn("lease token rejected", "nodeFingerprint", req.Fingerprint)
+
+			return nil, status.Error(codes.PermissionDenied, "invalid lease token")
+		}
+
+		logger.Error("failed to release license", "error", err)
+
+		if errors.Is(err, licenses.ErrBadPool) {
+			return nil, status.Error(codes.InvalidArgument, "invalid pool")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to release license")
+	}
+
+	h.metrics.ObserveRelease(pool, result.Status)
+
+	switch result.Status {
+	case licenses.OperationStatusSuccess:
+		h.queue.Notify(pool)
+
+		return &relayv1.ReleaseResponse{}, nil
+	case licenses.OperationStatusNotFound:
+		return nil, status.Error(codes.NotFound, "claim not found")
+	default:
+		return nil, status.Error(codes.Internal, "unknown release status")
+	}
+}
+
+// WatchLease heartbeats a lease at half its TTL for as long as the stream is open, so
+// that a single delayed heartbeat doesn't expire the lease. Once the stream is closed,
+// the lease expires as usual unless it's extended some other way.
+func (g *grpcHandler) WatchLease(req *relayv1.WatchLeaseRequest, stream relayv1.Relay_WatchLeaseServer) error {
+	h := g.handler
+	ctx := stream.Context()
+
+	pool, err := g.authorize(ctx, req.Fingerprint, true)
+	if err != nil {
+		return err
+	}
+
+	opts, err := g.extendOptions(ctx, pool, req.Fingerprint, req.LeaseId, req.LeaseToken, req.Ttl, req.Metadata)
+	if err != nil {
+		return err
+	}
+
+	for {
+		result, err := h.manager.ClaimLicense(ctx, pool, req.Fingerprint, opts...)
+		if err != nil {
+			return grpcClaimError(req.Fingerprint, err)
+		}
+
+		h.metrics.ObserveClaim(pool, result.Status)
+
+		if result.Status != licenses.OperationStatusExtended {
+			return grpcExtendStatusError(result.Status)
+		}
+
+		ttl := g.leaseTTL(result)
+
+		if err := stream.Send(&relayv1.LeaseEvent{
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			ExpiresIn: int64(ttl.Seconds()),
+		}); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(max(ttl/2, minWatchLeaseInterval))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			logger.Debug("client stopped watching lease", "nodeFingerprint", req.Fingerprint)
+
+			return nil
+		case <-h.server.Done():
+			timer.Stop()
+
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-timer.C:
+		}
+	}
+}
+
+// authorize runs the same checks as the REST handler before acting on a node's lease,
+// returning the resolved pool. Pool access is only checked when claiming or extending.
+func (g *grpcHandler) authorize(ctx context.Context, fingerprint string, checkAccess bool) (*string, error) {
+	h := g.handler
+
+	if fingerprint == "" {
+		return nil, status.Error(codes.InvalidArgument, "fingerprint is required")
+	}
+
+	p, _ := peer.FromContext(ctx)
+
+	var remoteAddr string
+	if p != nil && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+
+	if h.config.TLSClientFingerprint {
+		var info credentials.TLSInfo
+		if p != nil {
+			info, _ = p.AuthInfo.(credentials.TLSInfo)
+		}
+
+		if err := h.verifyFingerprint(&info.State, fingerprint); err != nil {
+			logger.Warn("fingerprint rejected", "nodeFingerprint", fingerprint, "remote_addr", remoteAddr, "error", err)
+
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+
+	pool, err := h.resolvePool(grpcMetadata(ctx, "relay-pool"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "unsupported pool")
+	}
+
+	if err := h.verifyAPIToken(ctx, grpcMetadata(ctx, "authorization"), pool); err != nil {
+		code, message := apiTokenError(err, fingerprint, pool, remoteAddr)
+
+		return nil, status.Error(grpcStatusCode(code), message)
+	}
+
+	if !checkAccess {
+		return pool, nil
+	}
+
+	ip := grpcClientIP(ctx, h.config)
+
+	if err := h.manager.CheckPoolAccess(ctx, pool, net.ParseIP(ip)); err != nil {
+		if errors.Is(err, licenses.ErrAccessDenied) {
+			logger.Warn("pool access denied", "nodeFingerprint", fingerprint, "pool", pool, "ip", ip, "remote_addr", remoteAddr)
+
+			return nil, status.Error(codes.PermissionDenied, "access to pool is denied")
+		}
+
+		logger.Error("failed to check pool access", "nodeFingerprint", fingerprint, "pool", pool, "error", err)
+
+		return nil, status.Error(codes.Internal, "failed to check pool access")
+	}
+
+	return pool, nil
+}
+
+// leaseTTL returns the TTL of a claimed or extended lease
+func (g *grpcHandler) leaseTTL(result *licenses.LicenseOperationResult) time.Duration {
+	if result.Node != nil {
+		return result.Node.LeaseTTL(g.handler.config.TTL)
+	}
+
+	return g.handler.config.TTL
+}
+
+// extendOptions returns the lease options for extending an existing lease
+func (g *grpcHandler) extendOptions(ctx context.Context, pool *string, fingerprint string, leaseID string, leaseToken string, ttl int64, md *relayv1.NodeMetadata) ([]licenses.LeaseOptionFunc, error) {
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl must be a positive integer")
+	}
+
+	node, err := grpcNodeMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []licenses.LeaseOptionFunc{
+		licenses.WithLeaseID(leaseID),
+		licenses.WithLeaseToken(leaseToken),
+		licenses.WithExtendOnly(),
+	}
+
+	if ttl > 0 {
+		opts = append(opts, licenses.WithTTL(g.handler.requestTTL(ctx, pool, fingerprint, ttl)))
+	}
+
+	if node != nil {
+		opts = append(opts, licenses.WithMetadata(node))
+	}
+
+	return opts, nil
+}
+
+// grpcNodeMetadata validates the node metadata reported in a request the same as the
+// REST API, where empty fields are not reported
+func grpcNodeMetadata(md *relayv1.NodeMetadata) (*db.NodeMetadata, error) {
+	if md == nil {
+		return nil, nil
+	}
+
+	reported := NodeMetadata{
+		Hostname:   nonEmpty(md.Hostname),
+		Platform:   nonEmpty(md.Platform),
+		Username:   nonEmpty(md.Username),
+		AppName:    nonEmpty(md.AppName),
+		AppVersion: nonEmpty(md.AppVersion),
+		Labels:     md.Labels,
+	}
+
+	node, err := reported.nodeMetadata()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return node, nil
+}
+
+// grpcClaimError converts an error from claiming or extending a lease into a status
+func grpcClaimError(fingerprint string, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		logger.Debug("client disconnected while claiming license", "nodeFingerprint", fingerprint)
+
+		return status.FromContextError(err).Err()
+	case errors.Is(err, errQueueClosed):
+		return status.Error(codes.Unavailable, "server is shutting down")
+	case errors.Is(err, licenses.ErrLeaseTokenMismatch):
+		logger.Warn("lease token rejected", "nodeFingerprint", fingerprint)
+
+		return status.Error(codes.PermissionDenied, "invalid lease token")
+	case errors.Is(err, licenses.ErrTooManyLeases):
+		return status.Error(codes.FailedPrecondition, "node has reached its maximum number of leases")
+	}
+
+	logger.Error("failed to claim license", "error", err)
+
+	if errors.Is(err, licenses.ErrBadPool) {
+		return status.Error(codes.InvalidArgument, "invalid pool")
+	}
+
+	return status.Error(codes.Internal, "failed to claim license")
+}
+
+// grpcClaimStatusError converts an unsuccessful claim status into a status
+func grpcClaimStatusError(s licenses.OperationStatus) error {
+	switch s {
+	case licenses.OperationStatusConflict:
+		return status.Error(codes.Aborted, "failed to claim license due to conflict")
+	case licenses.OperationStatusNoLicensesAvailable:
+		return status.Error(codes.ResourceExhausted, "no licenses available")
+	default:
+		return status.Error(codes.Internal, "unknown claim status")
+	}
+}
+
+// grpcExtendStatusError converts an unsuccessful extension status into a status, where
+// no licenses being available means the node has no lease to extend
+func grpcExtendStatusError(s licenses.OperationStatus) error {
+	if s == licenses.OperationStatusNoLicensesAvailable {
+		return status.Error(codes.NotFound, "lease not found")
+	}
+
+	return grpcClaimStatusError(s)
+}
+
+// grpcStatusCode maps an HTTP status to its gRPC equivalent
+func grpcStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
+
+// grpcMetadata returns the first value of an incoming metadata key, or an empty string
+func grpcMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// grpcSigningInterceptor signs unary responses like SigningMiddleware, where the envelope
+// is the method's full name as the path, the status code as the status, and the
+// response's deterministic protobuf encoding, or the status message for errors, as the
+// body. The signature is sent as relay-signature header metadata.
+func grpcSigningInterceptor(cfg *Config) grpc.UnaryServerInterceptor {
+	signer := NewSigner(cfg)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t := time.Now().Unix()
+		md := metadata.Pairs("relay-clock", strconv.FormatInt(t, 10))
+
+		resp, err := handler(ctx, req)
+
+		if signer.Enabled() {
+			var body []byte
+
+			if err != nil {
+				body = []byte(status.Convert(err).Message())
+			} else if msg, ok := resp.(proto.Message); ok {
+				b, merr := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+				if merr != nil {
+					logger.Error("failed to marshal response for signing", "method", info.FullMethod, "error", merr)
+
+					return nil, status.Error(codes.Internal, "failed to sign response")
+				}
+
+				body = b
+			}
+
+			md.Set("relay-signature", signer.Signature(t, Envelope{
+				Method: GRPCSignatureMethod,
+				Path:   info.FullMethod,
+				Status: int(status.Code(err)),
+				Nonce:  grpcMetadata(ctx, "relay-nonce"),
+				Body:   body,
+			}))
+		}
+
+		_ = grpc.SetHeader(ctx, md)
+
+		return resp, err
+	}
+}
+
+// grpcRateLimitInterceptor throttles unary requests using the same buckets as the REST
+// API, failing with RESOURCE_EXHAUSTED and a retry-after trailer once a bucket is empty
+func grpcRateLimitInterceptor(cfg *Config, metrics *Metrics, limiters *RateLimiters) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := grpcThrottle(ctx, cfg, metrics, limiters, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// grpcStreamRateLimitInterceptor throttles streams the same as unary requests, once the
+// request message carrying the node's fingerprint has been received
+func grpcStreamRateLimitInterceptor(cfg *Config, metrics *Metrics, limiters *RateLimiters) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &rateLimitedStream{ServerStream: ss, throttle: func(req any) error {
+			return grpcThrottle(ss.Context(), cfg, metrics, limiters, info.FullMethod, req)
+		}})
+	}
+}
+
+// rateLimitedStream throttles a stream on its first received message
+type rateLimitedStream struct {
+	grpc.ServerStream
+
+	throttle func(req any) error
+	received bool
+}
+
+func (s *rateLimitedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.received {
+		return nil
+	}
+
+	s.received = true
+
+	return s.throttle(m)
+}
+
+// grpcThrottle takes a token from the client IP's and the node fingerprint's buckets,
+// returning a RESOURCE_EXHAUSTED status once either is empty
+func grpcThrottle(ctx context.Context, cfg *Config, metrics *Metrics, limiters *RateLimiters, method string, req any) error {
+	// clients whose address isn't an ip address have no ip address to limit by
+	if ip := grpcClientIP(ctx, cfg); cfg.IPRateLimit.Enabled() && ip != "" {
+		if ok, wait, throttled := limiters.IPs.Allow(ip, cfg.IPRateLimit); !ok {
+			logger.Warn("request throttled", "by", "ip", "ip", ip, "remote_addr", grpcRemoteAddr(ctx), "method", method, "throttled", throttled, "retry_after", wait)
+
+			return grpcThrottled(ctx, metrics, "ip", wait)
+		}
+	}
+
+	r, ok := req.(interface{ GetFingerprint() string })
+	if !ok || !cfg.FingerprintRateLimit.Enabled() {
+		return nil
+	}
+
+	fingerprint := r.GetFingerprint()
+
+	if ok, wait, throttled := limiters.Fingerprints.Allow(fingerprint, cfg.FingerprintRateLimit); !ok {
+		logger.Warn("request throttled", "by", "fingerprint", "nodeFingerprint", fingerprint, "remote_addr", grpcRemoteAddr(ctx), "method", method, "throttled", throttled, "retry_after", wait)
+
+		return grpcThrottled(ctx, metrics, "fingerprint", wait)
+	}
+
+	return nil
+}
+
+func grpcThrottled(ctx context.Context, metrics *Metrics, by string, wait time.Duration) error {
+	if metrics != nil {
+		metrics.ObserveThrottle(by)
+	}
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", retryAfter(wait)))
+
+	return status.Error(codes.ResourceExhausted, "too many requests")
+}
+
+// grpcStreamClockInterceptor sends the server's clock for streams, which aren't signed
+func grpcStreamClockInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	_ = ss.SetHeader(metadata.Pairs("relay-clock", strconv.FormatInt(time.Now().Unix(), 10)))
+
+	return handler(srv, ss)
+}
+
+func grpcLoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	logger.Info("gRPC request",
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"remote_addr", grpcRemoteAddr(ctx),
+		"duration", time.Since(start),
+	)
+
+	return resp, err
+}
+
+func grpcStreamLoggingInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	logger.Info("gRPC stream",
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"remote_addr", grpcRemoteAddr(ss.Context()),
+		"duration", time.Since(start),
+	)
+
+	return err
+}
+
+// grpcClientIP returns the IP address of the client, the same as clientIP for REST requests
+func grpcClientIP(ctx context.Context, cfg *Config) string {
+	var forwarded []string
+	if cfg.ForwardedHeader != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			forwarded = md.Get(cfg.ForwardedHeader)
+		}
+	}
+
+	return forwardedClientIP(cfg, grpcRemoteAddr(ctx), forwarded)
+}
+
+func grpcRemoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,624 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	relayv1 "github.com/keygen-sh/keygen-relay/api/relay/v1"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufconnListener reports a fixed remote address for in-memory connections, like httptest
+// does for requests, since an in-memory connection's address isn't an IP address
+type bufconnListener struct {
+	*bufconn.Listener
+}
+
+func (l bufconnListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return remoteAddrConn{conn}, nil
+}
+
+type remoteAddrConn struct {
+	net.Conn
+}
+
+func (remoteAddrConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+}
+
+// newGRPCClient serves the gRPC API for the server over an in-memory connection
+func newGRPCClient(t *testing.T, srv server.Server) relayv1.RelayClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1024 * 1024)
+
+	grpcServer := server.NewGRPCServer(srv)
+	go func() {
+		_ = grpcServer.Serve(bufconnListener{ln})
+	}()
+
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	return relayv1.NewRelayClient(conn)
+}
+
+func TestGRPCClaim_NewNode_Success(t *testing.T) {
+	cfg := server.NewConfig()
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License:    &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					LeaseToken: "test_lease_token",
+					Status:     licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	resp, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"})
+	require.NoError(t, err)
+
+	assert.Equal(t, relayv1.ClaimStatus_CLAIM_STATUS_CREATED, resp.Status)
+	assert.Equal(t, []byte("test_license_file"), resp.LicenseFile)
+	assert.Equal(t, "test_license_key", resp.LicenseKey)
+	assert.Equal(t, "test_lease_token", resp.LeaseToken)
+	assert.WithinDuration(t, time.Now().Add(cfg.TTL), time.Unix(resp.ExpiresAt, 0), cfg.TTL/2)
+	assert.Equal(t, int64(cfg.TTL.Seconds()), resp.ExpiresIn)
+}
+
+func TestGRPCClaim_ExistingNode_Extended(t *testing.T) {
+	ttl := int64(45)
+
+	cfg := server.NewConfig()
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				assert.Equal(t, "test_lease_token", *opts.LeaseToken())
+				assert.Equal(t, 45*time.Second, *opts.TTL())
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Node:    &db.Node{Ttl: &ttl},
+					Status:  licenses.OperationStatusExtended,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	resp, err := client.Claim(context.Background(), &relayv1.ClaimRequest{
+		Fingerprint: "test_fingerprint",
+		LeaseToken:  "test_lease_token",
+		Ttl:         45,
+	})
+	require.NoError(t, err)
+
+	// the license is only sent for new leases, same as the REST API
+	assert.Equal(t, relayv1.ClaimStatus_CLAIM_STATUS_EXTENDED, resp.Status)
+	assert.Empty(t, resp.LicenseFile)
+	assert.Empty(t, resp.LicenseKey)
+	assert.Equal(t, ttl, resp.ExpiresIn)
+}
+
+func TestGRPCClaim_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *licenses.LicenseOperationResult
+		err    error
+		req    *relayv1.ClaimRequest
+		code   codes.Code
+	}{
+		{name: "missing fingerprint", req: &relayv1.ClaimRequest{}, code: codes.InvalidArgument},
+		{name: "negative ttl", req: &relayv1.ClaimRequest{Fingerprint: "test_fingerprint", Ttl: -1}, code: codes.InvalidArgument},
+		{name: "no licenses available", result: &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, code: codes.ResourceExhausted},
+		{name: "conflict", result: &licenses.LicenseOperationResult{Status: licenses.OperationStatusConflict}, code: codes.Aborted},
+		{name: "lease token mismatch", err: licenses.ErrLeaseTokenMismatch, code: codes.PermissionDenied},
+		{name: "too many leases", err: licenses.ErrTooManyLeases, code: codes.FailedPrecondition},
+		{name: "bad pool", err: fmt.Errorf("pool prod: %w", licenses.ErrBadPool), code: codes.InvalidArgument},
+		{name: "internal error", err: fmt.Errorf("database error"), code: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testutils.NewMockServer(
+				server.NewConfig(),
+				&testutils.FakeManager{
+					ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+						return tt.result, tt.err
+					},
+				},
+			)
+
+			client := newGRPCClient(t, srv)
+
+			req := tt.req
+			if req == nil {
+				req = &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"}
+			}
+
+			_, err := client.Claim(context.Background(), req)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestGRPCClaim_APIToken(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	var claims int
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+				switch {
+				case token != "relay_test_token":
+					return nil, licenses.ErrAPITokenInvalid
+				case pool == nil || *pool != "prod":
+					return nil, fmt.Errorf("api token 1: %w", licenses.ErrAPITokenPoolMismatch)
+				}
+
+				return &db.ApiToken{ID: 1}, nil
+			},
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				claims++
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	tests := []struct {
+		name string
+		auth string
+		pool string
+		code codes.Code
+	}{
+		{name: "missing token", pool: "prod", code: codes.Unauthenticated},
+		{name: "invalid token", auth: "Bearer relay_invalid", pool: "prod", code: codes.Unauthenticated},
+		{name: "mismatched pool", auth: "Bearer relay_test_token", pool: "dev", code: codes.PermissionDenied},
+		{name: "valid token", auth: "Bearer relay_test_token", pool: "prod", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			if tt.auth != "" {
+				md.Set("authorization", tt.auth)
+			}
+			if tt.pool != "" {
+				md.Set("relay-pool", tt.pool)
+			}
+
+			ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+			_, err := client.Claim(ctx, &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	// only the authenticated request should have claimed a license
+	assert.Equal(t, 1, claims)
+}
+
+func TestGRPCClaim_PoolAccessDenied(t *testing.T) {
+	var claims int
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			CheckPoolAccessFn: func(ctx context.Context, pool *string, ip net.IP) error {
+				if pool != nil && *pool == "prod" {
+					return fmt.Errorf("ip %s: %w", ip, licenses.ErrAccessDenied)
+				}
+
+				return nil
+			},
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				claims++
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "relay-pool", "prod")
+
+	_, err := client.Claim(ctx, &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "relay-pool", "dev")
+
+	_, err = client.Claim(ctx, &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, claims)
+}
+
+func TestGRPCExtend(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				// extending should never claim a new lease
+				assert.True(t, opts.ExtendOnly())
+
+				if fingerprint != "test_fingerprint" {
+					return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, nil
+				}
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusExtended}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	resp, err := client.Extend(context.Background(), &relayv1.ExtendRequest{Fingerprint: "test_fingerprint"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(server.NewConfig().TTL.Seconds()), resp.ExpiresIn)
+
+	_, err = client.Extend(context.Background(), &relayv1.ExtendRequest{Fingerprint: "other_fingerprint"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCClaim_Metadata(t *testing.T) {
+	var reported []*db.NodeMetadata
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				reported = append(reported, opts.Metadata())
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusExtended}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+	md := &relayv1.NodeMetadata{Hostname: "build-01", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}
+
+	_, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "test_fingerprint", Metadata: md})
+	require.NoError(t, err)
+
+	_, err = client.Extend(context.Background(), &relayv1.ExtendRequest{Fingerprint: "test_fingerprint", Metadata: md})
+	require.NoError(t, err)
+
+	// requests without metadata leave the node's stored metadata to the manager
+	_, err = client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"})
+	require.NoError(t, err)
+
+	expected := &db.NodeMetadata{Hostname: "build-01", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}
+	assert.Equal(t, []*db.NodeMetadata{expected, expected, nil}, reported)
+
+	// metadata is validated the same as the rest api
+	for _, md := range []*relayv1.NodeMetadata{
+		{Hostname: strings.Repeat("a", 256)},
+		{Labels: map[string]string{"": "value"}},
+	} {
+		_, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "test_fingerprint", Metadata: md})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Extend(context.Background(), &relayv1.ExtendRequest{Fingerprint: "test_fingerprint", Metadata: md})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	assert.Len(t, reported, 3)
+}
+
+func TestGRPCRelease(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ReleaseLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				switch {
+				case fingerprint != "test_fingerprint":
+					return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNotFound}, nil
+				case *opts.LeaseToken() != "test_lease_token":
+					return nil, licenses.ErrLeaseTokenMismatch
+				}
+
+				assert.Equal(t, "test_lease", opts.LeaseID())
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	_, err := client.Release(context.Background(), &relayv1.ReleaseRequest{Fingerprint: "test_fingerprint", LeaseId: "test_lease", LeaseToken: "test_lease_token"})
+	assert.NoError(t, err)
+
+	_, err = client.Release(context.Background(), &relayv1.ReleaseRequest{Fingerprint: "test_fingerprint", LeaseId: "test_lease", LeaseToken: "other_lease_token"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Release(context.Background(), &relayv1.ReleaseRequest{Fingerprint: "other_fingerprint"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCWatchLease(t *testing.T) {
+	ttl := int64(2)
+	heartbeats := make(chan struct{}, 10)
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				assert.True(t, opts.ExtendOnly())
+				assert.Equal(t, &db.NodeMetadata{Hostname: "build-01"}, opts.Metadata())
+
+				heartbeats <- struct{}{}
+
+				return &licenses.LicenseOperationResult{
+					Node:   &db.Node{Ttl: &ttl},
+					Status: licenses.OperationStatusExtended,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchLease(ctx, &relayv1.WatchLeaseRequest{
+		Fingerprint: "test_fingerprint",
+		Metadata:    &relayv1.NodeMetadata{Hostname: "build-01"},
+	})
+	require.NoError(t, err)
+
+	// the lease should be heartbeat at half its ttl for as long as the stream is open
+	for range 2 {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+
+		assert.Equal(t, ttl, event.ExpiresIn)
+	}
+
+	assert.Len(t, heartbeats, 2)
+
+	header, err := stream.Header()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, header.Get("relay-clock"))
+}
+
+func TestGRPCWatchLease_NotFound(t *testing.T) {
+	var heartbeats int
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				heartbeats++
+
+				// the lease is lost e.g. released by an admin after the first heartbeat
+				if heartbeats > 1 {
+					return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, nil
+				}
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusExtended}, nil
+			},
+		},
+	)
+
+	cfg := srv.Config()
+	cfg.TTL = 1 * time.Second
+
+	client := newGRPCClient(t, srv)
+
+	stream, err := client.WatchLease(context.Background(), &relayv1.WatchLeaseRequest{Fingerprint: "test_fingerprint"})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.NotErrorIs(t, err, io.EOF)
+}
+
+func TestGRPC_RateLimit(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.FingerprintRateLimit = server.RateLimit{Rate: 1, Burst: 1}
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusExtended}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+
+	// the rest api shares the same buckets
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.Use(server.RateLimitMiddleware(cfg, srv.Metrics(), srv.RateLimiters()))
+
+	t.Run("fingerprint", func(t *testing.T) {
+		_, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "a"})
+		require.NoError(t, err)
+
+		var trailer metadata.MD
+
+		_, err = client.Extend(context.Background(), &relayv1.ExtendRequest{Fingerprint: "a"}, grpc.Trailer(&trailer))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"1"}, trailer.Get("retry-after"))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/a", nil))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	})
+
+	t.Run("shared with rest", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/b", nil))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+
+		_, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "b"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		stream, err := client.WatchLease(context.Background(), &relayv1.WatchLeaseRequest{Fingerprint: "a"})
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"1"}, stream.Trailer().Get("retry-after"))
+	})
+
+	t.Run("ip", func(t *testing.T) {
+		cfg.FingerprintRateLimit.Rate = 0
+		cfg.IPRateLimit = server.RateLimit{Rate: 1, Burst: 1}
+
+		// in-memory connections share a single address, the same as httptest requests
+		_, err := client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "c"})
+		require.NoError(t, err)
+
+		_, err = client.Claim(context.Background(), &relayv1.ClaimRequest{Fingerprint: "d"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	expected := `
+# HELP relay_throttled_requests_total Total number of requests throttled by the rate limiter.
+# TYPE relay_throttled_requests_total counter
+relay_throttled_requests_total{by="fingerprint"} 4
+relay_throttled_requests_total{by="ip"} 1
+`
+
+	err := testutil.GatherAndCompare(srv.Metrics().Registry(), strings.NewReader(expected), "relay_throttled_requests_total")
+	assert.NoError(t, err)
+}
+
+func TestGRPCClaim_Signature(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.SigningKey = key
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				if fingerprint != "test_fingerprint" {
+					return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, nil
+				}
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	client := newGRPCClient(t, srv)
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	var header metadata.MD
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "relay-nonce", "test_nonce")
+
+	resp, err := client.Claim(ctx, &relayv1.ClaimRequest{Fingerprint: "test_fingerprint"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(resp)
+	require.NoError(t, err)
+
+	sig := header.Get("relay-signature")
+	require.Len(t, sig, 1)
+	assert.NotEmpty(t, header.Get("relay-clock"))
+
+	env := server.Envelope{
+		Method: server.GRPCSignatureMethod,
+		Path:   relayv1.Relay_Claim_FullMethodName,
+		Status: int(codes.OK),
+		Nonce:  "test_nonce",
+		Body:   body,
+	}
+
+	_, err = server.VerifyEnvelopeSignature(publicKey, sig[0], env)
+	assert.NoError(t, err)
+
+	// the response can't be replayed for another request
+	replayed := env
+	replayed.Nonce = "other_nonce"
+
+	_, err = server.VerifyEnvelopeSignature(publicKey, sig[0], replayed)
+	assert.ErrorIs(t, err, server.ErrSignatureInvalid)
+
+	// errors are signed over their status message
+	_, err = client.Claim(ctx, &relayv1.ClaimRequest{Fingerprint: "other_fingerprint"}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = server.VerifyEnvelopeSignature(publicKey, header.Get("relay-signature")[0], server.Envelope{
+		Method: server.GRPCSignatureMethod,
+		Path:   relayv1.Relay_Claim_FullMethodName,
+		Status: int(codes.ResourceExhausted),
+		Nonce:  "test_nonce",
+		Body:   []byte(status.Convert(err).Message()),
+	})
+	assert.NoError(t, err)
+}
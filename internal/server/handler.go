@@ -1,23 +1,48 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
 	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/logger"
 )
 
 type RequestBodyPayload struct {
 	Fingerprint string `json:"fingerprint"`
+	TTL         *int64 `json:"ttl"`
+
+	// optional metadata describing the node, stored on the node on every claim
+	NodeMetadata
+}
+
+// NodeMetadata is the API representation of a node's reported metadata
+type NodeMetadata struct {
+	Hostname   *string           `json:"hostname"`
+	Platform   *string           `json:"platform"`
+	Username   *string           `json:"username"`
+	AppName    *string           `json:"app_name"`
+	AppVersion *string           `json:"app_version"`
+	Labels     map[string]string `json:"labels"`
 }
 
 type ClaimLicenseResponse struct {
 	LicenseFile []byte `json:"license_file"`
 	LicenseKey  string `json:"license_key"`
+	LeaseToken  string `json:"lease_token"`
 	ExpiresAt   int64  `json:"expires_at"`
 	ExpiresIn   int64  `json:"expires_in"`
 }
@@ -27,6 +52,48 @@ type ExtendLicenseResponse struct {
 	ExpiresIn int64 `json:"expires_in"`
 }
 
+type NodeResponse struct {
+	Fingerprint     string        `json:"fingerprint"`
+	LeaseID         *string       `json:"lease_id"`
+	LicenseID       string        `json:"license_id"`
+	Pool            *string       `json:"pool"`
+	LastHeartbeatAt *int64        `json:"last_heartbeat_at"`
+	ExpiresAt       *int64        `json:"expires_at"`
+	ExpiresIn       *int64        `json:"expires_in"`
+	Metadata        *NodeMetadata `json:"metadata"`
+}
+
+type SigningKeyResponse struct {
+	Alg       string `json:"alg"`
+	PublicKey string `json:"public_key"`
+}
+
+type ListNodesResponse struct {
+	Nodes  []NodeResponse `json:"nodes"`
+	Limit  int64          `json:"limit"`
+	Offset int64          `json:"offset"`
+}
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 100
+)
+
+const (
+	maxMetadataLength = 255
+	maxLabels         = 32
+	maxLabelLength    = 64
+)
+
+// waitRetryInterval is how often the head of a wait queue retries its claim, in case a
+// license was freed without a notification e.g. by another relay process
+const waitRetryInterval = 5 * time.Second
+
+var (
+	errUnsupportedPool = errors.New("unsupported pool header")
+	errQueueClosed     = errors.New("wait queue is closed")
+)
+
 type Handler interface {
 	RegisterRoutes(r *mux.Router)
 }
@@ -35,43 +102,213 @@ type handler struct {
 	manager licenses.Manager
 	config  *Config
 	server  Server
+	metrics *Metrics
+	queue   *WaitQueue
 }
 
 func NewHandler(server Server) Handler {
+	return newHandler(server)
+}
+
+func newHandler(server Server) *handler {
 	return &handler{
 		manager: server.Manager(),
 		config:  server.Config(),
 		server:  server,
+		metrics: server.Metrics(),
+		queue:   server.Queue(),
 	}
 }
 
 func (h *handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/v1/health", h.HealthCheck).Methods("GET")
+	r.HandleFunc("/v1/ready", h.Ready).Methods("GET")
+	r.HandleFunc("/v1/openapi.json", h.OpenAPI).Methods("GET")
+	r.HandleFunc("/v1/signing-key", h.GetSigningKey).Methods("GET")
+	r.Handle("/metrics", h.metrics.Handler()).Methods("GET")
+	r.HandleFunc("/v1/nodes", h.ListNodes).Methods("GET")
+	r.HandleFunc("/v1/nodes/{fingerprint}", h.GetNode).Methods("GET")
 	r.HandleFunc("/v1/nodes/{fingerprint}", h.ClaimLicense).Methods("PUT")
 	r.HandleFunc("/v1/nodes/{fingerprint}", h.ReleaseLicense).Methods("DELETE")
+	r.HandleFunc("/v1/nodes/{fingerprint}/leases/{lease_id}", h.GetNode).Methods("GET")
+	r.HandleFunc("/v1/nodes/{fingerprint}/leases/{lease_id}", h.ClaimLicense).Methods("PUT")
+	r.HandleFunc("/v1/nodes/{fingerprint}/leases/{lease_id}", h.ReleaseLicense).Methods("DELETE")
+	r.HandleFunc("/v1/events", h.StreamEvents).Methods("GET").Name(eventsRouteName)
+
+	admin := r.PathPrefix("/v1/admin").Subrouter()
+	admin.Use(AdminAuthMiddleware(h.config))
+	admin.HandleFunc("/licenses", h.AdminListLicenses).Methods("GET")
+	admin.HandleFunc("/licenses", h.AdminAddLicense).Methods("POST")
+	admin.HandleFunc("/licenses/{id}", h.AdminGetLicense).Methods("GET")
+	admin.HandleFunc("/licenses/{id}", h.AdminRemoveLicense).Methods("DELETE")
+	admin.HandleFunc("/pools", h.AdminListPools).Methods("GET")
+	admin.HandleFunc("/pools", h.AdminCreatePool).Methods("POST")
+	admin.HandleFunc("/pools/{pool}", h.AdminDeletePool).Methods("DELETE")
+	admin.HandleFunc("/nodes/{fingerprint}", h.AdminReleaseLicense).Methods("DELETE")
+	admin.HandleFunc("/nodes/{fingerprint}/leases/{lease_id}", h.AdminReleaseLicense).Methods("DELETE")
 }
 
 func (h *handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// GetSigningKey publishes the public key for verifying Ed25519 response signatures
+func (h *handler) GetSigningKey(w http.ResponseWriter, r *http.Request) {
+	publicKey := NewSigner(h.config).PublicKey()
+	if publicKey == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "signing key is not configured"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(SigningKeyResponse{
+		Alg:       SignatureAlgorithmEd25519,
+		PublicKey: hex.EncodeToString(publicKey),
+	})
+}
+
 func (h *handler) ClaimLicense(w http.ResponseWriter, r *http.Request) {
 	fingerprint := mux.Vars(r)["fingerprint"]
-	pool := h.config.Pool
 
-	if p := r.Header.Get("Relay-Pool"); p != "" {
-		if pool != nil && *pool != p {
+	if err := h.verifyFingerprint(r.TLS, fingerprint); err != nil {
+		logger.Warn("fingerprint rejected", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr, "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.requestPool(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+		return
+	}
+
+	if err := h.verifyAPIToken(r.Context(), r.Header.Get("Authorization"), pool); err != nil {
+		writeAPITokenError(w, err, fingerprint, pool, r.RemoteAddr)
+
+		return
+	}
+
+	// clients connected over a unix socket have no ip address, where access is instead
+	// controlled by the socket's mode and owner
+	if !unixSocketRequest(r) {
+		ip := clientIP(h.config, r)
+
+		if err := h.manager.CheckPoolAccess(r.Context(), pool, net.ParseIP(ip)); err != nil {
+			if errors.Is(err, licenses.ErrAccessDenied) {
+				logger.Warn("pool access denied", "nodeFingerprint", fingerprint, "pool", pool, "ip", ip, "remote_addr", r.RemoteAddr)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "access to pool is denied"})
+				return
+			}
+
+			logger.Error("failed to check pool access", "nodeFingerprint", fingerprint, "pool", pool, "error", err)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to check pool access"})
+			return
+		}
+	}
+
+	body, err := requestBody(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	opts := []licenses.LeaseOptionFunc{
+		licenses.WithLeaseID(mux.Vars(r)["lease_id"]),
+		licenses.WithLeaseToken(r.Header.Get("Relay-Lease-Token")),
+	}
+
+	if body.TTL != nil {
+		if *body.TTL <= 0 {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "ttl must be a positive integer"})
 			return
 		}
 
-		pool = &p
+		opts = append(opts, licenses.WithTTL(h.requestTTL(r.Context(), pool, fingerprint, *body.TTL)))
+	}
+
+	metadata, err := body.nodeMetadata()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if metadata != nil {
+		opts = append(opts, licenses.WithMetadata(metadata))
 	}
 
-	result, err := h.manager.ClaimLicense(r.Context(), pool, fingerprint)
+	wait, err := requestWait(r, h.config.MaxWait)
 	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// new claims can't jump ahead of claims already waiting on the pool, but existing
+	// leases can still be extended
+	claimOpts := opts
+	if h.queue.Len(pool) > 0 {
+		claimOpts = append(slices.Clone(opts), licenses.WithExtendOnly())
+	}
+
+	result, err := h.manager.ClaimLicense(r.Context(), pool, fingerprint, claimOpts...)
+	if err == nil && result.Status == licenses.OperationStatusNoLicensesAvailable && wait > 0 {
+		var position int
+
+		result, position, err = h.waitForLicense(r.Context(), pool, fingerprint, wait, opts)
+		w.Header().Set("Relay-Queue-Position", strconv.Itoa(position))
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Debug("client disconnected while waiting for license", "nodeFingerprint", fingerprint)
+
+			return
+		}
+
+		if errors.Is(err, errQueueClosed) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "server is shutting down"})
+			return
+		}
+
+		if errors.Is(err, licenses.ErrLeaseTokenMismatch) {
+			logger.Warn("lease token rejected", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid lease token"})
+			return
+		}
+
+		if errors.Is(err, licenses.ErrTooManyLeases) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "node has reached its maximum number of leases"})
+			return
+		}
+
 		logger.Error("failed to claim license", "error", err)
 
 		if errors.Is(err, licenses.ErrBadPool) {
@@ -87,6 +324,13 @@ func (h *handler) ClaimLicense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.metrics.ObserveClaim(pool, result.Status)
+
+	ttl := h.config.TTL
+	if result.Node != nil {
+		ttl = result.Node.LeaseTTL(h.config.TTL)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	switch result.Status {
@@ -96,16 +340,17 @@ func (h *handler) ClaimLicense(w http.ResponseWriter, r *http.Request) {
 			resp := ClaimLicenseResponse{
 				LicenseFile: result.License.File,
 				LicenseKey:  result.License.Key,
-				ExpiresAt:   time.Now().Add(h.config.TTL).Unix(),
-				ExpiresIn:   int64(h.config.TTL.Seconds()),
+				LeaseToken:  result.LeaseToken,
+				ExpiresAt:   time.Now().Add(ttl).Unix(),
+				ExpiresIn:   int64(ttl.Seconds()),
 			}
 			_ = json.NewEncoder(w).Encode(resp)
 		}
 	case licenses.OperationStatusExtended:
 		w.WriteHeader(http.StatusAccepted)
 		resp := ExtendLicenseResponse{
-			ExpiresAt: time.Now().Add(h.config.TTL).Unix(),
-			ExpiresIn: int64(h.config.TTL.Seconds()),
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			ExpiresIn: int64(ttl.Seconds()),
 		}
 		_ = json.NewEncoder(w).Encode(resp)
 	case licenses.OperationStatusConflict:
@@ -125,21 +370,44 @@ func (h *handler) ClaimLicense(w http.ResponseWriter, r *http.Request) {
 
 func (h *handler) ReleaseLicense(w http.ResponseWriter, r *http.Request) {
 	fingerprint := mux.Vars(r)["fingerprint"]
-	pool := h.config.Pool
 
-	if p := r.Header.Get("Relay-Pool"); p != "" {
-		if pool != nil && *pool != p {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
-			return
-		}
+	if err := h.verifyFingerprint(r.TLS, fingerprint); err != nil {
+		logger.Warn("fingerprint rejected", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr, "error", err)
 
-		pool = &p
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
 	}
 
-	result, err := h.manager.ReleaseLicense(r.Context(), pool, fingerprint)
+	pool, err := h.requestPool(r)
 	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+		return
+	}
+
+	if err := h.verifyAPIToken(r.Context(), r.Header.Get("Authorization"), pool); err != nil {
+		writeAPITokenError(w, err, fingerprint, pool, r.RemoteAddr)
+
+		return
+	}
+
+	result, err := h.manager.ReleaseLicense(r.Context(), pool, fingerprint,
+		licenses.WithLeaseID(mux.Vars(r)["lease_id"]),
+		licenses.WithLeaseToken(r.Header.Get("Relay-Lease-Token")),
+	)
+	if err != nil {
+		if errors.Is(err, licenses.ErrLeaseTokenMismatch) {
+			logger.Warn("lease token rejected", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid lease token"})
+			return
+		}
+
 		logger.Error("failed to release license", "error", err)
 
 		if errors.Is(err, licenses.ErrBadPool) {
@@ -155,10 +423,14 @@ func (h *handler) ReleaseLicense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.metrics.ObserveRelease(pool, result.Status)
+
 	w.Header().Set("Content-Type", "application/json")
 
 	switch result.Status {
 	case licenses.OperationStatusSuccess:
+		h.queue.Notify(pool)
+
 		w.WriteHeader(http.StatusNoContent)
 	case licenses.OperationStatusNotFound:
 		w.WriteHeader(http.StatusNotFound)
@@ -168,3 +440,513 @@ func (h *handler) ReleaseLicense(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unknown release status"})
 	}
 }
+
+func (h *handler) ListNodes(w http.ResponseWriter, r *http.Request) {
+	pool, err := h.requestPool(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+		return
+	}
+
+	if err := h.verifyAPIToken(r.Context(), r.Header.Get("Authorization"), pool); err != nil {
+		writeAPITokenError(w, err, "", pool, r.RemoteAddr)
+		return
+	}
+
+	limit, offset, err := pagination(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	leases, err := h.manager.ListLeases(r.Context(), pool, limit, offset, h.listOptions(pool)...)
+	if err != nil {
+		logger.Error("failed to list nodes", "error", err)
+
+		if errors.Is(err, licenses.ErrBadPool) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid pool header"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to list nodes"})
+		return
+	}
+
+	pools, err := h.poolsByID(r)
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to list nodes"})
+		return
+	}
+
+	resp := ListNodesResponse{
+		Nodes:  make([]NodeResponse, 0, len(leases)),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	for _, lease := range leases {
+		resp.Nodes = append(resp.Nodes, h.nodeResponse(lease, pools))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *handler) GetNode(w http.ResponseWriter, r *http.Request) {
+	fingerprint := mux.Vars(r)["fingerprint"]
+
+	if err := h.verifyFingerprint(r.TLS, fingerprint); err != nil {
+		logger.Warn("fingerprint rejected", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr, "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.requestPool(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported pool header"})
+		return
+	}
+
+	if err := h.verifyAPIToken(r.Context(), r.Header.Get("Authorization"), pool); err != nil {
+		writeAPITokenError(w, err, fingerprint, pool, r.RemoteAddr)
+		return
+	}
+
+	lease, err := h.manager.GetLease(r.Context(), pool, fingerprint, licenses.WithLeaseID(mux.Vars(r)["lease_id"]))
+	if err == nil && pool == nil && h.config.RequireAPITokens && lease.License.PoolID != nil {
+		// an API token without a pool can't read leases in other pools
+		err = fmt.Errorf("node %s: %w", fingerprint, licenses.ErrLeaseNotFound)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, licenses.ErrLeaseNotFound):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "lease not found"})
+		case errors.Is(err, licenses.ErrBadPool):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid pool header"})
+		default:
+			logger.Error("failed to get node", "error", err)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to get node"})
+		}
+
+		return
+	}
+
+	pools, err := h.poolsByID(r)
+	if err != nil {
+		logger.Error("failed to list pools", "error", err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to get node"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(h.nodeResponse(*lease, pools))
+}
+
+// waitForLicense queues a claim until a license is freed for it, the wait elapses, or
+// the client disconnects. It returns the claim's position in the queue, i.e. when it
+// was queued for a successful claim, or when it gave up otherwise.
+func (h *handler) waitForLicense(ctx context.Context, pool *string, fingerprint string, wait time.Duration, opts []licenses.LeaseOptionFunc) (*licenses.LicenseOperationResult, int, error) {
+	waiter, position := h.queue.Enqueue(pool)
+	defer h.queue.Remove(waiter)
+
+	logger.Debug("waiting for license", "nodeFingerprint", fingerprint, "pool", pool, "position", position, "wait", wait)
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	retry := time.NewTicker(waitRetryInterval)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, h.queue.Position(waiter), ctx.Err()
+		case <-h.queue.Done():
+			return nil, h.queue.Position(waiter), errQueueClosed
+		case <-timeout.C:
+			logger.Debug("timed out waiting for license", "nodeFingerprint", fingerprint, "pool", pool)
+
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, h.queue.Position(waiter), nil
+		case <-waiter.Ready():
+		case <-retry.C:
+			if !h.queue.IsHead(waiter) {
+				continue
+			}
+		}
+
+		result, err := h.manager.ClaimLicense(ctx, pool, fingerprint, opts...)
+		if err != nil {
+			return nil, h.queue.Position(waiter), err
+		}
+
+		if result.Status == licenses.OperationStatusNoLicensesAvailable {
+			continue
+		}
+
+		// more than one license may have been freed, so let the next waiter try too
+		h.queue.Remove(waiter)
+		h.queue.Notify(pool)
+
+		return result, position, nil
+	}
+}
+
+// requestPool resolves the pool for a request, using the Relay-Pool header if provided
+// and falling back to the server's configured pool
+func (h *handler) requestPool(r *http.Request) (*string, error) {
+	return h.resolvePool(r.Header.Get("Relay-Pool"))
+}
+
+// resolvePool resolves a requested pool, where an empty pool falls back to the server's
+// configured pool, and any other pool must match it
+func (h *handler) resolvePool(p string) (*string, error) {
+	pool := h.config.Pool
+
+	if p != "" {
+		if pool != nil && *pool != p {
+			return nil, errUnsupportedPool
+		}
+
+		pool = &p
+	}
+
+	return pool, nil
+}
+
+// verifyFingerprint ensures a node can only act on its own fingerprint when fingerprints
+// are bound to client certificates, a no-op otherwise
+func (h *handler) verifyFingerprint(state *tls.ConnectionState, fingerprint string) error {
+	if !h.config.TLSClientFingerprint {
+		return nil
+	}
+
+	subject, err := ClientCertFingerprint(state)
+	if err != nil {
+		return err
+	}
+
+	if subject != fingerprint {
+		return errFingerprintMismatch
+	}
+
+	return nil
+}
+
+// verifyAPIToken ensures the request's authorization presents an API token scoped to the
+// pool when API tokens are required, a no-op otherwise
+func (h *handler) verifyAPIToken(ctx context.Context, authorization string, pool *string) error {
+	if !h.config.RequireAPITokens {
+		return nil
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return licenses.ErrAPITokenInvalid
+	}
+
+	_, err := h.manager.AuthenticateAPIToken(ctx, pool, token)
+
+	return err
+}
+
+// listOptions scopes listing leases and events to the request's API token, where a token
+// without a pool can only list leases and events without a pool, not across all pools
+func (h *handler) listOptions(pool *string) []licenses.ListOptionFunc {
+	if h.config.RequireAPITokens && pool == nil {
+		return []licenses.ListOptionFunc{licenses.WithoutPool()}
+	}
+
+	return nil
+}
+
+// apiTokenError logs a rejected API token, returning the HTTP status and message to
+// present to the client
+func apiTokenError(err error, fingerprint string, pool *string, remoteAddr string) (int, string) {
+	switch {
+	case errors.Is(err, licenses.ErrAPITokenPoolMismatch):
+		logger.Warn("api token rejected", "nodeFingerprint", fingerprint, "pool", pool, "remote_addr", remoteAddr, "error", err)
+
+		return http.StatusForbidden, "api token is not valid for pool"
+	case errors.Is(err, licenses.ErrAPITokenInvalid):
+		logger.Warn("api token rejected", "nodeFingerprint", fingerprint, "pool", pool, "remote_addr", remoteAddr, "error", err)
+
+		return http.StatusUnauthorized, "unauthorized"
+	default:
+		logger.Error("failed to verify api token", "nodeFingerprint", fingerprint, "error", err)
+
+		return http.StatusInternalServerError, "failed to verify api token"
+	}
+}
+
+// writeAPITokenError writes the response for a request rejected by verifyAPIToken
+func writeAPITokenError(w http.ResponseWriter, err error, fingerprint string, pool *string, remoteAddr string) {
+	code, message := apiTokenError(err, fingerprint, pool, remoteAddr)
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="relay"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// requestTTL clamps a node's requested TTL in seconds to the pool's TTL bounds, where
+// the max TTL is raised to the pool's own TTL when one is set via pools set --ttl
+func (h *handler) requestTTL(ctx context.Context, pool *string, fingerprint string, secs int64) time.Duration {
+	requested := time.Duration(secs) * time.Second
+
+	bounds := h.config.TTLBoundsFor(pool)
+	if ttl := h.poolTTL(ctx, pool); ttl != nil {
+		bounds.Max = max(bounds.Max, *ttl)
+	}
+
+	ttl := bounds.Clamp(requested)
+	if ttl != requested {
+		logger.Debug("clamped requested ttl", "nodeFingerprint", fingerprint, "requested", requested, "ttl", ttl)
+	}
+
+	return ttl
+}
+
+// poolTTL returns the TTL stored for a pool, if any
+func (h *handler) poolTTL(ctx context.Context, pool *string) *time.Duration {
+	if pool == nil {
+		return nil
+	}
+
+	pools, err := h.manager.GetPools(ctx)
+	if err != nil {
+		logger.Warn("failed to lookup pool ttl", "pool", *pool, "error", err)
+
+		return nil
+	}
+
+	for _, p := range pools {
+		if p.Name == *pool && p.Ttl != nil {
+			ttl := time.Duration(*p.Ttl) * time.Second
+
+			return &ttl
+		}
+	}
+
+	return nil
+}
+
+// poolsByID returns a map of pool IDs to pools, used to present a lease's pool
+func (h *handler) poolsByID(r *http.Request) (map[int64]db.Pool, error) {
+	pools, err := h.manager.GetPools(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]db.Pool, len(pools))
+	for _, p := range pools {
+		byID[p.ID] = p
+	}
+
+	return byID, nil
+}
+
+func (h *handler) nodeResponse(lease db.Lease, pools map[int64]db.Pool) NodeResponse {
+	resp := NodeResponse{
+		Fingerprint:     lease.Node.Fingerprint,
+		LicenseID:       lease.License.Guid,
+		LastHeartbeatAt: lease.Node.LastHeartbeatAt,
+	}
+
+	if lease.Node.LeaseID != "" {
+		resp.LeaseID = &lease.Node.LeaseID
+	}
+
+	heartbeat := h.config.EnabledHeartbeat
+
+	if lease.License.PoolID != nil {
+		if pool, ok := pools[*lease.License.PoolID]; ok {
+			resp.Pool = &pool.Name
+
+			if pool.Heartbeat != nil {
+				heartbeat = *pool.Heartbeat
+			}
+		}
+	}
+
+	// leases only expire when heartbeats are enabled for the lease's pool
+	if heartbeat && lease.Node.LastHeartbeatAt != nil {
+		expiresAt := time.Unix(*lease.Node.LastHeartbeatAt, 0).Add(lease.Node.LeaseTTL(h.config.TTL))
+		expiresIn := max(int64(time.Until(expiresAt).Seconds()), 0)
+		ts := expiresAt.Unix()
+
+		resp.ExpiresAt = &ts
+		resp.ExpiresIn = &expiresIn
+	}
+
+	resp.Metadata = nodeMetadataResponse(lease.Node.NodeMetadata())
+
+	return resp
+}
+
+func nodeMetadataResponse(metadata *db.NodeMetadata) *NodeMetadata {
+	if metadata == nil {
+		return nil
+	}
+
+	return &NodeMetadata{
+		Hostname:   nonEmpty(metadata.Hostname),
+		Platform:   nonEmpty(metadata.Platform),
+		Username:   nonEmpty(metadata.Username),
+		AppName:    nonEmpty(metadata.AppName),
+		AppVersion: nonEmpty(metadata.AppVersion),
+		Labels:     metadata.Labels,
+	}
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// requestBody decodes the optional JSON request body, where an empty body is allowed
+func requestBody(r *http.Request) (*RequestBodyPayload, error) {
+	var body RequestBodyPayload
+
+	if r.Body == nil {
+		return &body, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &body, nil
+}
+
+// nodeMetadata validates the reported metadata, returning it for storage or nil if none
+// was reported
+func (p *NodeMetadata) nodeMetadata() (*db.NodeMetadata, error) {
+	if p.Hostname == nil && p.Platform == nil && p.Username == nil && p.AppName == nil && p.AppVersion == nil && p.Labels == nil {
+		return nil, nil
+	}
+
+	metadata := &db.NodeMetadata{Labels: p.Labels}
+
+	for _, field := range []struct {
+		name  string
+		value *string
+		dest  *string
+	}{
+		{"hostname", p.Hostname, &metadata.Hostname},
+		{"platform", p.Platform, &metadata.Platform},
+		{"username", p.Username, &metadata.Username},
+		{"app_name", p.AppName, &metadata.AppName},
+		{"app_version", p.AppVersion, &metadata.AppVersion},
+	} {
+		if field.value == nil {
+			continue
+		}
+
+		if len(*field.value) > maxMetadataLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", field.name, maxMetadataLength)
+		}
+
+		*field.dest = *field.value
+	}
+
+	if len(p.Labels) > maxLabels {
+		return nil, fmt.Errorf("labels must have at most %d entries", maxLabels)
+	}
+
+	for k, v := range p.Labels {
+		if k == "" || len(k) > maxLabelLength {
+			return nil, fmt.Errorf("label keys must be between 1 and %d characters", maxLabelLength)
+		}
+
+		if len(v) > maxMetadataLength {
+			return nil, fmt.Errorf("label values must be at most %d characters", maxMetadataLength)
+		}
+	}
+
+	return metadata, nil
+}
+
+// requestWait parses the wait query parameter for claims, as either a duration or a
+// number of seconds, capped to the max wait where a zero max disables waiting
+func requestWait(r *http.Request, maxWait time.Duration) (time.Duration, error) {
+	v := r.URL.Query().Get("wait")
+	if v == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(v)
+	if err != nil {
+		secs, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.New("wait must be a duration e.g. 60s")
+		}
+
+		wait = time.Duration(secs) * time.Second
+	}
+
+	if wait < 0 {
+		return 0, errors.New("wait must be a positive duration")
+	}
+
+	return min(wait, maxWait), nil
+}
+
+// pagination parses the limit and offset query parameters for list endpoints
+func pagination(r *http.Request) (int64, int64, error) {
+	var (
+		limit  int64 = defaultPageLimit
+		offset int64 = 0
+		err    error
+	)
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
@@ -2,14 +2,20 @@ package server_test
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -21,6 +27,7 @@ import (
 	"github.com/keygen-sh/keygen-relay/internal/testutils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClaimLicense_NewNode_Success(t *testing.T) {
@@ -97,6 +104,288 @@ func TestClaimLicense_ExistingNode_Extended(t *testing.T) {
 	assert.Equal(t, int64(cfg.TTL.Seconds()), resp.ExpiresIn)
 }
 
+func TestClaimLicense_RequestedTTL(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.MinTTL = 30 * time.Second
+	cfg.MaxTTL = 1 * time.Hour
+	cfg.PoolTTLBounds = map[string]server.TTLBounds{
+		"prod": {Min: 1 * time.Minute, Max: 8 * time.Hour},
+	}
+
+	tests := []struct {
+		name     string
+		pool     string
+		body     string
+		expected *time.Duration
+	}{
+		{name: "no body", body: "", expected: nil},
+		{name: "no ttl", body: `{}`, expected: nil},
+		{name: "within bounds", body: `{"ttl":300}`, expected: ptr(5 * time.Minute)},
+		{name: "below minimum", body: `{"ttl":5}`, expected: ptr(30 * time.Second)},
+		{name: "above maximum", body: `{"ttl":86400}`, expected: ptr(1 * time.Hour)},
+		{name: "pool below minimum", pool: "prod", body: `{"ttl":30}`, expected: ptr(1 * time.Minute)},
+		{name: "pool above maximum", pool: "prod", body: `{"ttl":86400}`, expected: ptr(8 * time.Hour)},
+		{name: "pool ttl above server maximum", pool: "long", body: `{"ttl":14400}`, expected: ptr(4 * time.Hour)},
+		{name: "above pool ttl", pool: "long", body: `{"ttl":86400}`, expected: ptr(12 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested *time.Duration
+
+			srv := testutils.NewMockServer(
+				cfg,
+				&testutils.FakeManager{
+					GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+						return []db.Pool{
+							{ID: 1, Name: "prod"},
+							{ID: 2, Name: "long", Ttl: ptr(int64(12 * time.Hour / time.Second))},
+						}, nil
+					},
+					ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+						requested = opts.TTL()
+
+						var ttl *int64
+						if requested != nil {
+							secs := int64(requested.Seconds())
+							ttl = &secs
+						}
+
+						return &licenses.LicenseOperationResult{
+							License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+							Node:    &db.Node{Fingerprint: fingerprint, Ttl: ttl},
+							Status:  licenses.OperationStatusCreated,
+						}, nil
+					},
+				},
+			)
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", strings.NewReader(tt.body))
+			if tt.pool != "" {
+				req.Header.Set("Relay-Pool", tt.pool)
+			}
+			rr := httptest.NewRecorder()
+
+			router := mux.NewRouter()
+			server.NewHandler(srv).RegisterRoutes(router)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			assert.Equal(t, tt.expected, requested)
+
+			var resp server.ClaimLicenseResponse
+			err := json.NewDecoder(rr.Body).Decode(&resp)
+			assert.NoError(t, err)
+
+			expected := cfg.TTL
+			if tt.expected != nil {
+				expected = *tt.expected
+			}
+
+			assert.Equal(t, int64(expected.Seconds()), resp.ExpiresIn)
+		})
+	}
+}
+
+func TestClaimLicense_InvalidBody(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	for _, body := range []string{`{"ttl":"1h"}`, `{"ttl":0}`, `{"ttl":-60}`, `not json`} {
+		req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		server.NewHandler(srv).RegisterRoutes(router)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestClaimLicense_Metadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected *db.NodeMetadata
+	}{
+		{name: "no body", body: "", expected: nil},
+		{name: "no metadata", body: `{"ttl":60}`, expected: nil},
+		{name: "metadata", body: `{"hostname":"build-01","platform":"linux/amd64","username":"ci","app_name":"builder","app_version":"1.2.3","labels":{"team":"platform"}}`, expected: &db.NodeMetadata{Hostname: "build-01", Platform: "linux/amd64", Username: "ci", AppName: "builder", AppVersion: "1.2.3", Labels: map[string]string{"team": "platform"}}},
+		{name: "partial metadata", body: `{"hostname":"build-01"}`, expected: &db.NodeMetadata{Hostname: "build-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metadata *db.NodeMetadata
+
+			srv := testutils.NewMockServer(
+				server.NewConfig(),
+				&testutils.FakeManager{
+					ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+						metadata = opts.Metadata()
+
+						return &licenses.LicenseOperationResult{
+							License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+							Status:  licenses.OperationStatusCreated,
+						}, nil
+					},
+				},
+			)
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+
+			router := mux.NewRouter()
+			server.NewHandler(srv).RegisterRoutes(router)
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			assert.Equal(t, tt.expected, metadata)
+		})
+	}
+}
+
+func TestClaimLicense_InvalidMetadata(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	labels := make(map[string]string)
+	for i := range 33 {
+		labels[fmt.Sprintf("label_%d", i)] = "value"
+	}
+
+	tooManyLabels, err := json.Marshal(map[string]any{"labels": labels})
+	require.NoError(t, err)
+
+	for _, body := range []string{
+		`{"hostname":"` + strings.Repeat("a", 256) + `"}`,
+		`{"app_version":"` + strings.Repeat("1", 256) + `"}`,
+		`{"labels":{"":"value"}}`,
+		`{"labels":{"` + strings.Repeat("k", 65) + `":"value"}}`,
+		`{"labels":{"team":"` + strings.Repeat("v", 256) + `"}}`,
+		`{"labels":{"team":1}}`,
+		string(tooManyLabels),
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		server.NewHandler(srv).RegisterRoutes(router)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestClaimLicense_LeaseToken(t *testing.T) {
+	var token *string
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				token = opts.LeaseToken()
+
+				return &licenses.LicenseOperationResult{
+					License:    &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:     licenses.OperationStatusCreated,
+					LeaseToken: "test_lease_token",
+				}, nil
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	req.Header.Set("Relay-Lease-Token", "presented_lease_token")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "presented_lease_token", *token)
+
+	var resp server.ClaimLicenseResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_lease_token", resp.LeaseToken)
+}
+
+func TestClaimLicense_LeaseTokenMismatch(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return nil, fmt.Errorf("node %s: %w", fingerprint, licenses.ErrLeaseTokenMismatch)
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid lease token")
+}
+
+func TestClaimLicense_SubLease(t *testing.T) {
+	var leaseID string
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				leaseID = opts.LeaseID()
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint/leases/job_1", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "job_1", leaseID)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Empty(t, leaseID)
+}
+
+func TestClaimLicense_TooManyLeases(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return nil, fmt.Errorf("node %s: %w", fingerprint, licenses.ErrTooManyLeases)
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint/leases/job_2", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "maximum number of leases")
+}
+
 func TestClaimLicense_HeartbeatDisabled_Conflict(t *testing.T) {
 	srv := testutils.NewMockServer(
 		server.NewConfig(),
@@ -220,6 +509,58 @@ func TestReleaseLicense_NotFound(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "claim not found")
 }
 
+func TestReleaseLicense_LeaseTokenMismatch(t *testing.T) {
+	var token *string
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ReleaseLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				token = opts.LeaseToken()
+
+				return nil, fmt.Errorf("node %s: %w", fingerprint, licenses.ErrLeaseTokenMismatch)
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/nodes/test_fingerprint", nil)
+	req.Header.Set("Relay-Lease-Token", "stolen_lease_token")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid lease token")
+	assert.Equal(t, "stolen_lease_token", *token)
+}
+
+func TestReleaseLicense_SubLease(t *testing.T) {
+	var leaseID string
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ReleaseLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+				leaseID = opts.LeaseID()
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/nodes/test_fingerprint/leases/job_1", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "job_1", leaseID)
+}
+
 func TestReleaseLicense_InternalServerError(t *testing.T) {
 	srv := testutils.NewMockServer(
 		server.NewConfig(),
@@ -243,58 +584,134 @@ func TestReleaseLicense_InternalServerError(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), "failed to release license")
 }
 
-func TestClaimLicense_Signature_Enabled(t *testing.T) {
-	secret := "test_secret"
-
+func TestGetNode_Success(t *testing.T) {
 	cfg := server.NewConfig()
-	cfg.SigningSecret = &secret
+	heartbeat := time.Now().Unix()
+	poolID := int64(1)
 
 	srv := testutils.NewMockServer(
 		cfg,
 		&testutils.FakeManager{
-			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
-				return &licenses.LicenseOperationResult{
-					License: &db.License{
-						File: []byte("test_license_file"),
-						Key:  "test_license_key",
-					},
-					Status: licenses.OperationStatusCreated,
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return &db.Lease{
+					Node:    db.Node{Fingerprint: fingerprint, LastHeartbeatAt: &heartbeat},
+					License: db.License{Guid: "test_license_guid", PoolID: &poolID},
 				}, nil
 			},
+			GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+				return []db.Pool{{ID: poolID, Name: "prod"}}, nil
+			},
 		},
 	)
 
 	handler := server.NewHandler(srv)
 
-	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
 	rr := httptest.NewRecorder()
 
 	router := mux.NewRouter()
-	router.Use(server.SigningMiddleware(cfg))
 	handler.RegisterRoutes(router)
 	router.ServeHTTP(rr, req)
 
-	sig := rr.Header().Get("Relay-Signature")
-	clock := rr.Header().Get("Relay-Clock")
+	assert.Equal(t, http.StatusOK, rr.Code)
 
-	assert.NotEmpty(t, sig)
-	assert.NotEmpty(t, clock)
+	var resp server.NodeResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_fingerprint", resp.Fingerprint)
+	assert.Equal(t, "test_license_guid", resp.LicenseID)
+	assert.Equal(t, "prod", *resp.Pool)
+	assert.Equal(t, heartbeat, *resp.LastHeartbeatAt)
+	assert.Equal(t, heartbeat+int64(cfg.TTL.Seconds()), *resp.ExpiresAt)
+	assert.InDelta(t, cfg.TTL.Seconds(), *resp.ExpiresIn, 1)
+}
 
-	assert.True(t, verifySignature(secret, sig, rr.Body.String()))
+func TestGetNode_Metadata(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return &db.Lease{
+					Node: db.Node{
+						Fingerprint: fingerprint,
+						Hostname:    ptr("build-01"),
+						AppName:     ptr("builder"),
+						Labels:      ptr(`{"team":"platform"}`),
+					},
+					License: db.License{Guid: "test_license_guid"},
+				}, nil
+			},
+		},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp server.NodeResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	require.NotNil(t, resp.Metadata)
+	assert.Equal(t, "build-01", *resp.Metadata.Hostname)
+	assert.Equal(t, "builder", *resp.Metadata.AppName)
+	assert.Nil(t, resp.Metadata.Platform)
+	assert.Equal(t, map[string]string{"team": "platform"}, resp.Metadata.Labels)
 }
 
-func TestClaimLicense_Signature_Disabled(t *testing.T) {
+func TestGetNode_SubLease(t *testing.T) {
+	heartbeat := time.Now().Unix()
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			GetLeaseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*db.Lease, error) {
+				return &db.Lease{
+					Node:    db.Node{Fingerprint: fingerprint, LeaseID: opts.LeaseID(), LastHeartbeatAt: &heartbeat},
+					License: db.License{Guid: "test_license_guid"},
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint/leases/job_1", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp server.NodeResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_fingerprint", resp.Fingerprint)
+	assert.Equal(t, "job_1", *resp.LeaseID)
+
+	// the primary lease has no lease id
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"lease_id":null`)
+}
+
+func TestGetNode_HeartbeatDisabled_NoExpiry(t *testing.T) {
 	cfg := server.NewConfig()
+	cfg.EnabledHeartbeat = false
+	heartbeat := time.Now().Unix()
+
 	srv := testutils.NewMockServer(
 		cfg,
 		&testutils.FakeManager{
-			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
-				return &licenses.LicenseOperationResult{
-					License: &db.License{
-						File: []byte("test_license_file"),
-						Key:  "test_license_key",
-					},
-					Status: licenses.OperationStatusCreated,
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return &db.Lease{
+					Node:    db.Node{Fingerprint: fingerprint, LastHeartbeatAt: &heartbeat},
+					License: db.License{Guid: "test_license_guid"},
 				}, nil
 			},
 		},
@@ -302,48 +719,907 @@ func TestClaimLicense_Signature_Disabled(t *testing.T) {
 
 	handler := server.NewHandler(srv)
 
-	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
 	rr := httptest.NewRecorder()
 
 	router := mux.NewRouter()
-	router.Use(server.SigningMiddleware(cfg))
 	handler.RegisterRoutes(router)
 	router.ServeHTTP(rr, req)
 
-	sig := rr.Header().Get("Relay-Signature")
-	clock := rr.Header().Get("Relay-Clock")
+	assert.Equal(t, http.StatusOK, rr.Code)
 
-	assert.Empty(t, sig)
-	assert.NotEmpty(t, clock)
+	var resp server.NodeResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Nil(t, resp.Pool)
+	assert.Nil(t, resp.ExpiresAt)
+	assert.Nil(t, resp.ExpiresIn)
 }
 
-func verifySignature(secret string, header string, body string) bool {
-	var t, v1 string
-
-	for _, part := range strings.Split(header, ",") {
-		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
+func TestGetNode_PoolHeartbeatDisabled_NoExpiry(t *testing.T) {
+	heartbeat := time.Now().Unix()
+	poolID := int64(1)
 
-		switch kv[0] {
-		case "t":
-			t = kv[1]
-		case "v1":
-			v1 = kv[1]
-		}
-	}
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return &db.Lease{
+					Node:    db.Node{Fingerprint: fingerprint, LastHeartbeatAt: &heartbeat},
+					License: db.License{Guid: "test_license_guid", PoolID: &poolID},
+				}, nil
+			},
+			GetPoolsFn: func(ctx context.Context) ([]db.Pool, error) {
+				return []db.Pool{{ID: poolID, Name: "ci", Heartbeat: ptr(false)}}, nil
+			},
+		},
+	)
 
-	if t == "" || v1 == "" {
-		return false
-	}
+	handler := server.NewHandler(srv)
 
-	mac := hmac.New(sha256.New, []byte(secret))
-	msg := fmt.Sprintf("%s.%s", t, body)
-	mac.Write([]byte(msg))
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
 
-	expected := make([]byte, hex.EncodedLen(mac.Size()))
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// the pool's setting overrides the server's default
+	var resp server.NodeResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", *resp.Pool)
+	assert.Nil(t, resp.ExpiresAt)
+	assert.Nil(t, resp.ExpiresIn)
+}
+
+func TestGetNode_NotFound(t *testing.T) {
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return nil, licenses.ErrLeaseNotFound
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/non_existent_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "lease not found")
+}
+
+func TestGetNode_UnsupportedPool(t *testing.T) {
+	pool := "prod"
+	cfg := server.NewConfig()
+	cfg.Pool = &pool
+
+	srv := testutils.NewMockServer(cfg, &testutils.FakeManager{})
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
+	req.Header.Set("Relay-Pool", "dev")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unsupported pool header")
+}
+
+func TestListNodes_Success(t *testing.T) {
+	var (
+		gotLimit  int64
+		gotOffset int64
+		gotPool   *string
+	)
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			ListLeasesFn: func(ctx context.Context, pool *string, limit int64, offset int64) ([]db.Lease, error) {
+				gotPool, gotLimit, gotOffset = pool, limit, offset
+
+				return []db.Lease{
+					{Node: db.Node{Fingerprint: "node_1"}, License: db.License{Guid: "license_1"}},
+					{Node: db.Node{Fingerprint: "node_2"}, License: db.License{Guid: "license_2"}},
+				}, nil
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes?limit=2&offset=4", nil)
+	req.Header.Set("Relay-Pool", "prod")
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "prod", *gotPool)
+	assert.Equal(t, int64(2), gotLimit)
+	assert.Equal(t, int64(4), gotOffset)
+
+	var resp server.ListNodesResponse
+	err := json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Nodes, 2)
+	assert.Equal(t, "node_1", resp.Nodes[0].Fingerprint)
+	assert.Equal(t, "license_2", resp.Nodes[1].LicenseID)
+	assert.Equal(t, int64(2), resp.Limit)
+	assert.Equal(t, int64(4), resp.Offset)
+}
+
+func TestListNodes_Empty(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"nodes":[],"limit":100,"offset":0}`, rr.Body.String())
+}
+
+func TestListNodes_InvalidPagination(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+	handler := server.NewHandler(srv)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	for _, query := range []string{"limit=0", "limit=1000", "limit=foo", "offset=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/nodes?"+query, nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes?offset=-1", nil)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Contains(t, rr.Body.String(), "offset must be a non-negative integer")
+}
+
+func TestClaimLicense_ClientFingerprint_NoClientCert(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TLSClientFingerprint = true
+
+	var called bool
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				called = true
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusCreated}, nil
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "client certificate required")
+	assert.False(t, called)
+}
+
+func TestReleaseLicense_ClientFingerprint_Mismatch(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.TLSClientFingerprint = true
+
+	var called bool
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ReleaseLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				called = true
+
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/nodes/other_fingerprint", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "test_fingerprint"}}},
+	}
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "fingerprint does not match client certificate")
+	assert.False(t, called)
+}
+
+func TestClaimLicense_Signature_Enabled(t *testing.T) {
+	secret := "test_secret"
+
+	cfg := server.NewConfig()
+	cfg.SigningSecret = &secret
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{
+						File: []byte("test_license_file"),
+						Key:  "test_license_key",
+					},
+					Status: licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	sig := rr.Header().Get("Relay-Signature")
+	clock := rr.Header().Get("Relay-Clock")
+
+	assert.NotEmpty(t, sig)
+	assert.NotEmpty(t, clock)
+
+	assert.True(t, verifySignature(secret, sig, rr.Body.String()))
+}
+
+func TestClaimLicense_Signature_KeyIDs(t *testing.T) {
+	secret := "test_secret"
+
+	cfg := server.NewConfig()
+	cfg.SigningSecret = &secret
+	cfg.SigningSecrets = []server.SigningSecret{
+		{KeyID: "old", Secret: "test_old_secret"},
+		{KeyID: "new", Secret: "test_new_secret"},
+	}
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	sig := rr.Header().Get("Relay-Signature")
+	body := rr.Body.String()
+
+	// nodes can verify using either secret while rolling to the new one
+	assert.True(t, verifySignature(secret, sig, body))
+	assert.True(t, verifySignatureWithKeyID("old", "test_old_secret", sig, body))
+	assert.True(t, verifySignatureWithKeyID("new", "test_new_secret", sig, body))
+	assert.False(t, verifySignatureWithKeyID("new", "test_old_secret", sig, body))
+	assert.False(t, verifySignatureWithKeyID("gone", "test_old_secret", sig, body))
+
+	// the old secret is removed once all nodes have rolled
+	cfg.SigningSecret = nil
+	cfg.SigningSecrets = cfg.SigningSecrets[1:]
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	sig = rr.Header().Get("Relay-Signature")
+	body = rr.Body.String()
+
+	assert.NotContains(t, sig, "kid=old")
+	assert.False(t, verifySignature(secret, sig, body))
+	assert.True(t, verifySignatureWithKeyID("new", "test_new_secret", sig, body))
+}
+
+func TestClaimLicense_Signature_Ed25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.SigningKey = key
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	// nodes can fetch the public key to verify signatures with
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/signing-key", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp server.SigningKeyResponse
+	err = json.NewDecoder(rr.Body).Decode(&resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "ed25519", resp.Alg)
+
+	publicKey, err := hex.DecodeString(resp.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, key.Public(), ed25519.PublicKey(publicKey))
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	ts, err := server.VerifySignature(publicKey, rr.Header().Get("Relay-Signature"), rr.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, rr.Header().Get("Relay-Clock"), strconv.FormatInt(ts.Unix(), 10))
+}
+
+func TestClaimLicense_Signature_Envelope(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.SigningKey = key
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	req.Header.Set("Relay-Nonce", "test_nonce")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	sig := rr.Header().Get("Relay-Signature")
+	env := server.Envelope{
+		Method: http.MethodPut,
+		Path:   "/v1/nodes/test_fingerprint",
+		Status: http.StatusCreated,
+		Nonce:  "test_nonce",
+		Body:   rr.Body.Bytes(),
+	}
+
+	_, err = server.VerifyEnvelopeSignature(key.Public().(ed25519.PublicKey), sig, env)
+	assert.NoError(t, err)
+
+	// the response can't be replayed to another node, or for another request
+	replayed := env
+	replayed.Path = "/v1/nodes/other_fingerprint"
+
+	_, err = server.VerifyEnvelopeSignature(key.Public().(ed25519.PublicKey), sig, replayed)
+	assert.ErrorIs(t, err, server.ErrSignatureInvalid)
+
+	replayed = env
+	replayed.Nonce = "other_nonce"
+
+	_, err = server.VerifyEnvelopeSignature(key.Public().(ed25519.PublicKey), sig, replayed)
+	assert.ErrorIs(t, err, server.ErrSignatureInvalid)
+}
+
+func TestClaimLicense_Signature_EscapedPath(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.SigningKey = key
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test%0A201%0Afingerprint", nil)
+	req.Header.Set("Relay-Nonce", "test_nonce")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	sig := rr.Header().Get("Relay-Signature")
+	env := server.Envelope{
+		Method: http.MethodPut,
+		Path:   "/v1/nodes/test%0A201%0Afingerprint",
+		Status: http.StatusCreated,
+		Nonce:  "test_nonce",
+		Body:   rr.Body.Bytes(),
+	}
+
+	_, err = server.VerifyEnvelopeSignature(key.Public().(ed25519.PublicKey), sig, env)
+	assert.NoError(t, err)
+
+	// a decoded newline in the path can't be used to forge the envelope's other fields
+	decoded := env
+	decoded.Path = "/v1/nodes/test\n201\nfingerprint"
+
+	_, err = server.VerifyEnvelopeSignature(key.Public().(ed25519.PublicKey), sig, decoded)
+	assert.ErrorIs(t, err, server.ErrSignatureInvalid)
+}
+
+func TestGetSigningKey_NotConfigured(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/signing-key", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestClaimLicense_Signature_Disabled(t *testing.T) {
+	cfg := server.NewConfig()
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{
+						File: []byte("test_license_file"),
+						Key:  "test_license_key",
+					},
+					Status: licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	handler := server.NewHandler(srv)
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.Use(server.SigningMiddleware(cfg))
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	sig := rr.Header().Get("Relay-Signature")
+	clock := rr.Header().Get("Relay-Clock")
+
+	assert.Empty(t, sig)
+	assert.NotEmpty(t, clock)
+}
+
+func verifySignature(secret string, header string, body string) bool {
+	return verifySignatureWithKeyID("", secret, header, body)
+}
+
+// verifySignatureWithKeyID verifies the v1 signature following the given kid, where an
+// empty kid is the signature for the secret without a key id
+func verifySignatureWithKeyID(kid string, secret string, header string, body string) bool {
+	var t, v1, currentKid string
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			t = kv[1]
+		case "kid":
+			currentKid = kv[1]
+		case "v1":
+			if currentKid == kid {
+				v1 = kv[1]
+			}
+		}
+	}
+
+	if t == "" || v1 == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	msg := fmt.Sprintf("%s.%s", t, body)
+	mac.Write([]byte(msg))
+
+	expected := make([]byte, hex.EncodedLen(mac.Size()))
 	hex.Encode(expected, mac.Sum(nil))
 
 	return hmac.Equal(expected, []byte(v1))
 }
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestClaimLicense_APIToken(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	var claims int
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+				switch {
+				case token != "relay_test_token":
+					return nil, licenses.ErrAPITokenInvalid
+				case pool == nil || *pool != "prod":
+					return nil, fmt.Errorf("api token 1: %w", licenses.ErrAPITokenPoolMismatch)
+				}
+
+				return &db.ApiToken{ID: 1}, nil
+			},
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				claims++
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	tests := []struct {
+		name   string
+		auth   string
+		pool   string
+		status int
+	}{
+		{name: "missing token", pool: "prod", status: http.StatusUnauthorized},
+		{name: "malformed header", auth: "relay_test_token", pool: "prod", status: http.StatusUnauthorized},
+		{name: "invalid token", auth: "Bearer relay_invalid", pool: "prod", status: http.StatusUnauthorized},
+		{name: "mismatched pool", auth: "Bearer relay_test_token", pool: "dev", status: http.StatusForbidden},
+		{name: "missing pool", auth: "Bearer relay_test_token", status: http.StatusForbidden},
+		{name: "valid token", auth: "Bearer relay_test_token", pool: "prod", status: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.pool != "" {
+				req.Header.Set("Relay-Pool", tt.pool)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="relay"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// only the authenticated request should have claimed a license
+	assert.Equal(t, 1, claims)
+}
+
+func TestReadEndpoints_APIToken(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	manager := &testutils.FakeManager{
+		AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+			if token != "relay_test_token" {
+				return nil, licenses.ErrAPITokenInvalid
+			}
+
+			return &db.ApiToken{ID: 1}, nil
+		},
+		ConfigFn: func() *licenses.Config {
+			config := licenses.NewConfig()
+			config.EnabledAudit = true
+
+			return config
+		},
+	}
+
+	router := mux.NewRouter()
+	server.NewHandler(testutils.NewMockServer(cfg, manager)).RegisterRoutes(router)
+
+	for _, path := range []string{"/v1/nodes", "/v1/nodes/test_fingerprint", "/v1/events"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Equal(t, `Bearer realm="relay"`, rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestListNodes_APIToken_WithoutPool(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	var withoutPool bool
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+				return &db.ApiToken{ID: 1}, nil
+			},
+			ListLeasesWithOptionsFn: func(ctx context.Context, pool *string, limit int64, offset int64, opts *licenses.ListOptions) ([]db.Lease, error) {
+				withoutPool = opts.WithoutPool()
+
+				return nil, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes", nil)
+	req.Header.Set("Authorization", "Bearer relay_test_token")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// a token without a pool can't list leases across all pools
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, withoutPool)
+}
+
+func TestGetNode_APIToken_OtherPool(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+				return &db.ApiToken{ID: 1}, nil
+			},
+			GetLeaseFn: func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error) {
+				return &db.Lease{
+					Node:    db.Node{Fingerprint: fingerprint},
+					License: db.License{Guid: "license_1", PoolID: ptr(int64(1))},
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/nodes/test_fingerprint", nil)
+	req.Header.Set("Authorization", "Bearer relay_test_token")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestReleaseLicense_APIToken(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.RequireAPITokens = true
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			AuthenticateAPITokenFn: func(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+				if token != "relay_test_token" {
+					return nil, licenses.ErrAPITokenInvalid
+				}
+
+				return &db.ApiToken{ID: 1}, nil
+			},
+			ReleaseLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/nodes/test_fingerprint", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/v1/nodes/test_fingerprint/leases/test_lease", nil)
+	req.Header.Set("Authorization", "Bearer relay_test_token")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
+
+func TestClaimLicense_PoolAccessDenied(t *testing.T) {
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+
+	cfg := server.NewConfig()
+	cfg.ForwardedHeader = "X-Forwarded-For"
+	cfg.TrustedProxies = []*net.IPNet{trusted}
+
+	var claims int
+
+	srv := testutils.NewMockServer(
+		cfg,
+		&testutils.FakeManager{
+			CheckPoolAccessFn: func(ctx context.Context, pool *string, ip net.IP) error {
+				if pool != nil && *pool == "prod" && !ip.Equal(net.ParseIP("198.51.100.1")) {
+					return fmt.Errorf("ip %s: %w", ip, licenses.ErrAccessDenied)
+				}
+
+				return nil
+			},
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				claims++
+
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		pool       string
+		status     int
+	}{
+		{name: "denied", remoteAddr: "192.0.2.1:1234", pool: "prod", status: http.StatusForbidden},
+		{name: "allowed", remoteAddr: "198.51.100.1:1234", pool: "prod", status: http.StatusCreated},
+		{name: "unrestricted pool", remoteAddr: "192.0.2.1:1234", pool: "dev", status: http.StatusCreated},
+		{name: "forwarded by trusted proxy", remoteAddr: "10.0.0.1:1234", forwarded: "198.51.100.1", pool: "prod", status: http.StatusCreated},
+		{name: "forwarded by untrusted proxy", remoteAddr: "192.0.2.1:1234", forwarded: "198.51.100.1", pool: "prod", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("Relay-Pool", tt.pool)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+
+			if tt.status == http.StatusForbidden {
+				assert.JSONEq(t, `{"error":"access to pool is denied"}`, rr.Body.String())
+			}
+		})
+	}
+
+	// only the allowed requests should have claimed a license
+	assert.Equal(t, 3, claims)
+}
+
+func TestClaimLicense_UnixSocket_PoolAccess(t *testing.T) {
+	var checks int
+
+	srv := testutils.NewMockServer(
+		server.NewConfig(),
+		&testutils.FakeManager{
+			CheckPoolAccessFn: func(ctx context.Context, pool *string, ip net.IP) error {
+				checks++
+
+				return licenses.ErrAccessDenied
+			},
+			ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+				return &licenses.LicenseOperationResult{
+					License: &db.License{File: []byte("test_license_file"), Key: "test_license_key"},
+					Status:  licenses.OperationStatusCreated,
+				}, nil
+			},
+		},
+	)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	// clients connected over a unix socket have no ip address to check against the
+	// pool's cidr ranges, so access is left to the socket's mode and owner
+	req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/run/relay.sock", Net: "unix"}))
+	req.RemoteAddr = "@"
+	req.Header.Set("Relay-Pool", "prod")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 0, checks)
+}
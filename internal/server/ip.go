@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the IP address of the client, without its port. When a forwarded
+// header is configured and the request came from a trusted proxy, the client is the
+// right-most address in the header that isn't itself a trusted proxy, since addresses
+// further left can be spoofed by the client. An empty string is returned when the
+// remote address isn't an IP address.
+func clientIP(cfg *Config, r *http.Request) string {
+	var forwarded []string
+	if cfg.ForwardedHeader != "" {
+		forwarded = r.Header.Values(cfg.ForwardedHeader)
+	}
+
+	return forwardedClientIP(cfg, r.RemoteAddr, forwarded)
+}
+
+// forwardedClientIP returns the IP address of the client given the remote address of the
+// connection and the values of the forwarded header, if any
+func forwardedClientIP(cfg *Config, remoteAddr string, values []string) string {
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		ip = host
+	}
+
+	// an unparseable address must never be used as e.g. a rate limit key
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+
+	if cfg.ForwardedHeader == "" || !cfg.trustedProxy(ip) {
+		return ip
+	}
+
+	if len(values) == 0 {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(values, ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+
+		if !cfg.trustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// trustedProxy returns true if the IP address is within the trusted proxy ranges
+func (c *Config) trustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, ipnet := range c.TrustedProxies {
+		if ipnet.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unixSocketRequest returns true if the request was received over a unix socket, where
+// the client has no IP address
+func unixSocketRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+
+	return ok && addr.Network() == "unix"
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+	_, loopback, _ := net.ParseCIDR("::1/128")
+
+	tests := []struct {
+		name       string
+		header     string
+		proxies    []*net.IPNet
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "ipv6 remote address", remoteAddr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{name: "header not configured", remoteAddr: "10.0.0.1:1234", forwarded: []string{"198.51.100.1"}, want: "10.0.0.1"},
+		{name: "untrusted proxy", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "192.0.2.1:1234", forwarded: []string{"198.51.100.1"}, want: "192.0.2.1"},
+		{name: "trusted proxy", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "10.0.0.1:1234", forwarded: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "missing header", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "spoofed hops", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "10.0.0.1:1234", forwarded: []string{"203.0.113.1, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chained proxies", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "10.0.0.1:1234", forwarded: []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"}, want: "198.51.100.1"},
+		{name: "malformed hop", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "10.0.0.1:1234", forwarded: []string{"198.51.100.1, bogus"}, want: "10.0.0.1"},
+		{name: "unparseable remote address", remoteAddr: "bogus", want: ""},
+		{name: "unparseable remote host", remoteAddr: "bogus:1234", want: ""},
+		{name: "remote address without port", remoteAddr: "192.0.2.1", want: "192.0.2.1"},
+		{name: "unparseable trusted proxy", header: "X-Forwarded-For", proxies: []*net.IPNet{private}, remoteAddr: "bogus", forwarded: []string{"198.51.100.1"}, want: ""},
+		{name: "custom header", header: "X-Real-IP", proxies: []*net.IPNet{loopback}, remoteAddr: "[::1]:1234", forwarded: []string{"2001:db8::2"}, want: "2001:db8::2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ForwardedHeader: tt.header, TrustedProxies: tt.proxies}
+
+			req := httptest.NewRequest("PUT", "/v1/nodes/test_fingerprint", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+				req.Header.Add("X-Real-IP", value)
+			}
+
+			assert.Equal(t, tt.want, clientIP(cfg, req))
+		})
+	}
+}
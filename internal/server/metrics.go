@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "relay"
+
+// metricsScrapeTimeout bounds the database queries run when collecting pool gauges
+const metricsScrapeTimeout = 5 * time.Second
+
+// Metrics holds the Prometheus collectors for the relay server. Lease and pool gauges
+// are computed from the database at scrape time, while counters are incremented by
+// the handler and reaper as events occur.
+type Metrics struct {
+	registry        *prometheus.Registry
+	claims          *prometheus.CounterVec
+	extensions      *prometheus.CounterVec
+	releases        *prometheus.CounterVec
+	conflicts       *prometheus.CounterVec
+	exhaustions     *prometheus.CounterVec
+	culls           prometheus.Counter
+	throttles       *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+func NewMetrics(m licenses.Manager) *Metrics {
+	metrics := &Metrics{
+		registry: prometheus.NewRegistry(),
+		claims: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "claims_total",
+			Help:      "Total number of new leases claimed.",
+		}, []string{"pool"}),
+		extensions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "extensions_total",
+			Help:      "Total number of leases extended.",
+		}, []string{"pool"}),
+		releases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "releases_total",
+			Help:      "Total number of leases released.",
+		}, []string{"pool"}),
+		conflicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "conflicts_total",
+			Help:      "Total number of claims rejected due to a conflict.",
+		}, []string{"pool"}),
+		exhaustions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "no_licenses_available_total",
+			Help:      "Total number of claims rejected because no licenses were available.",
+		}, []string{"pool"}),
+		culls: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "culled_nodes_total",
+			Help:      "Total number of dead nodes culled by the reaper.",
+		}),
+		throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "throttled_requests_total",
+			Help:      "Total number of requests throttled by the rate limiter.",
+		}, []string{"by"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+
+	metrics.registry.MustRegister(
+		metrics.claims,
+		metrics.extensions,
+		metrics.releases,
+		metrics.conflicts,
+		metrics.exhaustions,
+		metrics.culls,
+		metrics.throttles,
+		metrics.requestDuration,
+		newPoolCollector(m),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return metrics
+}
+
+// Handler returns an HTTP handler that serves metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the underlying Prometheus registry
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) ObserveClaim(pool *string, status licenses.OperationStatus) {
+	label := poolLabel(pool)
+
+	switch status {
+	case licenses.OperationStatusCreated:
+		m.claims.WithLabelValues(label).Inc()
+	case licenses.OperationStatusExtended:
+		m.extensions.WithLabelValues(label).Inc()
+	case licenses.OperationStatusConflict:
+		m.conflicts.WithLabelValues(label).Inc()
+	case licenses.OperationStatusNoLicensesAvailable:
+		m.exhaustions.WithLabelValues(label).Inc()
+	}
+}
+
+// ObserveThrottle records a request throttled by the rate limiter, by fingerprint or ip
+func (m *Metrics) ObserveThrottle(by string) {
+	m.throttles.WithLabelValues(by).Inc()
+}
+
+func (m *Metrics) ObserveRelease(pool *string, status licenses.OperationStatus) {
+	if status == licenses.OperationStatusSuccess {
+		m.releases.WithLabelValues(poolLabel(pool)).Inc()
+	}
+}
+
+func (m *Metrics) ObserveCull(count int) {
+	m.culls.Add(float64(count))
+}
+
+func (m *Metrics) ObserveRequest(method string, route string, status int, duration time.Duration) {
+	m.requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// poolLabel returns the label value for a pool, where the global pool is an empty string
+func poolLabel(pool *string) string {
+	if pool == nil {
+		return ""
+	}
+
+	return *pool
+}
+
+// poolCollector collects license and node gauges from the database at scrape time, so
+// that they're always consistent with the database, e.g. after a restart or a cull
+type poolCollector struct {
+	manager     licenses.Manager
+	total       *prometheus.Desc
+	leased      *prometheus.Desc
+	free        *prometheus.Desc
+	activeNodes *prometheus.Desc
+}
+
+func newPoolCollector(m licenses.Manager) *poolCollector {
+	return &poolCollector{
+		manager:     m,
+		total:       prometheus.NewDesc(metricsNamespace+"_pool_licenses", "Total number of licenses in a pool.", []string{"pool"}, nil),
+		leased:      prometheus.NewDesc(metricsNamespace+"_pool_licenses_leased", "Number of licenses in a pool with an active lease.", []string{"pool"}, nil),
+		free:        prometheus.NewDesc(metricsNamespace+"_pool_licenses_free", "Number of licenses in a pool available to be leased.", []string{"pool"}, nil),
+		activeNodes: prometheus.NewDesc(metricsNamespace+"_active_nodes", "Number of active nodes.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.leased
+	ch <- c.free
+	ch <- c.activeNodes
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsScrapeTimeout)
+	defer cancel()
+
+	stats, err := c.manager.GetPoolStats(ctx)
+	if err != nil {
+		logger.Error("failed to collect pool metrics", "error", err)
+
+		ch <- prometheus.NewInvalidMetric(c.total, err)
+	} else {
+		for _, stat := range stats {
+			var label string
+			if stat.Pool != nil {
+				label = stat.Pool.Name
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.Total), label)
+			ch <- prometheus.MustNewConstMetric(c.leased, prometheus.GaugeValue, float64(stat.Leased), label)
+			ch <- prometheus.MustNewConstMetric(c.free, prometheus.GaugeValue, float64(stat.Free()), label)
+		}
+	}
+
+	count, err := c.manager.CountActiveNodes(ctx)
+	if err != nil {
+		logger.Error("failed to collect node metrics", "error", err)
+
+		ch <- prometheus.NewInvalidMetric(c.activeNodes, err)
+
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.activeNodes, prometheus.GaugeValue, float64(count))
+}
@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_PoolGauges(t *testing.T) {
+	manager := &testutils.FakeManager{
+		GetPoolStatsFn: func(ctx context.Context) ([]db.PoolStats, error) {
+			return []db.PoolStats{
+				{Total: 2, Leased: 1},
+				{Pool: &db.Pool{ID: 1, Name: "prod"}, Total: 5, Leased: 5},
+			}, nil
+		},
+		CountActiveNodesFn: func(ctx context.Context) (int64, error) {
+			return 6, nil
+		},
+	}
+
+	metrics := server.NewMetrics(manager)
+
+	expected := `
+# HELP relay_active_nodes Number of active nodes.
+# TYPE relay_active_nodes gauge
+relay_active_nodes 6
+# HELP relay_pool_licenses Total number of licenses in a pool.
+# TYPE relay_pool_licenses gauge
+relay_pool_licenses{pool=""} 2
+relay_pool_licenses{pool="prod"} 5
+# HELP relay_pool_licenses_free Number of licenses in a pool available to be leased.
+# TYPE relay_pool_licenses_free gauge
+relay_pool_licenses_free{pool=""} 1
+relay_pool_licenses_free{pool="prod"} 0
+# HELP relay_pool_licenses_leased Number of licenses in a pool with an active lease.
+# TYPE relay_pool_licenses_leased gauge
+relay_pool_licenses_leased{pool=""} 1
+relay_pool_licenses_leased{pool="prod"} 5
+`
+
+	err := testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected),
+		"relay_active_nodes",
+		"relay_pool_licenses",
+		"relay_pool_licenses_free",
+		"relay_pool_licenses_leased",
+	)
+	assert.NoError(t, err)
+}
+
+func TestMetrics_PoolGauges_Error(t *testing.T) {
+	manager := &testutils.FakeManager{
+		GetPoolStatsFn: func(ctx context.Context) ([]db.PoolStats, error) {
+			return nil, errors.New("database is locked")
+		},
+	}
+
+	metrics := server.NewMetrics(manager)
+
+	_, err := metrics.Registry().Gather()
+	assert.ErrorContains(t, err, "database is locked")
+}
+
+func TestMetrics_Counters(t *testing.T) {
+	cfg := server.NewConfig()
+	statuses := []licenses.OperationStatus{
+		licenses.OperationStatusCreated,
+		licenses.OperationStatusExtended,
+		licenses.OperationStatusExtended,
+		licenses.OperationStatusConflict,
+		licenses.OperationStatusNoLicensesAvailable,
+	}
+
+	manager := &testutils.FakeManager{
+		ClaimLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+			status := statuses[0]
+			statuses = statuses[1:]
+
+			return &licenses.LicenseOperationResult{License: &db.License{}, Status: status}, nil
+		},
+		ReleaseLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+		},
+	}
+
+	srv := testutils.NewMockServer(cfg, manager)
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+	router.Use(server.LoggingMiddleware(srv.Metrics()))
+
+	for range 5 {
+		req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint", nil)
+		req.Header.Set("Relay-Pool", "prod")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/nodes/test_fingerprint", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := `
+# HELP relay_claims_total Total number of new leases claimed.
+# TYPE relay_claims_total counter
+relay_claims_total{pool="prod"} 1
+# HELP relay_conflicts_total Total number of claims rejected due to a conflict.
+# TYPE relay_conflicts_total counter
+relay_conflicts_total{pool="prod"} 1
+# HELP relay_extensions_total Total number of leases extended.
+# TYPE relay_extensions_total counter
+relay_extensions_total{pool="prod"} 2
+# HELP relay_no_licenses_available_total Total number of claims rejected because no licenses were available.
+# TYPE relay_no_licenses_available_total counter
+relay_no_licenses_available_total{pool="prod"} 1
+# HELP relay_releases_total Total number of leases released.
+# TYPE relay_releases_total counter
+relay_releases_total{pool=""} 1
+`
+
+	err := testutil.GatherAndCompare(srv.Metrics().Registry(), strings.NewReader(expected),
+		"relay_claims_total",
+		"relay_conflicts_total",
+		"relay_extensions_total",
+		"relay_no_licenses_available_total",
+		"relay_releases_total",
+	)
+	assert.NoError(t, err)
+
+	// requests are labeled by route template rather than by fingerprint
+	count, err := testutil.GatherAndCount(srv.Metrics().Registry(), "relay_http_request_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count) // one series per method, route and status
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `relay_http_request_duration_seconds_count{method="PUT",route="/v1/nodes/{fingerprint}",status="202"} 2`)
+}
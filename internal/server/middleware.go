@@ -2,11 +2,14 @@ package server
 
 import (
 	"bytes"
-	"encoding/hex"
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/keygen-sh/keygen-relay/internal/logger"
 )
 
@@ -28,22 +31,48 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ww := wrapResponseWriter(w)
-		start := time.Now()
-
-		next.ServeHTTP(ww, r)
-
-		logger.Info("HTTP request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", ww.Status(),
-			"remote_addr", r.RemoteAddr,
-			"user_agent", r.UserAgent(),
-			"duration", time.Since(start),
-		)
-	})
+// Unwrap allows an http.ResponseController to flush the underlying response writer
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
+// LoggingMiddleware creates a middleware that logs requests and records their latency
+func LoggingMiddleware(metrics *Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := wrapResponseWriter(w)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			duration := time.Since(start)
+
+			logger.Info("HTTP request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.Status(),
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+				"duration", duration,
+			)
+
+			if metrics != nil {
+				metrics.ObserveRequest(r.Method, routeTemplate(r), ww.Status(), duration)
+			}
+		})
+	}
+}
+
+// routeTemplate returns the matched route's path template, e.g. /v1/nodes/{fingerprint},
+// so that metrics aren't labeled per fingerprint
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+
+	return "unknown"
 }
 
 // signingResponseWriter captures the response body for signing
@@ -61,8 +90,9 @@ func (srw *signingResponseWriter) WriteHeader(code int) {
 	srw.statusCode = code
 }
 
-// SigningMiddleware creates a middleware that signs response bodies with HMAC-SHA256.
-// The signature is added as a Relay-Signature header in the format: t=<timestamp>,v1=<signature>
+// SigningMiddleware creates a middleware that signs responses with HMAC-SHA256 and/or
+// Ed25519. The signature is added as a Relay-Signature header in the format:
+// t=<timestamp>,v1=<hmac>,v3=<hmac envelope>,alg=ed25519,v2=<ed25519>,v4=<ed25519 envelope>
 func SigningMiddleware(cfg *Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		signer := NewSigner(cfg)
@@ -71,7 +101,8 @@ func SigningMiddleware(cfg *Config) func(http.Handler) http.Handler {
 			t := time.Now().Unix()
 			w.Header().Set("Relay-Clock", fmt.Sprintf("%d", t))
 
-			if !signer.Enabled() {
+			// streamed responses can't be buffered for signing
+			if !signer.Enabled() || isStreaming(r) {
 				next.ServeHTTP(w, r)
 
 				return
@@ -85,13 +116,46 @@ func SigningMiddleware(cfg *Config) func(http.Handler) http.Handler {
 
 			next.ServeHTTP(ww, r)
 
-			// signature is computed over "<timestamp>.<raw response body>"
-			msg := fmt.Sprintf("%d.%s", t, ww.body.Bytes())
-			sig := signer.Sign([]byte(msg))
-			w.Header().Set("Relay-Signature", fmt.Sprintf("t=%d,v1=%s", t, hex.EncodeToString(sig)))
+			w.Header().Set("Relay-Signature", signer.Signature(t, Envelope{
+				Method: r.Method,
+				Path:   r.URL.EscapedPath(),
+				Status: ww.statusCode,
+				Nonce:  r.Header.Get("Relay-Nonce"),
+				Body:   ww.body.Bytes(),
+			}))
 
 			w.WriteHeader(ww.statusCode)
 			w.Write(ww.body.Bytes())
 		})
 	}
 }
+
+// AdminAuthMiddleware creates a middleware that authenticates admin requests using the
+// configured admin token as a bearer token. The admin API is disabled without a token.
+func AdminAuthMiddleware(cfg *Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.AdminToken == nil || *cfg.AdminToken == "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "admin api is disabled"})
+
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(*cfg.AdminToken)) != 1 {
+				logger.Warn("admin request unauthorized", "method", r.Method, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+
+				w.Header().Set("WWW-Authenticate", `Bearer realm="relay"`)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+// getOpenAPIDocument fetches the OpenAPI document from the router
+func getOpenAPIDocument(t *testing.T, router *mux.Router) openAPIDocument {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc openAPIDocument
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&doc))
+
+	return doc
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	doc := getOpenAPIDocument(t, router)
+
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	var routes []string
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		// subrouters e.g. the admin api's path prefix don't have methods
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		for _, method := range methods {
+			routes = append(routes, method+" "+path)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+
+	// every route must be documented, and every documented route must exist
+	assert.Equal(t, routes, documented)
+}
+
+func TestOpenAPI_StatusCodes(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	doc := getOpenAPIDocument(t, router)
+
+	for _, path := range []string{"/v1/nodes/{fingerprint}", "/v1/nodes/{fingerprint}/leases/{lease_id}"} {
+		claim := doc.Paths[path]["put"]
+
+		for _, code := range []string{"201", "202", "400", "401", "403", "409", "410", "429", "500", "503"} {
+			assert.Contains(t, claim.Responses, code, "claim %s is missing status %s", path, code)
+		}
+
+		release := doc.Paths[path]["delete"]
+
+		for _, code := range []string{"204", "400", "401", "403", "404", "429", "500"} {
+			assert.Contains(t, release.Responses, code, "release %s is missing status %s", path, code)
+		}
+
+		get := doc.Paths[path]["get"]
+
+		for _, code := range []string{"200", "400", "403", "404", "429", "500"} {
+			assert.Contains(t, get.Responses, code, "get %s is missing status %s", path, code)
+		}
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), &testutils.FakeManager{})
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	doc := getOpenAPIDocument(t, router)
+
+	schemas := map[string]any{
+		"ClaimLicenseRequest":   server.RequestBodyPayload{},
+		"ClaimLicenseResponse":  server.ClaimLicenseResponse{},
+		"ExtendLicenseResponse": server.ExtendLicenseResponse{},
+		"NodeResponse":          server.NodeResponse{},
+		"NodeMetadata":          server.NodeMetadata{},
+		"ListNodesResponse":     server.ListNodesResponse{},
+		"SigningKeyResponse":    server.SigningKeyResponse{},
+		"EventResponse":         server.EventResponse{},
+		"LicenseResponse":       server.LicenseResponse{},
+		"ListLicensesResponse":  server.ListLicensesResponse{},
+		"PoolResponse":          server.PoolResponse{},
+		"ListPoolsResponse":     server.ListPoolsResponse{},
+		"CreatePoolRequest":     server.CreatePoolRequest{},
+		"ReadyResponse":         server.ReadyResponse{},
+		"CheckResponse":         server.CheckResponse{},
+		"ReaperCheckResponse":   server.ReaperCheckResponse{},
+		"PoolCapacityResponse":  server.PoolCapacityResponse{},
+	}
+
+	for name, v := range schemas {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "schema %s is not documented", name)
+
+			var fields []string
+
+			// embedded structs contribute their fields to the schema
+			for _, field := range reflect.VisibleFields(reflect.TypeOf(v)) {
+				tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+				if tag != "" && tag != "-" {
+					fields = append(fields, tag)
+				}
+			}
+
+			var properties []string
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+
+			sort.Strings(fields)
+			sort.Strings(properties)
+
+			assert.Equal(t, fields, properties)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WaitQueue is a per-pool FIFO queue of claims waiting for a license to be freed. Only
+// the waiter at the head of a pool's queue is notified when a license may be available,
+// so that waiters are served in the order they arrived.
+type WaitQueue struct {
+	mu     sync.Mutex
+	queues map[string]*list.List
+	done   chan struct{}
+	closed bool
+}
+
+// Waiter is a claim waiting in a WaitQueue
+type Waiter struct {
+	key   string
+	ready chan struct{}
+	elem  *list.Element
+}
+
+// Ready returns a channel that receives when the waiter should retry its claim
+func (w *Waiter) Ready() <-chan struct{} {
+	return w.ready
+}
+
+func NewWaitQueue() *WaitQueue {
+	return &WaitQueue{
+		queues: make(map[string]*list.List),
+		done:   make(chan struct{}),
+	}
+}
+
+// Enqueue adds a waiter to the back of a pool's queue, returning the waiter and its
+// 1-based position in the queue
+func (q *WaitQueue) Enqueue(pool *string) (*Waiter, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := poolLabel(pool)
+
+	queue, ok := q.queues[key]
+	if !ok {
+		queue = list.New()
+		q.queues[key] = queue
+	}
+
+	w := &Waiter{key: key, ready: make(chan struct{}, 1)}
+	w.elem = queue.PushBack(w)
+
+	// the head of the queue should immediately attempt a claim in case a license was
+	// freed between its failed claim and being queued
+	if queue.Len() == 1 {
+		w.notify()
+	}
+
+	return w, queue.Len()
+}
+
+// Remove removes a waiter from its queue. If the waiter was notified but never acted on
+// it, e.g. the client disconnected, the notification is passed on to the next waiter.
+func (q *WaitQueue) Remove(w *Waiter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, ok := q.queues[w.key]
+	if !ok || w.elem == nil {
+		return
+	}
+
+	queue.Remove(w.elem)
+	w.elem = nil
+
+	select {
+	case <-w.ready:
+		if front := queue.Front(); front != nil {
+			front.Value.(*Waiter).notify()
+		}
+	default:
+	}
+
+	if queue.Len() == 0 {
+		delete(q.queues, w.key)
+	}
+}
+
+// Position returns the 1-based position of a waiter in its queue, or 0 if it's no
+// longer queued
+func (q *WaitQueue) Position(w *Waiter) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, ok := q.queues[w.key]
+	if !ok || w.elem == nil {
+		return 0
+	}
+
+	pos := 1
+	for e := queue.Front(); e != nil; e = e.Next() {
+		if e == w.elem {
+			return pos
+		}
+
+		pos++
+	}
+
+	return 0
+}
+
+// IsHead returns true if the waiter is at the head of its queue
+func (q *WaitQueue) IsHead(w *Waiter) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue, ok := q.queues[w.key]
+	if !ok || w.elem == nil {
+		return false
+	}
+
+	return queue.Front() == w.elem
+}
+
+// Len returns the number of waiters in a pool's queue
+func (q *WaitQueue) Len(pool *string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if queue, ok := q.queues[poolLabel(pool)]; ok {
+		return queue.Len()
+	}
+
+	return 0
+}
+
+// Notify notifies the head of a pool's queue that a license may have been freed
+func (q *WaitQueue) Notify(pool *string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if queue, ok := q.queues[poolLabel(pool)]; ok {
+		if front := queue.Front(); front != nil {
+			front.Value.(*Waiter).notify()
+		}
+	}
+}
+
+// NotifyAll notifies the head of every pool's queue, e.g. after the reaper culls nodes
+// across pools
+func (q *WaitQueue) NotifyAll() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, queue := range q.queues {
+		if front := queue.Front(); front != nil {
+			front.Value.(*Waiter).notify()
+		}
+	}
+}
+
+// Close stops all waiters e.g. during shutdown
+func (q *WaitQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+
+		close(q.done)
+	}
+}
+
+// Done returns a channel that's closed when the queue is closed
+func (q *WaitQueue) Done() <-chan struct{} {
+	return q.done
+}
+
+func (w *Waiter) notify() {
+	select {
+	case w.ready <- struct{}{}:
+	default: // already notified
+	}
+}
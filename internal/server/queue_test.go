@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitQueue_FIFO(t *testing.T) {
+	queue := server.NewWaitQueue()
+	pool := "prod"
+
+	first, pos := queue.Enqueue(&pool)
+	assert.Equal(t, 1, pos)
+
+	second, pos := queue.Enqueue(&pool)
+	assert.Equal(t, 2, pos)
+
+	// queues are isolated per pool
+	global, pos := queue.Enqueue(nil)
+	assert.Equal(t, 1, pos)
+
+	assert.Equal(t, 2, queue.Len(&pool))
+	assert.Equal(t, 1, queue.Len(nil))
+	assert.True(t, queue.IsHead(first))
+	assert.False(t, queue.IsHead(second))
+
+	// the head is notified when it's queued alone
+	assertNotified(t, first)
+	assertNotified(t, global)
+	assertNotNotified(t, second)
+
+	// only the head is notified
+	queue.Notify(&pool)
+	assertNotified(t, first)
+	assertNotNotified(t, second)
+
+	queue.Remove(first)
+	assert.True(t, queue.IsHead(second))
+	assert.Equal(t, 1, queue.Position(second))
+	assert.Equal(t, 0, queue.Position(first))
+
+	queue.Remove(second)
+	queue.Remove(global)
+	assert.Equal(t, 0, queue.Len(&pool))
+	assert.Equal(t, 0, queue.Len(nil))
+}
+
+func TestWaitQueue_RemoveForwardsNotification(t *testing.T) {
+	queue := server.NewWaitQueue()
+
+	first, _ := queue.Enqueue(nil)
+	second, _ := queue.Enqueue(nil)
+	assertNotified(t, first)
+
+	// the head was notified but disconnected before acting on it
+	queue.Notify(nil)
+	queue.Remove(first)
+
+	assertNotified(t, second)
+}
+
+func TestWaitQueue_NotifyAll(t *testing.T) {
+	queue := server.NewWaitQueue()
+	pool := "prod"
+
+	first, _ := queue.Enqueue(nil)
+	second, _ := queue.Enqueue(&pool)
+	assertNotified(t, first)
+	assertNotified(t, second)
+
+	queue.NotifyAll()
+	assertNotified(t, first)
+	assertNotified(t, second)
+}
+
+func TestWaitQueue_Close(t *testing.T) {
+	queue := server.NewWaitQueue()
+	queue.Close()
+	queue.Close()
+
+	select {
+	case <-queue.Done():
+	default:
+		t.Fatal("expected queue to be closed")
+	}
+}
+
+func assertNotified(t *testing.T, w *server.Waiter) {
+	t.Helper()
+
+	select {
+	case <-w.Ready():
+	default:
+		t.Fatal("expected waiter to be notified")
+	}
+}
+
+func assertNotNotified(t *testing.T, w *server.Waiter) {
+	t.Helper()
+
+	select {
+	case <-w.Ready():
+		t.Fatal("expected waiter to not be notified")
+	default:
+	}
+}
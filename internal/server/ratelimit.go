@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+// rateLimitSweepInterval is how often idle buckets are evicted, so that e.g. random
+// fingerprints can't grow the limiter unbounded
+const rateLimitSweepInterval = 1 * time.Minute
+
+// RateLimit is a token bucket limit, where a zero rate disables the limit
+type RateLimit struct {
+	Rate  float64 // tokens per second
+	Burst int
+}
+
+// Enabled returns true if the limit has a positive rate
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+type bucket struct {
+	tokens    float64
+	last      time.Time
+	throttled int64
+}
+
+// RateLimiter is a set of token buckets keyed by e.g. fingerprint or IP address. The
+// limit is given per call, so that changes to the server's config apply immediately.
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the key's bucket, returning whether or not the request is
+// allowed, and if not, how long until a token is available along with the number of
+// requests throttled for the key since its bucket was last idle
+func (rl *RateLimiter) Allow(key string, limit RateLimit) (bool, time.Duration, int64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	burst := float64(max(limit.Burst, 1))
+
+	if now.Sub(rl.lastSweep) >= rateLimitSweepInterval {
+		rl.sweep(now, limit.Rate, burst)
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		rl.buckets[key] = b
+	}
+
+	// refill the bucket for the time elapsed since it was last used
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0, b.throttled
+	}
+
+	b.throttled++
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+
+	return false, wait, b.throttled
+}
+
+// sweep evicts buckets which would have refilled completely, since they're equivalent
+// to a new bucket
+func (rl *RateLimiter) sweep(now time.Time, rate float64, burst float64) {
+	idle := time.Duration(burst / rate * float64(time.Second))
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= idle {
+			delete(rl.buckets, key)
+		}
+	}
+
+	rl.lastSweep = now
+}
+
+// RateLimiters are the buckets keyed by fingerprint and by IP address, shared by the REST
+// and gRPC APIs so that a node can't double its limit by using both
+type RateLimiters struct {
+	Fingerprints *RateLimiter
+	IPs          *RateLimiter
+}
+
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{
+		Fingerprints: NewRateLimiter(),
+		IPs:          NewRateLimiter(),
+	}
+}
+
+// RateLimitMiddleware creates a middleware that throttles requests using token buckets
+// keyed by the node's fingerprint and the client's IP address, responding with a 429
+// and a Retry-After header once a bucket is empty. Health and readiness checks are never
+// throttled, and requests over a unix socket are only throttled by fingerprint.
+func RateLimitMiddleware(cfg *Config, metrics *Metrics, limiters *RateLimiters) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := routeTemplate(r); route == "/v1/health" || route == "/v1/ready" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			// clients connected over a unix socket, or from an address that isn't an ip
+			// address, have no ip address to limit by
+			var ip string
+			if !unixSocketRequest(r) {
+				ip = clientIP(cfg, r)
+			}
+
+			if cfg.IPRateLimit.Enabled() && ip != "" {
+				if ok, wait, throttled := limiters.IPs.Allow(ip, cfg.IPRateLimit); !ok {
+					logger.Warn("request throttled", "by", "ip", "ip", ip, "remote_addr", r.RemoteAddr, "method", r.Method, "path", r.URL.Path, "throttled", throttled, "retry_after", wait)
+
+					if metrics != nil {
+						metrics.ObserveThrottle("ip")
+					}
+
+					writeThrottled(w, wait)
+
+					return
+				}
+			}
+
+			if fingerprint, ok := mux.Vars(r)["fingerprint"]; ok && cfg.FingerprintRateLimit.Enabled() {
+				if ok, wait, throttled := limiters.Fingerprints.Allow(fingerprint, cfg.FingerprintRateLimit); !ok {
+					logger.Warn("request throttled", "by", "fingerprint", "nodeFingerprint", fingerprint, "remote_addr", r.RemoteAddr, "method", r.Method, "path", r.URL.Path, "throttled", throttled, "retry_after", wait)
+
+					if metrics != nil {
+						metrics.ObserveThrottle("fingerprint")
+					}
+
+					writeThrottled(w, wait)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeThrottled(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", retryAfter(wait))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "too many requests"})
+}
+
+// retryAfter returns the whole number of seconds to wait before retrying
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(1764949490, 0)
+
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := RateLimit{Rate: 1, Burst: 2}
+
+	// the bucket starts full
+	for range 2 {
+		ok, _, _ := limiter.Allow("a", limit)
+		assert.True(t, ok)
+	}
+
+	ok, wait, throttled := limiter.Allow("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 1*time.Second, wait)
+	assert.Equal(t, int64(1), throttled)
+
+	ok, _, throttled = limiter.Allow("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, int64(2), throttled)
+
+	// other keys have their own bucket
+	ok, _, _ = limiter.Allow("b", limit)
+	assert.True(t, ok)
+
+	// the bucket refills over time
+	now = now.Add(500 * time.Millisecond)
+
+	ok, wait, _ = limiter.Allow("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+
+	ok, _, _ = limiter.Allow("a", limit)
+	assert.True(t, ok)
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	now := time.Unix(1764949490, 0)
+
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := RateLimit{Rate: 1, Burst: 1}
+
+	limiter.Allow("a", limit)
+	limiter.Allow("b", limit)
+	assert.Len(t, limiter.buckets, 2)
+
+	// idle buckets are evicted once they would have refilled
+	now = now.Add(rateLimitSweepInterval)
+
+	limiter.Allow("c", limit)
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "c")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := &Config{
+		FingerprintRateLimit: RateLimit{Rate: 1, Burst: 1},
+		IPRateLimit:          RateLimit{Rate: 1, Burst: 3},
+	}
+
+	metrics := NewMetrics(nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	router.HandleFunc("/v1/ready", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	router.HandleFunc("/v1/nodes/{fingerprint}", func(w http.ResponseWriter, r *http.Request) {}).Methods("PUT")
+	router.Use(RateLimitMiddleware(cfg, metrics, NewRateLimiters()))
+
+	request := func(method string, path string, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("fingerprint", func(t *testing.T) {
+		rr := request(http.MethodPut, "/v1/nodes/a", "192.0.2.1:1234")
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = request(http.MethodPut, "/v1/nodes/a", "192.0.2.2:1234")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"too many requests"}`, rr.Body.String())
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.throttles.WithLabelValues("fingerprint")))
+	})
+
+	t.Run("ip", func(t *testing.T) {
+		for _, fingerprint := range []string{"b", "c", "d"} {
+			rr := request(http.MethodPut, "/v1/nodes/"+fingerprint, "198.51.100.1:1234")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		// random fingerprints from a single host are throttled regardless of port
+		rr := request(http.MethodPut, "/v1/nodes/e", "198.51.100.1:5678")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.throttles.WithLabelValues("ip")))
+	})
+
+	t.Run("unparseable address", func(t *testing.T) {
+		// an address that isn't an ip address is never used as a bucket key
+		for _, fingerprint := range []string{"f", "g", "h", "i"} {
+			rr := request(http.MethodPut, "/v1/nodes/"+fingerprint, "bogus")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.throttles.WithLabelValues("ip")))
+	})
+
+	t.Run("health check", func(t *testing.T) {
+		for range 5 {
+			rr := request(http.MethodGet, "/v1/health", "198.51.100.1:1234")
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			rr = request(http.MethodGet, "/v1/ready", "198.51.100.1:1234")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg.FingerprintRateLimit.Rate = 0
+		cfg.IPRateLimit.Rate = 0
+
+		for range 5 {
+			rr := request(http.MethodPut, "/v1/nodes/a", "198.51.100.1:1234")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+	})
+}
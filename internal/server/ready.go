@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/keygen-sh/keygen-relay/internal/logger"
+)
+
+// reaperStaleIntervals is the number of cull intervals the reaper may go without a
+// successful cull before the server is considered not ready, since dead nodes would
+// otherwise hold onto their licenses indefinitely
+const reaperStaleIntervals = 3
+
+// readyTimeout bounds how long a readiness check may take, so that a locked database
+// fails the probe rather than hanging it
+const readyTimeout = 5 * time.Second
+
+var errReadyPoolNotFound = errors.New("pool not found")
+
+type ReadyResponse struct {
+	Ready    bool                   `json:"ready"`
+	Database CheckResponse          `json:"database"`
+	Reaper   ReaperCheckResponse    `json:"reaper"`
+	Pools    []PoolCapacityResponse `json:"pools"`
+}
+
+type CheckResponse struct {
+	OK    bool    `json:"ok"`
+	Error *string `json:"error"`
+}
+
+type ReaperCheckResponse struct {
+	OK         bool    `json:"ok"`
+	Error      *string `json:"error"`
+	Enabled    bool    `json:"enabled"`
+	LastCullAt *int64  `json:"last_cull_at"`
+}
+
+type PoolCapacityResponse struct {
+	Pool   *string `json:"pool"`
+	Total  int64   `json:"total"`
+	Leased int64   `json:"leased"`
+	Free   int64   `json:"free"`
+}
+
+// Ready reports whether or not the server can actually serve claims, i.e. the database
+// is reachable, the reaper is culling dead nodes and the server isn't shutting down,
+// along with each pool's capacity. Unlike HealthCheck, a 503 is returned when any check
+// fails, so that load balancers and probes can act on it.
+func (h *handler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	resp := ReadyResponse{
+		Database: h.checkDatabase(ctx),
+		Reaper:   h.checkReaper(),
+		Pools:    []PoolCapacityResponse{},
+	}
+
+	if resp.Database.OK {
+		pools, err := h.poolCapacity(ctx)
+		switch {
+		case errors.Is(err, errReadyPoolNotFound):
+			resp.Database = checkFailed("pool not found", err)
+		case err != nil:
+			resp.Database = checkFailed("failed to get pool capacity", err)
+		default:
+			resp.Pools = pools
+		}
+	}
+
+	resp.Ready = resp.Database.OK && resp.Reaper.OK
+
+	select {
+	case <-h.server.Done():
+		resp.Ready = false
+	default:
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if resp.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (h *handler) checkDatabase(ctx context.Context) CheckResponse {
+	if err := h.manager.Ping(ctx); err != nil {
+		return checkFailed("database is unavailable", err)
+	}
+
+	return CheckResponse{OK: true}
+}
+
+// checkReaper checks that the reaper has culled recently, where the reaper always runs
+// since heartbeats may be enabled for a pool at any time
+func (h *handler) checkReaper() ReaperCheckResponse {
+	var lastCull time.Time
+	if reaper := h.server.Reaper(); reaper != nil {
+		lastCull = reaper.LastCull()
+	}
+
+	resp := ReaperCheckResponse{Enabled: true}
+
+	if lastCull.IsZero() {
+		msg := "reaper is not running"
+		resp.Error = &msg
+
+		return resp
+	}
+
+	t := lastCull.Unix()
+	resp.LastCullAt = &t
+
+	if time.Since(lastCull) > reaperStaleIntervals*h.config.CullInterval {
+		msg := "reaper has not culled recently"
+		resp.Error = &msg
+
+		return resp
+	}
+
+	resp.OK = true
+
+	return resp
+}
+
+// poolCapacity returns the capacity of each pool, or only the server's pool when it's
+// serving a single pool, in which case the pool must exist
+func (h *handler) poolCapacity(ctx context.Context) ([]PoolCapacityResponse, error) {
+	stats, err := h.manager.GetPoolStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make([]PoolCapacityResponse, 0, len(stats))
+
+	for _, s := range stats {
+		var pool *string
+		if s.Pool != nil {
+			pool = &s.Pool.Name
+		}
+
+		if h.config.Pool != nil && (pool == nil || *pool != *h.config.Pool) {
+			continue
+		}
+
+		pools = append(pools, PoolCapacityResponse{
+			Pool:   pool,
+			Total:  s.Total,
+			Leased: s.Leased,
+			Free:   s.Free(),
+		})
+	}
+
+	if h.config.Pool != nil && len(pools) == 0 {
+		return nil, errReadyPoolNotFound
+	}
+
+	return pools, nil
+}
+
+// checkFailed logs a failed check's error, returning a fixed message for the response
+// since the endpoint is unauthenticated and errors may leak e.g. database paths
+func checkFailed(msg string, err error) CheckResponse {
+	logger.Warn("readiness check failed", "check", msg, "error", err)
+
+	return CheckResponse{Error: &msg}
+}
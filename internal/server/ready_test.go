@@ -0,0 +1,191 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getReady requests the server's readiness
+func getReady(t *testing.T, srv server.Server) (int, server.ReadyResponse) {
+	t.Helper()
+
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/ready", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var resp server.ReadyResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+	return rr.Code, resp
+}
+
+func newReadyManager() *testutils.FakeManager {
+	return &testutils.FakeManager{
+		GetPoolStatsFn: func(ctx context.Context) ([]db.PoolStats, error) {
+			return []db.PoolStats{
+				{Total: 3, Leased: 1},
+				{Pool: &db.Pool{ID: 1, Name: "prod"}, Total: 5, Leased: 5},
+			}, nil
+		},
+	}
+}
+
+// newReadyServer returns a mock server with a reaper that culled recently
+func newReadyServer(cfg *server.Config) *testutils.FakeServer {
+	srv := testutils.NewMockServer(cfg, newReadyManager())
+	srv.ReaperData = &testutils.FakeReaper{
+		LastCullFn: func() time.Time { return time.Now().Add(-5 * time.Second) },
+	}
+
+	return srv
+}
+
+func TestReady_Success(t *testing.T) {
+	srv := newReadyServer(server.NewConfig())
+
+	code, resp := getReady(t, srv)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Ready)
+	assert.True(t, resp.Database.OK)
+	assert.True(t, resp.Reaper.OK)
+	assert.True(t, resp.Reaper.Enabled)
+	assert.NotNil(t, resp.Reaper.LastCullAt)
+
+	// a full pool is reported, but doesn't affect readiness since leases can still be
+	// extended and claims can still wait
+	require.Len(t, resp.Pools, 2)
+	assert.Nil(t, resp.Pools[0].Pool)
+	assert.Equal(t, int64(3), resp.Pools[0].Total)
+	assert.Equal(t, int64(2), resp.Pools[0].Free)
+	assert.Equal(t, "prod", *resp.Pools[1].Pool)
+	assert.Equal(t, int64(5), resp.Pools[1].Total)
+	assert.Equal(t, int64(0), resp.Pools[1].Free)
+}
+
+func TestReady_SinglePool(t *testing.T) {
+	pool := "prod"
+
+	cfg := server.NewConfig()
+	cfg.Pool = &pool
+
+	code, resp := getReady(t, newReadyServer(cfg))
+
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Pools, 1)
+	assert.Equal(t, "prod", *resp.Pools[0].Pool)
+
+	// the server can't serve claims from a pool that doesn't exist
+	other := "dev"
+	cfg.Pool = &other
+
+	code, resp = getReady(t, newReadyServer(cfg))
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.Database.OK)
+	assert.Equal(t, "pool not found", *resp.Database.Error)
+}
+
+func TestReady_DatabaseUnavailable(t *testing.T) {
+	cfg := server.NewConfig()
+
+	manager := newReadyManager()
+	manager.PingFn = func(ctx context.Context) error {
+		return errors.New("database is locked")
+	}
+
+	code, resp := getReady(t, testutils.NewMockServer(cfg, manager))
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.Ready)
+	assert.False(t, resp.Database.OK)
+	assert.Equal(t, "database is unavailable", *resp.Database.Error)
+	assert.Empty(t, resp.Pools)
+}
+
+func TestReady_PoolCapacityUnavailable(t *testing.T) {
+	manager := newReadyManager()
+	manager.GetPoolStatsFn = func(ctx context.Context) ([]db.PoolStats, error) {
+		return nil, errors.New("disk I/O error: /var/lib/relay/relay.sqlite")
+	}
+
+	srv := testutils.NewMockServer(server.NewConfig(), manager)
+	srv.ReaperData = &testutils.FakeReaper{
+		LastCullFn: func() time.Time { return time.Now() },
+	}
+
+	code, resp := getReady(t, srv)
+
+	// the underlying error is logged rather than exposed by the unauthenticated endpoint
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.Database.OK)
+	assert.Equal(t, "failed to get pool capacity", *resp.Database.Error)
+}
+
+func TestReady_Reaper(t *testing.T) {
+	tests := []struct {
+		name      string
+		heartbeat bool
+		lastCull  time.Time
+		ready     bool
+	}{
+		{name: "culled recently", heartbeat: true, lastCull: time.Now(), ready: true},
+		{name: "not culled recently", heartbeat: true, lastCull: time.Now().Add(-time.Hour), ready: false},
+		{name: "not running", heartbeat: true, ready: false},
+		{name: "heartbeats disabled", heartbeat: false, lastCull: time.Now(), ready: true},
+		{name: "heartbeats disabled and not running", heartbeat: false, ready: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := server.NewConfig()
+			cfg.EnabledHeartbeat = tt.heartbeat
+
+			srv := testutils.NewMockServer(cfg, newReadyManager())
+			srv.ReaperData = &testutils.FakeReaper{
+				LastCullFn: func() time.Time { return tt.lastCull },
+			}
+
+			code, resp := getReady(t, srv)
+
+			assert.Equal(t, tt.ready, resp.Ready)
+			assert.Equal(t, tt.ready, resp.Reaper.OK)
+			assert.True(t, resp.Reaper.Enabled)
+
+			if tt.ready {
+				assert.Equal(t, http.StatusOK, code)
+			} else {
+				assert.Equal(t, http.StatusServiceUnavailable, code)
+				assert.NotNil(t, resp.Reaper.Error)
+			}
+		})
+	}
+}
+
+func TestReady_ShuttingDown(t *testing.T) {
+	cfg := server.NewConfig()
+
+	srv := testutils.NewMockServer(cfg, newReadyManager())
+	srv.Shutdown()
+
+	code, resp := getReady(t, srv)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.Ready)
+}
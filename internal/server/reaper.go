@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/keygen-sh/keygen-relay/internal/licenses"
@@ -12,17 +13,26 @@ type Reaper interface {
 	Start(ctx context.Context) error
 	Manager() licenses.Manager
 	Config() *Config
+
+	// LastCull returns when the reaper last successfully culled dead nodes, or when it
+	// started if it hasn't culled yet, or the zero time if it was never started
+	LastCull() time.Time
 }
 
 type reaper struct {
-	manager licenses.Manager
-	config  *Config
+	manager  licenses.Manager
+	config   *Config
+	metrics  *Metrics
+	queue    *WaitQueue
+	lastCull atomic.Int64 // unix nanoseconds
 }
 
 func (r *reaper) Start(ctx context.Context) error {
 	ticker := time.NewTicker(r.config.CullInterval)
 	defer ticker.Stop()
 
+	r.lastCull.Store(time.Now().UnixNano())
+
 	logger.Debug("starting reaper", "ttl", r.config.TTL, "interval", r.config.CullInterval)
 
 	for {
@@ -44,6 +54,14 @@ func (r *reaper) Config() *Config {
 	return r.config
 }
 
+func (r *reaper) LastCull() time.Time {
+	if t := r.lastCull.Load(); t > 0 {
+		return time.Unix(0, t)
+	}
+
+	return time.Time{}
+}
+
 func (r *reaper) cull(ctx context.Context) {
 	nodes, err := r.manager.CullDeadNodes(ctx, r.config.TTL)
 	if err != nil {
@@ -52,13 +70,24 @@ func (r *reaper) cull(ctx context.Context) {
 		return
 	}
 
+	r.lastCull.Store(time.Now().UnixNano())
+
+	if r.metrics != nil {
+		r.metrics.ObserveCull(len(nodes))
+	}
+
 	if len(nodes) > 0 {
 		logger.Debug("reaper successfully culled dead nodes", "count", len(nodes))
+
+		// culled leases may have freed licenses for waiting claims
+		if r.queue != nil {
+			r.queue.NotifyAll()
+		}
 	} else {
 		logger.Debug("reaper has nothing to cull")
 	}
 }
 
-func NewReaper(c *Config, m licenses.Manager) Reaper {
-	return &reaper{config: c, manager: m}
+func NewReaper(c *Config, m licenses.Manager, metrics *Metrics, queue *WaitQueue) Reaper {
+	return &reaper{config: c, manager: m, metrics: metrics, queue: queue}
 }
@@ -2,13 +2,20 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type Server interface {
@@ -17,49 +24,247 @@ type Server interface {
 	Config() *Config
 	Manager() licenses.Manager
 	Reaper() Reaper
+	Metrics() *Metrics
+	Queue() *WaitQueue
+	RateLimiters() *RateLimiters
+	Done() <-chan struct{}
 }
 
 type server struct {
-	config  *Config
-	router  *mux.Router
-	manager licenses.Manager
-	reaper  Reaper
+	config   *Config
+	router   *mux.Router
+	manager  licenses.Manager
+	reaper   Reaper
+	metrics  *Metrics
+	queue    *WaitQueue
+	limiters *RateLimiters
+
+	// done is closed when the server starts shutting down, so that long-lived requests
+	// e.g. event streams can end rather than hold up shutdown
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 func New(c *Config, m licenses.Manager) Server {
+	metrics := NewMetrics(m)
+	queue := NewWaitQueue()
+
 	return &server{
-		config:  c,
-		router:  mux.NewRouter(),
-		manager: m,
-		reaper:  NewReaper(c, m),
+		config:   c,
+		router:   mux.NewRouter(),
+		manager:  m,
+		reaper:   NewReaper(c, m, metrics, queue),
+		metrics:  metrics,
+		queue:    queue,
+		limiters: NewRateLimiters(),
+		done:     make(chan struct{}),
 	}
 }
 
 func (s *server) Run() error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	addr := fmt.Sprintf("%s:%d", s.config.ServerAddr, s.config.ServerPort)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	logger.Info("starting server", "addr", s.config.ServerAddr, "port", s.config.ServerPort, "pool", s.config.Pool)
+	var (
+		ln  net.Listener
+		err error
+	)
 
-	if s.Config().EnabledHeartbeat {
-		go s.reaper.Start(ctx)
+	if path, ok := s.config.UnixSocket(); ok {
+		ln, err = listenUnix(path, s.config)
+	} else {
+		ln, err = net.Listen("tcp", fmt.Sprintf("%s:%d", s.config.ServerAddr, s.config.ServerPort))
 	}
 
-	if err := http.ListenAndServe(addr, s.router); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	if err != nil {
 		logger.Error("server failed to start", "error", err)
 
-		cancel()
-
 		return err
 	}
 
+	var grpcLn net.Listener
+
+	if s.config.GRPCPort > 0 {
+		grpcLn, err = net.Listen("tcp", fmt.Sprintf("%s:%d", s.config.ServerAddr, s.config.GRPCPort))
+		if err != nil {
+			logger.Error("grpc server failed to start", "error", err)
+
+			ln.Close()
+
+			return err
+		}
+	}
+
+	return s.serve(ctx, ln, grpcLn)
+}
+
+// serve serves requests on the listener, and gRPC requests on the gRPC listener if any,
+// until the context is done, at which point the server is gracefully shutdown, allowing
+// in-flight requests to drain before stopping the reaper.
+func (s *server) serve(ctx context.Context, ln net.Listener, grpcLn net.Listener) error {
+	httpServer := &http.Server{
+		Handler: s.router,
+	}
+
+	// release any waiting claims and end any streams so that they don't hold up shutdown
+	if s.queue != nil {
+		httpServer.RegisterOnShutdown(s.queue.Close)
+	}
+
+	if s.done != nil {
+		httpServer.RegisterOnShutdown(func() {
+			s.doneOnce.Do(func() { close(s.done) })
+		})
+	}
+
+	var grpcOpts []grpc.ServerOption
+
+	if s.config.TLSEnabled() {
+		tlsConfig, err := NewTLSConfig(s.config)
+		if err != nil {
+			logger.Error("failed to configure tls", "error", err)
+
+			closeListeners(ln, grpcLn)
+
+			return err
+		}
+
+		httpServer.TLSConfig = tlsConfig
+
+		if grpcLn != nil {
+			cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
+			if err != nil {
+				logger.Error("failed to configure tls", "error", err)
+
+				closeListeners(ln, grpcLn)
+
+				return err
+			}
+
+			grpcTLSConfig := tlsConfig.Clone()
+			grpcTLSConfig.Certificates = []tls.Certificate{cert}
+
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLSConfig)))
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if grpcLn != nil {
+		grpcServer = NewGRPCServer(s, grpcOpts...)
+	}
+
+	logger.Info("starting server", "addr", s.config.ServerAddr, "port", s.config.ServerPort, "grpc_port", s.config.GRPCPort, "pool", s.config.Pool, "tls", s.config.TLSEnabled(), "mtls", s.config.TLSClientCAFile != "")
+
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+
+	var wg sync.WaitGroup
+
+	// the reaper is always started, even when heartbeats are disabled by default, since
+	// heartbeats may be enabled for a pool at any time via pools set
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		s.reaper.Start(reaperCtx)
+	}()
+
+	errs := make(chan error, 2)
+
+	go func() {
+		if s.config.TLSEnabled() {
+			errs <- httpServer.ServeTLS(ln, s.config.TLSCertFile, s.config.TLSKeyFile)
+		} else {
+			errs <- httpServer.Serve(ln)
+		}
+	}()
+
+	if grpcServer != nil {
+		go func() {
+			errs <- grpcServer.Serve(grpcLn)
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+
+		httpServer.Close()
+
+		stopReaper()
+		wg.Wait()
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed to start", "error", err)
+
+			return err
+		}
+	case <-ctx.Done():
+		logger.Info("shutting down server", "timeout", s.config.ShutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+		defer cancel()
+
+		// drain gRPC calls alongside http requests, forcibly stopping the gRPC server if it
+		// doesn't drain before the shutdown timeout e.g. due to an open WatchLease stream
+		grpcStopped := make(chan struct{})
+
+		if grpcServer != nil {
+			go func() {
+				defer close(grpcStopped)
+
+				grpcServer.GracefulStop()
+			}()
+		} else {
+			close(grpcStopped)
+		}
+
+		err := httpServer.Shutdown(shutdownCtx)
+
+		select {
+		case <-grpcStopped:
+		case <-shutdownCtx.Done():
+			if grpcServer != nil {
+				grpcServer.Stop()
+				<-grpcStopped
+			}
+
+			if err == nil {
+				err = shutdownCtx.Err()
+			}
+		}
+
+		// stop the reaper after in-flight requests have drained so that it doesn't
+		// cull nodes that were mid-heartbeat during shutdown
+		stopReaper()
+		wg.Wait()
+
+		if err != nil {
+			logger.Error("server failed to shutdown gracefully", "error", err)
+
+			httpServer.Close()
+
+			return fmt.Errorf("failed to shutdown gracefully: %w", err)
+		}
+	}
+
 	logger.Info("server stopped")
 
 	return nil
 }
 
+// closeListeners closes the listeners, ignoring nil listeners
+func closeListeners(listeners ...net.Listener) {
+	for _, ln := range listeners {
+		if ln != nil {
+			ln.Close()
+		}
+	}
+}
+
 func (s *server) Mount(r *mux.Router) {
 	s.router = r
 }
@@ -79,3 +284,19 @@ func (s *server) Manager() licenses.Manager {
 func (s *server) Reaper() Reaper {
 	return s.reaper
 }
+
+func (s *server) Metrics() *Metrics {
+	return s.metrics
+}
+
+func (s *server) Queue() *WaitQueue {
+	return s.queue
+}
+
+func (s *server) RateLimiters() *RateLimiters {
+	return s.limiters
+}
+
+func (s *server) Done() <-chan struct{} {
+	return s.done
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubReaper struct {
+	stopped atomic.Bool
+}
+
+func (r *stubReaper) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	r.stopped.Store(true)
+
+	return nil
+}
+
+func (r *stubReaper) Manager() licenses.Manager { return nil }
+func (r *stubReaper) Config() *Config           { return nil }
+func (r *stubReaper) LastCull() time.Time       { return time.Time{} }
+
+func newTestServer(t *testing.T, cfg *Config, handler http.HandlerFunc) (*server, *stubReaper, net.Listener) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/slow", handler)
+
+	reaper := &stubReaper{}
+
+	return &server{config: cfg, router: router, reaper: reaper}, reaper, ln
+}
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	cfg := NewConfig()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	srv, reaper, ln := newTestServer(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.serve(ctx, ln, nil)
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			responses <- 0
+
+			return
+		}
+		defer resp.Body.Close()
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		responses <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	// new connections should be refused while in-flight requests drain
+	assert.Eventually(t, func() bool {
+		_, err := net.DialTimeout("tcp", ln.Addr().String(), 50*time.Millisecond)
+
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	assert.False(t, reaper.stopped.Load(), "reaper should run until requests have drained")
+
+	close(release)
+
+	assert.Equal(t, http.StatusCreated, <-responses)
+	assert.NoError(t, <-done)
+	assert.True(t, reaper.stopped.Load())
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	cfg := NewConfig()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	srv, reaper, ln := newTestServer(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.serve(ctx, ln, nil)
+	}()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, reaper.stopped.Load())
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shutdown after timeout")
+	}
+}
+
+func TestServe_HeartbeatDisabled(t *testing.T) {
+	cfg := NewConfig()
+	cfg.EnabledHeartbeat = false
+
+	srv, reaper, ln := newTestServer(t, cfg, func(w http.ResponseWriter, r *http.Request) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// heartbeats may be enabled for a pool at any time, so the reaper always runs
+	assert.NoError(t, srv.serve(ctx, ln, nil))
+	assert.True(t, reaper.stopped.Load(), "reaper should be started")
+}
+
+func TestServe_GRPCShutdown(t *testing.T) {
+	cfg := NewConfig()
+
+	srv, reaper, ln := newTestServer(t, cfg, func(w http.ResponseWriter, r *http.Request) {})
+
+	grpcLn, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.serve(ctx, ln, grpcLn)
+	}()
+
+	// the grpc api should be served alongside the http api
+	assert.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", grpcLn.Addr().String(), 50*time.Millisecond)
+		if err != nil {
+			return false
+		}
+
+		conn.Close()
+
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.True(t, reaper.stopped.Load())
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shutdown")
+	}
+
+	// both listeners should be closed once the server has shutdown
+	_, err = net.DialTimeout("tcp", grpcLn.Addr().String(), 50*time.Millisecond)
+	assert.Error(t, err)
+
+	_, err = net.DialTimeout("tcp", ln.Addr().String(), 50*time.Millisecond)
+	assert.Error(t, err)
+}
@@ -1,31 +1,249 @@
 package server
 
 import (
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureAlgorithmEd25519 is the alg of v2 and v4 signatures
+const SignatureAlgorithmEd25519 = "ed25519"
+
+var (
+	ErrSignatureMissing = errors.New("signature is missing")
+	ErrSignatureInvalid = errors.New("signature is invalid")
 )
 
 type Signer struct {
+	secret  []byte
+	secrets []signingSecret
+	key     ed25519.PrivateKey
+}
+
+type signingSecret struct {
+	kid    string
 	secret []byte
 }
 
 func NewSigner(cfg *Config) *Signer {
+	signer := &Signer{}
+
 	if cfg.SigningSecret != nil {
-		return &Signer{secret: []byte(*cfg.SigningSecret)}
+		signer.secret = []byte(*cfg.SigningSecret)
+	}
+
+	for _, s := range cfg.SigningSecrets {
+		signer.secrets = append(signer.secrets, signingSecret{kid: s.KeyID, secret: []byte(s.Secret)})
 	}
 
-	return &Signer{secret: nil}
+	if len(cfg.SigningKey) == ed25519.PrivateKeySize {
+		signer.key = cfg.SigningKey
+	}
+
+	return signer
 }
 
-// Sign generates an HMAC-SHA256 signature for the given message
+// Sign generates an HMAC-SHA256 signature for the given message using the secret
+// without a key ID
 func (s *Signer) Sign(message []byte) []byte {
-	mac := hmac.New(sha256.New, s.secret)
+	return signHMAC(s.secret, message)
+}
+
+// SignWithKeyID generates an HMAC-SHA256 signature for the given message using the
+// secret identified by kid, returning nil if there is no such secret
+func (s *Signer) SignWithKeyID(kid string, message []byte) []byte {
+	for _, secret := range s.secrets {
+		if secret.kid == kid {
+			return signHMAC(secret.secret, message)
+		}
+	}
+
+	return nil
+}
+
+func signHMAC(secret []byte, message []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
 	mac.Write(message)
 
 	return mac.Sum(nil)
 }
 
-// Enabled returns true if the signer has a secret configured
+// SignEd25519 generates an Ed25519 signature for the given message
+func (s *Signer) SignEd25519(message []byte) []byte {
+	return ed25519.Sign(s.key, message)
+}
+
+// Envelope is the part of an HTTP exchange covered by an envelope signature, so that a
+// signed response can't be replayed for another request or have its status swapped
+type Envelope struct {
+	Method string
+	Path   string // escaped, e.g. %0A rather than a newline
+	Status int
+	Nonce  string
+	Body   []byte
+}
+
+// message returns the envelope's signing data, delimited by newlines since neither the
+// method, escaped path, status nor nonce can contain one, with the raw body last
+func (e Envelope) message(t int64) []byte {
+	return []byte(fmt.Sprintf("%d\n%s\n%s\n%d\n%s\n%s", t, e.Method, e.Path, e.Status, e.Nonce, e.Body))
+}
+
+// Signature returns the Relay-Signature header value for a response, including v1 and
+// v3 HMAC-SHA256 signatures when a secret is configured, and v2 and v4 Ed25519
+// signatures when a key is configured. The v1 and v2 signatures only cover the body,
+// for backwards compatibility, while v3 and v4 cover the full envelope.
+//
+// Secrets with a key ID are signed last, each as a kid followed by its v1 and v3
+// signatures, so that nodes can roll to a new secret before the old one is removed.
+func (s *Signer) Signature(t int64, env Envelope) string {
+	// body signatures are computed over "<timestamp>.<raw response body>"
+	msg := []byte(fmt.Sprintf("%d.%s", t, env.Body))
+	envMsg := env.message(t)
+
+	parts := []string{fmt.Sprintf("t=%d", t)}
+
+	if len(s.secret) > 0 {
+		parts = append(parts,
+			"v1="+hex.EncodeToString(s.Sign(msg)),
+			"v3="+hex.EncodeToString(s.Sign(envMsg)),
+		)
+	}
+
+	if s.key != nil {
+		parts = append(parts,
+			"alg="+SignatureAlgorithmEd25519,
+			"v2="+hex.EncodeToString(s.SignEd25519(msg)),
+			"v4="+hex.EncodeToString(s.SignEd25519(envMsg)),
+		)
+	}
+
+	for _, secret := range s.secrets {
+		parts = append(parts,
+			"kid="+secret.kid,
+			"v1="+hex.EncodeToString(signHMAC(secret.secret, msg)),
+			"v3="+hex.EncodeToString(signHMAC(secret.secret, envMsg)),
+		)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// PublicKey returns the public key for verifying v2 and v4 signatures, or nil if no key is
+// configured
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	if s.key == nil {
+		return nil
+	}
+
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Enabled returns true if the signer has a secret or key configured
 func (s *Signer) Enabled() bool {
-	return len(s.secret) > 0
+	return len(s.secret) > 0 || len(s.secrets) > 0 || s.key != nil
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS #8 Ed25519 private key, e.g. as generated
+// by `openssl genpkey -algorithm ed25519`
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no pem-encoded signing key found at '%s'", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+
+	ed25519Key, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key at '%s' is not an ed25519 key", path)
+	}
+
+	return ed25519Key, nil
+}
+
+// VerifySignature verifies the v2 Ed25519 signature in a Relay-Signature header against
+// a raw response body, returning the signature's timestamp. Callers should reject
+// timestamps outside of their tolerance to prevent replay attacks.
+func VerifySignature(publicKey ed25519.PublicKey, header string, body []byte) (time.Time, error) {
+	return verifyEd25519(publicKey, header, "v2", func(t int64) []byte {
+		return []byte(fmt.Sprintf("%d.%s", t, body))
+	})
+}
+
+// VerifyEnvelopeSignature verifies the v4 Ed25519 signature in a Relay-Signature header
+// against the request and response it was received for, returning the signature's
+// timestamp. Unlike VerifySignature, a response can't be replayed for another request,
+// especially when the request was sent with a unique Relay-Nonce.
+func VerifyEnvelopeSignature(publicKey ed25519.PublicKey, header string, env Envelope) (time.Time, error) {
+	return verifyEd25519(publicKey, header, "v4", env.message)
+}
+
+func verifyEd25519(publicKey ed25519.PublicKey, header string, version string, message func(t int64) []byte) (time.Time, error) {
+	var (
+		ts   string
+		alg  string
+		sigs [][]byte
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		switch k {
+		case "t":
+			ts = v
+		case "alg":
+			alg = v
+		case version:
+			sig, err := hex.DecodeString(v)
+			if err != nil {
+				return time.Time{}, ErrSignatureInvalid
+			}
+
+			sigs = append(sigs, sig)
+		}
+	}
+
+	if ts == "" || len(sigs) == 0 {
+		return time.Time{}, ErrSignatureMissing
+	}
+
+	if alg != SignatureAlgorithmEd25519 {
+		return time.Time{}, fmt.Errorf("unsupported signature algorithm %q: %w", alg, ErrSignatureInvalid)
+	}
+
+	t, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrSignatureInvalid
+	}
+
+	msg := message(t)
+
+	for _, sig := range sigs {
+		if ed25519.Verify(publicKey, msg, sig) {
+			return time.Unix(t, 0), nil
+		}
+	}
+
+	return time.Time{}, ErrSignatureInvalid
 }
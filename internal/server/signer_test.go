@@ -1,6 +1,13 @@
 package server
 
 import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -80,3 +87,189 @@ func TestSigner_Sign_DifferentSecrets(t *testing.T) {
 
 	assert.NotEqual(t, sig1, sig2)
 }
+
+func TestSigner_Signature_Ed25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	signer := NewSigner(&Config{SigningKey: key})
+	assert.True(t, signer.Enabled())
+
+	body := []byte(`{"license_key":"test_license_key"}`)
+	header := signer.Signature(1764949490, Envelope{Body: body})
+
+	assert.True(t, strings.HasPrefix(header, "t=1764949490,alg=ed25519,v2="))
+	assert.NotContains(t, header, "v1=")
+
+	ts, err := VerifySignature(signer.PublicKey(), header, body)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1764949490), ts.Unix())
+
+	_, err = VerifySignature(signer.PublicKey(), header, []byte(`{"license_key":"forged_license_key"}`))
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	_, err = VerifySignature(signer.PublicKey(), strings.Replace(header, "t=1764949490", "t=1764949491", 1), body)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	otherPublicKey, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	_, err = VerifySignature(otherPublicKey, header, body)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+}
+
+func TestSigner_Signature_HMACAndEd25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	secret := "hunter2"
+	signer := NewSigner(&Config{SigningSecret: &secret, SigningKey: key})
+
+	body := []byte("message")
+	header := signer.Signature(1764949490, Envelope{Body: body})
+
+	assert.Contains(t, header, ",v1="+hex.EncodeToString(signer.Sign([]byte("1764949490.message"))))
+	assert.Contains(t, header, ",alg=ed25519,v2=")
+
+	_, err = VerifySignature(signer.PublicKey(), header, body)
+	assert.NoError(t, err)
+
+	// hmac signatures can't be verified with a public key
+	hmacOnly := NewSigner(&Config{SigningSecret: &secret})
+	assert.Nil(t, hmacOnly.PublicKey())
+
+	_, err = VerifySignature(signer.PublicKey(), hmacOnly.Signature(1764949490, Envelope{Body: body}), body)
+	assert.ErrorIs(t, err, ErrSignatureMissing)
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "signing.key")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	assert.NoError(t, err)
+
+	loaded, err := LoadSigningKey(path)
+	assert.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	invalid := filepath.Join(dir, "invalid.key")
+	err = os.WriteFile(invalid, []byte("not a key"), 0600)
+	assert.NoError(t, err)
+
+	_, err = LoadSigningKey(invalid)
+	assert.ErrorContains(t, err, "no pem-encoded signing key found")
+
+	_, err = LoadSigningKey(filepath.Join(dir, "missing.key"))
+	assert.ErrorContains(t, err, "failed to read signing key")
+}
+
+func TestSigner_Signature_Envelope(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	secret := "hunter2"
+	signer := NewSigner(&Config{SigningSecret: &secret, SigningKey: key})
+
+	env := Envelope{
+		Method: "PUT",
+		Path:   "/v1/nodes/test_fingerprint",
+		Status: 201,
+		Nonce:  "test_nonce",
+		Body:   []byte(`{"license_key":"test_license_key"}`),
+	}
+
+	header := signer.Signature(1764949490, env)
+
+	// v1 is unchanged for old clients
+	assert.Contains(t, header, "v1="+hex.EncodeToString(signer.Sign([]byte(`1764949490.{"license_key":"test_license_key"}`))))
+	assert.Contains(t, header, "v3="+hex.EncodeToString(signer.Sign([]byte("1764949490\nPUT\n/v1/nodes/test_fingerprint\n201\ntest_nonce\n"+`{"license_key":"test_license_key"}`))))
+
+	ts, err := VerifyEnvelopeSignature(signer.PublicKey(), header, env)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1764949490), ts.Unix())
+
+	_, err = VerifySignature(signer.PublicKey(), header, env.Body)
+	assert.NoError(t, err)
+
+	tests := map[string]func(e *Envelope){
+		"method": func(e *Envelope) { e.Method = "GET" },
+		"path":   func(e *Envelope) { e.Path = "/v1/nodes/other_fingerprint" },
+		"status": func(e *Envelope) { e.Status = 202 },
+		"nonce":  func(e *Envelope) { e.Nonce = "other_nonce" },
+		"body":   func(e *Envelope) { e.Body = []byte(`{"license_key":"forged_license_key"}`) },
+	}
+
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			tampered := env
+			tamper(&tampered)
+
+			_, err := VerifyEnvelopeSignature(signer.PublicKey(), header, tampered)
+			assert.ErrorIs(t, err, ErrSignatureInvalid)
+		})
+	}
+}
+
+func TestSigner_Signature_KeyIDs(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	secret := "hunter2"
+	signer := NewSigner(&Config{
+		SigningSecret: &secret,
+		SigningSecrets: []SigningSecret{
+			{KeyID: "a", Secret: "hunter3"},
+			{KeyID: "b", Secret: "hunter4"},
+		},
+		SigningKey: key,
+	})
+
+	assert.True(t, signer.Enabled())
+
+	env := Envelope{Method: "GET", Path: "/v1/nodes/test_fingerprint", Status: 200, Body: []byte("message")}
+	header := signer.Signature(1764949490, env)
+
+	msg := []byte("1764949490.message")
+	envMsg := env.message(1764949490)
+
+	// keyed secrets are signed after the secret without a key id and the ed25519 key, so
+	// that older clients only looking at the first v1 signature are unaffected
+	assert.True(t, strings.HasPrefix(header, "t=1764949490,v1="+hex.EncodeToString(signer.Sign(msg))+","))
+	assert.True(t, strings.HasSuffix(header, strings.Join([]string{
+		"kid=a",
+		"v1=" + hex.EncodeToString(signer.SignWithKeyID("a", msg)),
+		"v3=" + hex.EncodeToString(signer.SignWithKeyID("a", envMsg)),
+		"kid=b",
+		"v1=" + hex.EncodeToString(signer.SignWithKeyID("b", msg)),
+		"v3=" + hex.EncodeToString(signer.SignWithKeyID("b", envMsg)),
+	}, ",")))
+
+	assert.NotEqual(t, signer.SignWithKeyID("a", msg), signer.SignWithKeyID("b", msg))
+
+	rotated := "hunter3"
+	assert.Equal(t, NewSigner(&Config{SigningSecret: &rotated}).Sign(msg), signer.SignWithKeyID("a", msg))
+	assert.Nil(t, signer.SignWithKeyID("c", msg))
+
+	_, err = VerifyEnvelopeSignature(signer.PublicKey(), header, env)
+	assert.NoError(t, err)
+}
+
+func TestSigner_Signature_KeyIDsOnly(t *testing.T) {
+	signer := NewSigner(&Config{
+		SigningSecrets: []SigningSecret{{KeyID: "a", Secret: "hunter3"}},
+	})
+
+	assert.True(t, signer.Enabled())
+	assert.Nil(t, signer.PublicKey())
+
+	header := signer.Signature(1764949490, Envelope{Body: []byte("message")})
+
+	assert.True(t, strings.HasPrefix(header, "t=1764949490,kid=a,v1="+hex.EncodeToString(signer.SignWithKeyID("a", []byte("1764949490.message")))+",v3="))
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleSocketTimeout is how long to wait when checking if an existing socket is in use
+const staleSocketTimeout = 100 * time.Millisecond
+
+// listenUnix listens on a unix socket at the path, replacing a stale socket left behind
+// by e.g. a crashed server, and applies the configured owner and file mode. The socket
+// is removed when the listener is closed.
+func listenUnix(path string, cfg *Config) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix socket path is required")
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	// when a mode is configured, create the socket under a restrictive umask so that it's
+	// only accessible to us until its owner and mode are applied, rather than being
+	// accessible to anyone until it's chmod'ed
+	mask := 0
+	if cfg.SocketMode != 0 {
+		mask = 0177
+	}
+
+	var ln net.Listener
+
+	err := withUmask(mask, func() error {
+		var err error
+
+		ln, err = net.Listen("unix", path)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SocketOwner != "" {
+		uid, gid, err := lookupSocketOwner(cfg.SocketOwner)
+		if err != nil {
+			ln.Close()
+
+			return nil, err
+		}
+
+		if err := os.Chown(path, uid, gid); err != nil {
+			ln.Close()
+
+			return nil, fmt.Errorf("failed to set unix socket owner: %w", err)
+		}
+	}
+
+	if cfg.SocketMode != 0 {
+		if err := os.Chmod(path, cfg.SocketMode); err != nil {
+			ln.Close()
+
+			return nil, fmt.Errorf("failed to set unix socket mode: %w", err)
+		}
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket removes an existing socket at the path, unless another process is
+// still listening on it. Anything other than a socket is never removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a unix socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, staleSocketTimeout); err == nil {
+		conn.Close()
+
+		return fmt.Errorf("%s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// lookupSocketOwner resolves an owner in the form user[:group], where each may be a name
+// or a numeric ID. Without a group, the user's primary group is used when the user is
+// given by name, otherwise the group is left unchanged.
+func lookupSocketOwner(owner string) (int, int, error) {
+	username, groupname, hasGroup := strings.Cut(owner, ":")
+
+	uid, gid := -1, -1
+
+	if username != "" {
+		if id, err := strconv.Atoi(username); err == nil {
+			uid = id
+		} else {
+			u, err := user.Lookup(username)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket owner: %w", err)
+			}
+
+			uid, _ = strconv.Atoi(u.Uid)
+
+			if !hasGroup {
+				gid, _ = strconv.Atoi(u.Gid)
+			}
+		}
+	}
+
+	if groupname != "" {
+		if id, err := strconv.Atoi(groupname); err == nil {
+			gid = id
+		} else {
+			g, err := user.LookupGroup(groupname)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket group: %w", err)
+			}
+
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
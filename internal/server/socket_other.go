@@ -0,0 +1,8 @@
+//go:build !unix
+
+package server
+
+// withUmask calls fn as-is, since there's no umask on this platform
+func withUmask(mask int, fn func() error) error {
+	return fn()
+}
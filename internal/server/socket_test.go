@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketPath returns a path for a unix socket in a temp dir, where t.TempDir() isn't
+// used since its paths can exceed the max length of a unix socket path
+func socketPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "relay")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "relay.sock")
+}
+
+func TestListenUnix(t *testing.T) {
+	path := socketPath(t)
+
+	cfg := NewConfig()
+	cfg.SocketMode = 0600
+	cfg.SocketOwner = strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+
+	ln, err := listenUnix(path, cfg)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	assert.True(t, info.Mode()&os.ModeSocket != 0)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	stat := info.Sys().(*syscall.Stat_t)
+	assert.Equal(t, uint32(os.Getuid()), stat.Uid)
+	assert.Equal(t, uint32(os.Getgid()), stat.Gid)
+
+	// a socket that's in use should never be replaced
+	_, err = listenUnix(path, cfg)
+	assert.ErrorContains(t, err, "is already in use")
+
+	require.NoError(t, ln.Close())
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket should be removed on close")
+}
+
+func TestWithUmask(t *testing.T) {
+	path := socketPath(t)
+
+	old := syscall.Umask(0022)
+	defer syscall.Umask(old)
+
+	// the socket is only accessible to its owner until its mode is applied
+	err := withUmask(0177, func() error {
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// and the umask is restored afterwards
+	assert.Equal(t, 0022, syscall.Umask(0022))
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	path := socketPath(t)
+
+	// leave behind a socket without a listener, like a crashed server would
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	ln, err := listenUnix(path, NewConfig())
+	require.NoError(t, err)
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+}
+
+func TestListenUnix_NotSocket(t *testing.T) {
+	path := socketPath(t)
+
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0644))
+
+	_, err := listenUnix(path, NewConfig())
+	assert.ErrorContains(t, err, "is not a unix socket")
+
+	// the file should be left alone
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(b))
+}
+
+func TestListenUnix_InvalidOwner(t *testing.T) {
+	path := socketPath(t)
+
+	cfg := NewConfig()
+	cfg.SocketOwner = "relay_test_nonexistent_user"
+
+	_, err := listenUnix(path, cfg)
+	assert.ErrorContains(t, err, "invalid unix socket owner")
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket should be removed on error")
+}
+
+func TestLookupSocketOwner(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		uid   int
+		gid   int
+	}{
+		{name: "numeric user", owner: "1000", uid: 1000, gid: -1},
+		{name: "numeric user and group", owner: "1000:1001", uid: 1000, gid: 1001},
+		{name: "numeric group", owner: ":1001", uid: -1, gid: 1001},
+		{name: "named user", owner: "root", uid: 0, gid: 0},
+		{name: "named user and numeric group", owner: "root:1001", uid: 0, gid: 1001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, gid, err := lookupSocketOwner(tt.owner)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.uid, uid)
+			assert.Equal(t, tt.gid, gid)
+		})
+	}
+
+	_, _, err := lookupSocketOwner("relay:relay_test_nonexistent_group")
+	assert.Error(t, err)
+}
+
+func TestServe_UnixSocket(t *testing.T) {
+	path := socketPath(t)
+	secret := "test_secret"
+
+	cfg := NewConfig()
+	cfg.ServerAddr = "unix://" + path
+	cfg.SigningSecret = &secret
+
+	ln, err := listenUnix(path, cfg)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(SigningMiddleware(cfg))
+	router.Use(LoggingMiddleware(NewMetrics(nil)))
+	router.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	srv := &server{config: cfg, router: router, reaper: &stubReaper{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.serve(ctx, ln, nil)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://relay/v1/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	// responses should be signed the same as over tcp
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"status":"ok"}`, string(body))
+	assert.NotEmpty(t, resp.Header.Get("Relay-Clock"))
+	assert.NotEmpty(t, resp.Header.Get("Relay-Signature"))
+
+	client.CloseIdleConnections()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shutdown")
+	}
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "socket should be removed on shutdown")
+}
+
+func TestServe_UnixSocket_RateLimit(t *testing.T) {
+	path := socketPath(t)
+
+	cfg := NewConfig()
+	cfg.IPRateLimit = RateLimit{Rate: 1, Burst: 1}
+	cfg.FingerprintRateLimit = RateLimit{Rate: 1, Burst: 1}
+
+	ln, err := listenUnix(path, cfg)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Use(RateLimitMiddleware(cfg, nil, NewRateLimiters()))
+	router.HandleFunc("/v1/nodes/{fingerprint}", func(w http.ResponseWriter, r *http.Request) {}).Methods("PUT")
+
+	httpServer := &http.Server{Handler: router}
+	go httpServer.Serve(ln)
+	defer httpServer.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	put := func(fingerprint string) int {
+		req, err := http.NewRequest(http.MethodPut, "http://relay/v1/nodes/"+fingerprint, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// clients connected over a unix socket have no ip address, so they would otherwise
+	// all share a single bucket
+	for _, fingerprint := range []string{"a", "b", "c"} {
+		assert.Equal(t, http.StatusOK, put(fingerprint))
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, put("a"))
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package server
+
+import "syscall"
+
+// withUmask calls fn with the process's umask set to mask, restoring it afterwards. The
+// umask is process-wide, so this should only be used while the server is starting.
+func withUmask(mask int, fn func() error) error {
+	old := syscall.Umask(mask)
+	defer syscall.Umask(old)
+
+	return fn()
+}
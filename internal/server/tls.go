@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	errClientCertRequired  = errors.New("client certificate required")
+	errFingerprintMismatch = errors.New("fingerprint does not match client certificate")
+)
+
+// NewTLSConfig builds a TLS config for the server. When a client CA bundle is configured,
+// clients must present a certificate signed by one of the CAs in the bundle i.e. mTLS.
+func NewTLSConfig(cfg *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		bundle, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(bundle); !ok {
+			return nil, fmt.Errorf("no valid certificates found in client CA bundle at '%s'", cfg.TLSClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// ClientCertFingerprint returns the node fingerprint bound to a verified client
+// certificate, i.e. the common name of the certificate's subject
+func ClientCertFingerprint(state *tls.ConnectionState) (string, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return "", errClientCertRequired
+	}
+
+	cn := state.PeerCertificates[0].Subject.CommonName
+	if cn == "" {
+		return "", errClientCertRequired
+	}
+
+	return cn, nil
+}
@@ -0,0 +1,184 @@
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func writePEM(t *testing.T, c *testCert) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func TestNewTLSConfig_WithoutClientCA(t *testing.T) {
+	cfg := server.NewConfig()
+
+	tlsConfig, err := server.NewTLSConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+	assert.Nil(t, tlsConfig.ClientCAs)
+}
+
+func TestNewTLSConfig_WithClientCA(t *testing.T) {
+	ca := newTestCert(t, "relay-ca", nil)
+
+	cfg := server.NewConfig()
+	cfg.TLSClientCAFile = writePEM(t, ca)
+
+	tlsConfig, err := server.NewTLSConfig(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+	assert.NotNil(t, tlsConfig.ClientCAs)
+}
+
+func TestNewTLSConfig_InvalidClientCA(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	err := os.WriteFile(path, []byte("not a certificate"), 0o600)
+	assert.NoError(t, err)
+
+	cfg := server.NewConfig()
+	cfg.TLSClientCAFile = path
+
+	_, err = server.NewTLSConfig(cfg)
+	assert.ErrorContains(t, err, "no valid certificates found in client CA bundle")
+
+	cfg.TLSClientCAFile = filepath.Join(t.TempDir(), "missing.crt")
+
+	_, err = server.NewTLSConfig(cfg)
+	assert.ErrorContains(t, err, "failed to read client CA bundle")
+}
+
+func TestClientCertFingerprint(t *testing.T) {
+	ca := newTestCert(t, "relay-ca", nil)
+	client := newTestCert(t, "test_fingerprint", ca)
+
+	fingerprint, err := server.ClientCertFingerprint(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{client.cert}})
+	assert.NoError(t, err)
+	assert.Equal(t, "test_fingerprint", fingerprint)
+
+	_, err = server.ClientCertFingerprint(&tls.ConnectionState{})
+	assert.Error(t, err)
+
+	_, err = server.ClientCertFingerprint(nil)
+	assert.Error(t, err)
+}
+
+func TestMutualTLS_ClientFingerprint(t *testing.T) {
+	ca := newTestCert(t, "relay-ca", nil)
+	client := newTestCert(t, "test_fingerprint", ca)
+	other := newTestCert(t, "test_fingerprint", newTestCert(t, "untrusted-ca", nil))
+
+	cfg := server.NewConfig()
+	cfg.TLSClientCAFile = writePEM(t, ca)
+	cfg.TLSClientFingerprint = true
+
+	tlsConfig, err := server.NewTLSConfig(cfg)
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	server.NewHandler(testutils.NewMockServer(cfg, &testutils.FakeManager{})).RegisterRoutes(router)
+
+	ts := httptest.NewUnstartedServer(router)
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	newClient := func(cert *testCert) *http.Client {
+		transport := ts.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{cert.tlsCertificate()}
+		}
+
+		return &http.Client{Transport: transport}
+	}
+
+	t.Run("matching fingerprint", func(t *testing.T) {
+		resp, err := newClient(client).Get(ts.URL + "/v1/nodes/test_fingerprint")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("mismatched fingerprint", func(t *testing.T) {
+		resp, err := newClient(client).Get(ts.URL + "/v1/nodes/other_fingerprint")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("untrusted client certificate", func(t *testing.T) {
+		_, err := newClient(other).Get(ts.URL + "/v1/nodes/test_fingerprint")
+		assert.Error(t, err)
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		_, err := newClient(nil).Get(ts.URL + "/v1/nodes/test_fingerprint")
+		assert.Error(t, err)
+	})
+}
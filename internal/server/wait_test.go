@@ -0,0 +1,211 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/keygen-sh/keygen-relay/internal/db"
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
+	"github.com/keygen-sh/keygen-relay/internal/server"
+	"github.com/keygen-sh/keygen-relay/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePool is a minimal in-memory license pool for exercising wait queues
+type fakePool struct {
+	mu     sync.Mutex
+	free   int
+	leases map[string]bool
+}
+
+func newFakePoolManager(free int) *testutils.FakeManager {
+	p := &fakePool{free: free, leases: make(map[string]bool)}
+
+	return &testutils.FakeManager{
+		ClaimLicenseWithOptionsFn: func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			switch {
+			case p.leases[fingerprint]:
+				return &licenses.LicenseOperationResult{License: &db.License{}, Status: licenses.OperationStatusExtended}, nil
+			case opts.ExtendOnly() || p.free == 0:
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNoLicensesAvailable}, nil
+			}
+
+			p.free--
+			p.leases[fingerprint] = true
+
+			return &licenses.LicenseOperationResult{License: &db.License{Key: fingerprint}, Status: licenses.OperationStatusCreated}, nil
+		},
+		ReleaseLicenseFn: func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			if !p.leases[fingerprint] {
+				return &licenses.LicenseOperationResult{Status: licenses.OperationStatusNotFound}, nil
+			}
+
+			p.free++
+			delete(p.leases, fingerprint)
+
+			return &licenses.LicenseOperationResult{Status: licenses.OperationStatusSuccess}, nil
+		},
+	}
+}
+
+func newWaitRouter(srv server.Server) *mux.Router {
+	router := mux.NewRouter()
+	server.NewHandler(srv).RegisterRoutes(router)
+
+	return router
+}
+
+func TestClaimLicense_Wait_Handover(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newFakePoolManager(1))
+	router := newWaitRouter(srv)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/node_a", nil))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	waiters := make(map[string]*httptest.ResponseRecorder)
+	done := make(map[string]chan struct{})
+
+	for i, fingerprint := range []string{"node_b", "node_c"} {
+		waiters[fingerprint] = httptest.NewRecorder()
+		done[fingerprint] = make(chan struct{})
+
+		go func() {
+			defer close(done[fingerprint])
+
+			router.ServeHTTP(waiters[fingerprint], httptest.NewRequest(http.MethodPut, "/v1/nodes/"+fingerprint+"?wait=5s", nil))
+		}()
+
+		// wait for the claim to be queued so that ordering is deterministic
+		assert.Eventually(t, func() bool { return srv.Queue().Len(nil) == i+1 }, time.Second, time.Millisecond)
+	}
+
+	// a new claim can't jump the queue
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/node_d", nil))
+	assert.Equal(t, http.StatusGone, rr.Code)
+
+	// an existing lease can still be extended
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/node_a", nil))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	// releasing hands the license over to the first waiter
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/nodes/node_a", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	<-done["node_b"]
+	assert.Equal(t, http.StatusCreated, waiters["node_b"].Code)
+	assert.Equal(t, "1", waiters["node_b"].Header().Get("Relay-Queue-Position"))
+
+	select {
+	case <-done["node_c"]:
+		t.Fatal("expected second waiter to still be waiting")
+	default:
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/v1/nodes/node_b", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	<-done["node_c"]
+	assert.Equal(t, http.StatusCreated, waiters["node_c"].Code)
+	assert.Equal(t, "2", waiters["node_c"].Header().Get("Relay-Queue-Position"))
+	assert.Equal(t, 0, srv.Queue().Len(nil))
+}
+
+func TestClaimLicense_Wait_Timeout(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newFakePoolManager(0))
+	router := newWaitRouter(srv)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint?wait=50ms", nil))
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Relay-Queue-Position"))
+	assert.Contains(t, rr.Body.String(), "no licenses available")
+	assert.Equal(t, 0, srv.Queue().Len(nil))
+}
+
+func TestClaimLicense_Wait_MaxWait(t *testing.T) {
+	cfg := server.NewConfig()
+	cfg.MaxWait = 50 * time.Millisecond
+
+	srv := testutils.NewMockServer(cfg, newFakePoolManager(0))
+	router := newWaitRouter(srv)
+
+	start := time.Now()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint?wait=60", nil))
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestClaimLicense_Wait_Disconnect(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newFakePoolManager(0))
+	router := newWaitRouter(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		req := httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint?wait=1m", nil).WithContext(ctx)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	assert.Eventually(t, func() bool { return srv.Queue().Len(nil) == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, 0, srv.Queue().Len(nil))
+}
+
+func TestClaimLicense_Wait_Shutdown(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newFakePoolManager(0))
+	router := newWaitRouter(srv)
+
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint?wait=1m", nil))
+	}()
+
+	assert.Eventually(t, func() bool { return srv.Queue().Len(nil) == 1 }, time.Second, time.Millisecond)
+
+	srv.Queue().Close()
+	<-done
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestClaimLicense_Wait_Invalid(t *testing.T) {
+	srv := testutils.NewMockServer(server.NewConfig(), newFakePoolManager(0))
+	router := newWaitRouter(srv)
+
+	for _, wait := range []string{"forever", "-1s"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/nodes/test_fingerprint?wait="+wait, nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, wait)
+	}
+}
@@ -2,6 +2,7 @@ package testutils
 
 import (
 	"context"
+	"net"
 	"time"
 
 	"github.com/keygen-sh/keygen-relay/internal/db"
@@ -9,22 +10,49 @@ import (
 )
 
 type FakeManager struct {
-	store              db.Store
-	AddLicenseFn       func(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error)
-	RemoveLicenseFn    func(ctx context.Context, pool *string, id string) error
-	ListLicensesFn     func(ctx context.Context, pool *string) ([]db.License, error)
-	GetLicenseByGUIDFn func(ctx context.Context, pool *string, id string) (*db.License, error)
-	ClaimLicenseFn     func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error)
-	ReleaseLicenseFn   func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error)
-	CullDeadNodesFn    func(ctx context.Context, ttl time.Duration) ([]db.Node, error)
-	ConfigFn           func() *licenses.Config
-	GetPoolsFn         func(ctx context.Context) ([]db.Pool, error)
-	GetPoolByIDFn      func(ctx context.Context, id int64) (*db.Pool, error)
-}
-
-func (f *FakeManager) AddLicense(ctx context.Context, pool *string, filePath, key, publicKey string) (*db.License, error) {
+	store                       db.Store
+	AddLicenseFn                func(ctx context.Context, pool *string, filePath, key string) (*db.License, error)
+	RemoveLicenseFn             func(ctx context.Context, pool *string, id string) error
+	ListLicensesFn              func(ctx context.Context, pool *string) ([]db.License, error)
+	GetLicenseByGUIDFn          func(ctx context.Context, pool *string, id string) (*db.License, error)
+	ClaimLicenseFn              func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error)
+	ClaimLicenseWithOptionsFn   func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error)
+	ReleaseLicenseFn            func(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error)
+	ReleaseLicenseWithOptionsFn func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*licenses.LicenseOperationResult, error)
+	CullDeadNodesFn             func(ctx context.Context, ttl time.Duration) ([]db.Node, error)
+	ConfigFn                    func() *licenses.Config
+	GetPoolsFn                  func(ctx context.Context) ([]db.Pool, error)
+	GetPoolByIDFn               func(ctx context.Context, id int64) (*db.Pool, error)
+	CreatePoolFn                func(ctx context.Context, name string) (*db.Pool, error)
+	DeletePoolFn                func(ctx context.Context, name string) error
+	UpdatePoolFn                func(ctx context.Context, name string, settings licenses.PoolSettings) (*db.Pool, error)
+	ResetPoolFn                 func(ctx context.Context, name string) (*db.Pool, error)
+	GetPoolStatsFn              func(ctx context.Context) ([]db.PoolStats, error)
+	PingFn                      func(ctx context.Context) error
+	CountActiveNodesFn          func(ctx context.Context) (int64, error)
+	GetLeaseFn                  func(ctx context.Context, pool *string, fingerprint string) (*db.Lease, error)
+	GetLeaseWithOptionsFn       func(ctx context.Context, pool *string, fingerprint string, opts *licenses.LeaseOptions) (*db.Lease, error)
+	GetNodeByIDFn               func(ctx context.Context, id int64) (*db.Node, error)
+	ListLeasesFn                func(ctx context.Context, pool *string, limit int64, offset int64) ([]db.Lease, error)
+	ListLeasesWithOptionsFn     func(ctx context.Context, pool *string, limit int64, offset int64, opts *licenses.ListOptions) ([]db.Lease, error)
+	ListEventsFn                func(ctx context.Context, pool *string, afterID int64, limit int64) ([]db.Event, error)
+	ListEventsWithOptionsFn     func(ctx context.Context, pool *string, afterID int64, limit int64, opts *licenses.ListOptions) ([]db.Event, error)
+	LastEventIDFn               func(ctx context.Context) (int64, error)
+	CreateAPITokenFn            func(ctx context.Context, pool *string) (string, *db.ApiToken, error)
+	ListAPITokensFn             func(ctx context.Context) ([]db.ApiToken, error)
+	DeleteAPITokenFn            func(ctx context.Context, id int64) error
+	AuthenticateAPITokenFn      func(ctx context.Context, pool *string, token string) (*db.ApiToken, error)
+	AddPoolCIDRFn               func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) (*db.PoolCidr, error)
+	RemovePoolCIDRFn            func(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) error
+	ListPoolCIDRsFn             func(ctx context.Context) ([]db.PoolCidr, error)
+	CheckPoolAccessFn           func(ctx context.Context, pool *string, ip net.IP) error
+	ReserveLicenseFn            func(ctx context.Context, id string, fingerprint string) (*db.License, error)
+	UnreserveLicenseFn          func(ctx context.Context, id string) (*db.License, error)
+}
+
+func (f *FakeManager) AddLicense(ctx context.Context, pool *string, filePath, key string) (*db.License, error) {
 	if f.AddLicenseFn != nil {
-		return f.AddLicenseFn(ctx, pool, filePath, key, publicKey)
+		return f.AddLicenseFn(ctx, pool, filePath, key)
 	}
 	return &db.License{}, nil
 }
@@ -54,7 +82,11 @@ func (f *FakeManager) AttachStore(store db.Store) {
 	f.store = store
 }
 
-func (f *FakeManager) ClaimLicense(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+func (f *FakeManager) ClaimLicense(ctx context.Context, pool *string, fingerprint string, opts ...licenses.LeaseOptionFunc) (*licenses.LicenseOperationResult, error) {
+	if f.ClaimLicenseWithOptionsFn != nil {
+		return f.ClaimLicenseWithOptionsFn(ctx, pool, fingerprint, licenses.ApplyLeaseOptions(opts...))
+	}
+
 	if f.ClaimLicenseFn != nil {
 		return f.ClaimLicenseFn(ctx, pool, fingerprint)
 	}
@@ -62,7 +94,11 @@ func (f *FakeManager) ClaimLicense(ctx context.Context, pool *string, fingerprin
 	return nil, nil
 }
 
-func (f *FakeManager) ReleaseLicense(ctx context.Context, pool *string, fingerprint string) (*licenses.LicenseOperationResult, error) {
+func (f *FakeManager) ReleaseLicense(ctx context.Context, pool *string, fingerprint string, opts ...licenses.LeaseOptionFunc) (*licenses.LicenseOperationResult, error) {
+	if f.ReleaseLicenseWithOptionsFn != nil {
+		return f.ReleaseLicenseWithOptionsFn(ctx, pool, fingerprint, licenses.ApplyLeaseOptions(opts...))
+	}
+
 	if f.ReleaseLicenseFn != nil {
 		return f.ReleaseLicenseFn(ctx, pool, fingerprint)
 	}
@@ -101,3 +137,191 @@ func (f *FakeManager) GetPoolByID(ctx context.Context, id int64) (*db.Pool, erro
 
 	return &db.Pool{}, nil
 }
+
+func (f *FakeManager) CreatePool(ctx context.Context, name string) (*db.Pool, error) {
+	if f.CreatePoolFn != nil {
+		return f.CreatePoolFn(ctx, name)
+	}
+
+	return &db.Pool{Name: name}, nil
+}
+
+func (f *FakeManager) DeletePool(ctx context.Context, name string) error {
+	if f.DeletePoolFn != nil {
+		return f.DeletePoolFn(ctx, name)
+	}
+
+	return nil
+}
+
+func (f *FakeManager) UpdatePool(ctx context.Context, name string, settings licenses.PoolSettings) (*db.Pool, error) {
+	if f.UpdatePoolFn != nil {
+		return f.UpdatePoolFn(ctx, name, settings)
+	}
+
+	return &db.Pool{Name: name}, nil
+}
+
+func (f *FakeManager) ResetPool(ctx context.Context, name string) (*db.Pool, error) {
+	if f.ResetPoolFn != nil {
+		return f.ResetPoolFn(ctx, name)
+	}
+
+	return &db.Pool{Name: name}, nil
+}
+
+func (f *FakeManager) GetPoolStats(ctx context.Context) ([]db.PoolStats, error) {
+	if f.GetPoolStatsFn != nil {
+		return f.GetPoolStatsFn(ctx)
+	}
+
+	return []db.PoolStats{}, nil
+}
+
+func (f *FakeManager) Ping(ctx context.Context) error {
+	if f.PingFn != nil {
+		return f.PingFn(ctx)
+	}
+
+	return nil
+}
+
+func (f *FakeManager) CountActiveNodes(ctx context.Context) (int64, error) {
+	if f.CountActiveNodesFn != nil {
+		return f.CountActiveNodesFn(ctx)
+	}
+
+	return 0, nil
+}
+
+func (f *FakeManager) GetLease(ctx context.Context, pool *string, fingerprint string, opts ...licenses.LeaseOptionFunc) (*db.Lease, error) {
+	if f.GetLeaseWithOptionsFn != nil {
+		return f.GetLeaseWithOptionsFn(ctx, pool, fingerprint, licenses.ApplyLeaseOptions(opts...))
+	}
+
+	if f.GetLeaseFn != nil {
+		return f.GetLeaseFn(ctx, pool, fingerprint)
+	}
+
+	return &db.Lease{}, nil
+}
+
+func (f *FakeManager) GetNodeByID(ctx context.Context, id int64) (*db.Node, error) {
+	if f.GetNodeByIDFn != nil {
+		return f.GetNodeByIDFn(ctx, id)
+	}
+
+	return &db.Node{}, nil
+}
+
+func (f *FakeManager) ListLeases(ctx context.Context, pool *string, limit int64, offset int64, opts ...licenses.ListOptionFunc) ([]db.Lease, error) {
+	if f.ListLeasesWithOptionsFn != nil {
+		return f.ListLeasesWithOptionsFn(ctx, pool, limit, offset, licenses.ApplyListOptions(opts...))
+	}
+
+	if f.ListLeasesFn != nil {
+		return f.ListLeasesFn(ctx, pool, limit, offset)
+	}
+
+	return []db.Lease{}, nil
+}
+
+func (f *FakeManager) ListEvents(ctx context.Context, pool *string, afterID int64, limit int64, opts ...licenses.ListOptionFunc) ([]db.Event, error) {
+	if f.ListEventsWithOptionsFn != nil {
+		return f.ListEventsWithOptionsFn(ctx, pool, afterID, limit, licenses.ApplyListOptions(opts...))
+	}
+
+	if f.ListEventsFn != nil {
+		return f.ListEventsFn(ctx, pool, afterID, limit)
+	}
+
+	return []db.Event{}, nil
+}
+
+func (f *FakeManager) LastEventID(ctx context.Context) (int64, error) {
+	if f.LastEventIDFn != nil {
+		return f.LastEventIDFn(ctx)
+	}
+
+	return 0, nil
+}
+
+func (f *FakeManager) CreateAPIToken(ctx context.Context, pool *string) (string, *db.ApiToken, error) {
+	if f.CreateAPITokenFn != nil {
+		return f.CreateAPITokenFn(ctx, pool)
+	}
+
+	return "", &db.ApiToken{}, nil
+}
+
+func (f *FakeManager) ListAPITokens(ctx context.Context) ([]db.ApiToken, error) {
+	if f.ListAPITokensFn != nil {
+		return f.ListAPITokensFn(ctx)
+	}
+
+	return nil, nil
+}
+
+func (f *FakeManager) DeleteAPIToken(ctx context.Context, id int64) error {
+	if f.DeleteAPITokenFn != nil {
+		return f.DeleteAPITokenFn(ctx, id)
+	}
+
+	return nil
+}
+
+func (f *FakeManager) AuthenticateAPIToken(ctx context.Context, pool *string, token string) (*db.ApiToken, error) {
+	if f.AuthenticateAPITokenFn != nil {
+		return f.AuthenticateAPITokenFn(ctx, pool, token)
+	}
+
+	return &db.ApiToken{}, nil
+}
+
+func (f *FakeManager) AddPoolCIDR(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) (*db.PoolCidr, error) {
+	if f.AddPoolCIDRFn != nil {
+		return f.AddPoolCIDRFn(ctx, pool, cidr, action)
+	}
+
+	return &db.PoolCidr{}, nil
+}
+
+func (f *FakeManager) RemovePoolCIDR(ctx context.Context, pool string, cidr string, action licenses.CIDRAction) error {
+	if f.RemovePoolCIDRFn != nil {
+		return f.RemovePoolCIDRFn(ctx, pool, cidr, action)
+	}
+
+	return nil
+}
+
+func (f *FakeManager) ListPoolCIDRs(ctx context.Context) ([]db.PoolCidr, error) {
+	if f.ListPoolCIDRsFn != nil {
+		return f.ListPoolCIDRsFn(ctx)
+	}
+
+	return nil, nil
+}
+
+func (f *FakeManager) CheckPoolAccess(ctx context.Context, pool *string, ip net.IP) error {
+	if f.CheckPoolAccessFn != nil {
+		return f.CheckPoolAccessFn(ctx, pool, ip)
+	}
+
+	return nil
+}
+
+func (f *FakeManager) ReserveLicense(ctx context.Context, id string, fingerprint string) (*db.License, error) {
+	if f.ReserveLicenseFn != nil {
+		return f.ReserveLicenseFn(ctx, id, fingerprint)
+	}
+
+	return &db.License{Guid: id, ReservedFingerprint: &fingerprint}, nil
+}
+
+func (f *FakeManager) UnreserveLicense(ctx context.Context, id string) (*db.License, error) {
+	if f.UnreserveLicenseFn != nil {
+		return f.UnreserveLicenseFn(ctx, id)
+	}
+
+	return &db.License{Guid: id}, nil
+}
@@ -2,14 +2,17 @@ package testutils
 
 import (
 	"context"
+	"time"
 
+	"github.com/keygen-sh/keygen-relay/internal/licenses"
 	"github.com/keygen-sh/keygen-relay/internal/server"
 )
 
 type FakeReaper struct {
-	StartFn   func(ctx context.Context) error
-	ManagerFn func() FakeManager
-	ConfigFn  func() *server.Config
+	StartFn    func(ctx context.Context) error
+	ManagerFn  func() licenses.Manager
+	ConfigFn   func() *server.Config
+	LastCullFn func() time.Time
 }
 
 func (r *FakeReaper) Start(ctx context.Context) error {
@@ -19,12 +22,12 @@ func (r *FakeReaper) Start(ctx context.Context) error {
 	return nil
 }
 
-func (r *FakeReaper) Manager() FakeManager {
+func (r *FakeReaper) Manager() licenses.Manager {
 	if r.ManagerFn != nil {
 		return r.ManagerFn()
 	}
 
-	return FakeManager{}
+	return &FakeManager{}
 }
 
 func (r *FakeReaper) Config() *server.Config {
@@ -34,3 +37,11 @@ func (r *FakeReaper) Config() *server.Config {
 
 	return &server.Config{}
 }
+
+func (r *FakeReaper) LastCull() time.Time {
+	if r.LastCullFn != nil {
+		return r.LastCullFn()
+	}
+
+	return time.Time{}
+}
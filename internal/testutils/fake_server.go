@@ -13,8 +13,12 @@ type FakeServer struct {
 	RunErr      error
 	RunCalledMu sync.Mutex
 	ConfigData  *server.Config
+	ReaperData  server.Reaper
 	manager     *FakeManager
-	reaper      *server.Reaper
+	metrics     *server.Metrics
+	queue       *server.WaitQueue
+	limiters    *server.RateLimiters
+	done        chan struct{}
 }
 
 func (s *FakeServer) Run() error {
@@ -36,12 +40,37 @@ func (s *FakeServer) Manager() licenses.Manager {
 }
 
 func (s *FakeServer) Reaper() server.Reaper {
-	return *s.reaper
+	return s.ReaperData
+}
+
+func (s *FakeServer) Metrics() *server.Metrics {
+	return s.metrics
+}
+
+func (s *FakeServer) Queue() *server.WaitQueue {
+	return s.queue
+}
+
+func (s *FakeServer) RateLimiters() *server.RateLimiters {
+	return s.limiters
+}
+
+func (s *FakeServer) Done() <-chan struct{} {
+	return s.done
+}
+
+// Shutdown simulates the server shutting down, ending long-lived requests
+func (s *FakeServer) Shutdown() {
+	close(s.done)
 }
 
 func NewMockServer(config *server.Config, manager *FakeManager) *FakeServer {
 	return &FakeServer{
 		ConfigData: config,
 		manager:    manager,
+		metrics:    server.NewMetrics(manager),
+		queue:      server.NewWaitQueue(),
+		limiters:   server.NewRateLimiters(),
+		done:       make(chan struct{}),
 	}
 }
@@ -61,6 +61,16 @@ func EnvInt(key string) func() int {
 	})
 }
 
+func EnvFloat(key string) func() float64 {
+	return EnvAs(key, func(value string) float64 {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		} else {
+			return 0
+		}
+	})
+}
+
 func EnvDuration(key string) func() time.Duration {
 	return EnvAs(key, func(value string) time.Duration {
 		if d, err := time.ParseDuration(value); err == nil {